@@ -0,0 +1,55 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"prosimcorp.com/replika/api/v1beta1"
+)
+
+var _ conversion.Convertible = &Replika{}
+
+// ConvertTo converts this Replika to the Hub version (v1beta1)
+func (src *Replika) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.Replika)
+
+	err := convertReplika(src, dst)
+	dst.SetGroupVersionKind(v1beta1.GroupVersion.WithKind("Replika"))
+	return err
+}
+
+// ConvertFrom converts from the Hub version (v1beta1) to this version
+func (dst *Replika) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.Replika)
+
+	err := convertReplika(src, dst)
+	dst.SetGroupVersionKind(GroupVersion.WithKind("Replika"))
+	return err
+}
+
+// convertReplika copy a Replika between both versions through its JSON representation, as they share the fields
+func convertReplika(src, dst interface{}) (err error) {
+	var content []byte
+	content, err = json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, dst)
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"prosimcorp.com/replika/api/v1beta1"
+)
+
+func TestConversion(t *testing.T) {
+	tests := []struct {
+		name    string
+		replika *Replika
+	}{
+		{
+			name: "ConfigMap source",
+			replika: &Replika{
+				ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default", Labels: map[string]string{"team": "platform"}},
+				Spec: ReplikaSpec{
+					Synchronization: SynchronizationSpec{Time: "30s", Parallelism: 5},
+					Source:          ReplikaSourceSpec{Version: "v1", Kind: "ConfigMap", Name: "app-config", Namespace: "default"},
+					Target: ReplikaTargetSpec{
+						Namespaces: ReplikaTargetNamespacesSpec{MatchAll: true, ExcludeFrom: []string{"kube-system"}},
+						HashSuffix: true,
+					},
+				},
+				Status: ReplikaStatus{TotalTargets: 3, SyncedNamespaces: []string{"team-a", "team-b", "team-c"}},
+			},
+		},
+		{
+			name: "inline source",
+			replika: &Replika{
+				ObjectMeta: metav1.ObjectMeta{Name: "inline", Namespace: "default"},
+				Spec: ReplikaSpec{
+					Source: ReplikaSourceSpec{Inline: &runtime.RawExtension{
+						Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"inline"},"data":{"a":"1"}}`),
+					}},
+					Target: ReplikaTargetSpec{Namespaces: ReplikaTargetNamespacesSpec{ReplicateIn: []string{"team-a"}}},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hub := &v1beta1.Replika{}
+			if err := test.replika.ConvertTo(hub); err != nil {
+				t.Fatalf("unexpected error converting to the hub: %v", err)
+			}
+			if hub.GroupVersionKind() != v1beta1.GroupVersion.WithKind("Replika") {
+				t.Errorf("unexpected kind of the hub %v", hub.GroupVersionKind())
+			}
+			if hub.Name != test.replika.Name || hub.Spec.Target.Namespaces.MatchAll != test.replika.Spec.Target.Namespaces.MatchAll {
+				t.Errorf("the fields are not copied to the hub: %v", hub)
+			}
+
+			converted := &Replika{}
+			if err := converted.ConvertFrom(hub); err != nil {
+				t.Fatalf("unexpected error converting from the hub: %v", err)
+			}
+			if converted.GroupVersionKind() != GroupVersion.WithKind("Replika") {
+				t.Errorf("unexpected kind of the converted %v", converted.GroupVersionKind())
+			}
+
+			// The round trip through the hub keeps every field
+			converted.TypeMeta = test.replika.TypeMeta
+			if !equality.Semantic.DeepEqual(converted, test.replika) {
+				t.Errorf("expected %v, got %v", test.replika, converted)
+			}
+		})
+	}
+}
@@ -0,0 +1,682 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Layout of the start and end of the synchronization windows
+const synchronizationWindowLayout = "15:04"
+
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// SynchronizationSpec defines the spec of the synchronization section of a Replika
+type SynchronizationSpec struct {
+	// Time between synchronizations. The default time of the operator configuration is used when empty
+	//+kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|ms|s|m|h))+$`
+	Time string `json:"time,omitempty"`
+
+	// DryRunValidation runs every target through a server-side dry-run before the real writes,
+	// so admission rejections are reported per namespace instead of failing silently
+	DryRunValidation bool `json:"dryRunValidation,omitempty"`
+
+	// AuditOnly disables the writes. The targets are only audited against the source on each synchronization
+	AuditOnly bool `json:"auditOnly,omitempty"`
+
+	// Parallelism is the number of targets written at the same time. The targets are written one by one when empty
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=100
+	Parallelism int `json:"parallelism,omitempty"`
+
+	// RequestTimeout bounds each request reading a source or writing a target, like 30s, so a slow API server
+	// does not stall the synchronization. The timeout of the operator is used when empty
+	RequestTimeout string `json:"requestTimeout,omitempty"`
+
+	// OptimisticLock writes each target only over the version read before computing its changes. The target is
+	// read again when it is modified in the meantime, so concurrent changes are never overwritten blindly
+	OptimisticLock bool `json:"optimisticLock,omitempty"`
+
+	// Window defers the synchronizations happening inside it until it ends
+	Window *SynchronizationWindowSpec `json:"window,omitempty"`
+}
+
+// SynchronizationWindowSpec defines a daily window where the synchronizations are deferred
+type SynchronizationWindowSpec struct {
+	// Start of the window as HH:MM
+	//+kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+
+	// End of the window as HH:MM. Windows ending before they start cross midnight
+	//+kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+
+	// TimeZone of the window as an IANA name, like Europe/Madrid. UTC when empty
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// GetEnd return the end of the window when the time is inside it. An error is returned when the window is malformed
+func (w *SynchronizationWindowSpec) GetEnd(now time.Time) (end time.Time, inside bool, err error) {
+
+	location := time.UTC
+	if w.TimeZone != "" {
+		location, err = time.LoadLocation(w.TimeZone)
+		if err != nil {
+			return end, inside, err
+		}
+	}
+
+	var start, stop time.Time
+	start, err = time.Parse(synchronizationWindowLayout, w.Start)
+	if err != nil {
+		return end, inside, err
+	}
+	stop, err = time.Parse(synchronizationWindowLayout, w.End)
+	if err != nil {
+		return end, inside, err
+	}
+
+	// Look for the window in the current day and in the previous one, for windows crossing midnight
+	now = now.In(location)
+	for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+		windowStart := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, location)
+		windowEnd := time.Date(day.Year(), day.Month(), day.Day(), stop.Hour(), stop.Minute(), 0, 0, location)
+		if !windowEnd.After(windowStart) {
+			windowEnd = windowEnd.AddDate(0, 0, 1)
+		}
+		if !now.Before(windowStart) && now.Before(windowEnd) {
+			return windowEnd, true, err
+		}
+	}
+
+	return end, inside, err
+}
+
+// ReplikaTargetNamespacesSpec defines the spec of the target namespaces section of a Replika
+type ReplikaTargetNamespacesSpec struct {
+	ReplicateIn []string `json:"replicateIn,omitempty"`
+	MatchAll    bool     `json:"matchAll"`
+	ExcludeFrom []string `json:"excludeFrom,omitempty"`
+
+	// CELExpression is evaluated against each Namespace, available as 'ns', to select the targets.
+	// Example: ns.metadata.labels['team'] in ['a', 'b']
+	CELExpression string `json:"celExpression,omitempty"`
+
+	// RequireResource restricts the targets to the namespaces containing at least one object of the kind matching the selector
+	RequireResource *ReplikaRequiredResourceSpec `json:"requireResource,omitempty"`
+
+	// Order lists the namespaces, or patterns like 'infra-*', synchronized first and in the same order.
+	// The rest of the namespaces are synchronized in alphabetical order after them
+	Order []string `json:"order,omitempty"`
+}
+
+// ReplikaRequiredResourceSpec defines the objects a namespace must contain to be a target
+type ReplikaRequiredResourceSpec struct {
+	Group   string `json:"group,omitempty"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+
+	// Selector restricts the required objects to those matching the labels
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// ReplikaCanarySpec defines the namespace synchronized and verified before the rest of the targets
+type ReplikaCanarySpec struct {
+	// Namespace synchronized first. It must be one of the target namespaces
+	Namespace string `json:"namespace"`
+
+	// VerificationJob is the name of a Job in the canary namespace. When set, the rest of the targets
+	// are synchronized once it completes after the canary was written
+	VerificationJob string `json:"verificationJob,omitempty"`
+}
+
+// ReplikaRolloutSpec defines how the source is rolled out across the targets
+type ReplikaRolloutSpec struct {
+	Canary *ReplikaCanarySpec `json:"canary,omitempty"`
+}
+
+// ReplikaTargetSpec defines the spec of the target section of a Replica
+type ReplikaTargetSpec struct {
+	Namespaces ReplikaTargetNamespacesSpec `json:"namespaces,omitempty"`
+
+	// ReloadWorkloads triggers a rollout of the Deployments and StatefulSets consuming
+	// a replicated ConfigMap or Secret each time its content changes
+	ReloadWorkloads bool `json:"reloadWorkloads,omitempty"`
+
+	// AdoptExisting writes the targets over the objects with their names not written by the controller,
+	// taking them over, so they are deleted along with the rest of the targets. Otherwise, those namespaces
+	// are rejected with a TargetConflict
+	AdoptExisting bool `json:"adoptExisting,omitempty"`
+
+	// DiscoverConsumers records in the status the workloads referencing the targets
+	DiscoverConsumers bool `json:"discoverConsumers,omitempty"`
+
+	// HashSuffix appends a short hash of the content to the names of the ConfigMap and Secret targets,
+	// writing each change as a new object. The workloads referencing the base name or a previous version
+	// are switched to the new name, the base name only when no object has it or it is a target of this Replika.
+	// The previous versions are pruned once nothing references them
+	HashSuffix bool `json:"hashSuffix,omitempty"`
+
+	// OnDemand replicates a ConfigMap or Secret only into the selected namespaces where a workload or Pod
+	// references it by name, deleting the target once the last consumer disappears. The new consumers get
+	// their target right away when the operator watches the Pods, and on the next synchronization otherwise
+	OnDemand bool `json:"onDemand,omitempty"`
+
+	// RecordEvents emits an Event in the namespace of each target when it is created or replaced,
+	// giving the teams owning the namespaces a local trail of the changes
+	RecordEvents bool `json:"recordEvents,omitempty"`
+
+	// StripLabels removes from the targets the labels of the source starting with any of the prefixes,
+	// like 'helm.sh/' or 'app.kubernetes.io/managed-by', so the tooling managing the source does not
+	// mistake the copies for its own objects. Labels already copied on existing targets are kept
+	StripLabels []string `json:"stripLabels,omitempty"`
+
+	// RegistryRewrites renames the registries of the replicated docker config Secrets, so the same credentials
+	// are used to pull from a mirror. The keys are the registries of the source and the values their replacement
+	RegistryRewrites map[string]string `json:"registryRewrites,omitempty"`
+
+	// RewriteSubjectNamespaces points the ServiceAccount subjects of the replicated RoleBindings
+	// referencing the namespace of the source to the namespace of each target
+	RewriteSubjectNamespaces bool `json:"rewriteSubjectNamespaces,omitempty"`
+
+	// TemplateSelectors replaces 'replika.target-namespace' and 'replika.source-namespace' in the values of the
+	// namespaceSelector and podSelector entries of the replicated NetworkPolicies with the namespaces of each target
+	TemplateSelectors bool `json:"templateSelectors,omitempty"`
+
+	// LookupValues replaces the '{{ lookup("<name>").data.<key> }}' placeholders in the string values of the targets
+	// with the key of the ConfigMap with that name in the namespace of each target. The namespaces missing the
+	// ConfigMap or the key are rejected
+	LookupValues bool `json:"lookupValues,omitempty"`
+
+	// Outputs composes more objects from the data of a ConfigMap or Secret source in each target namespace,
+	// like a ConfigMap holding only the public certificate of a TLS Secret, without a second Replika
+	Outputs []ReplikaOutputSpec `json:"outputs,omitempty"`
+
+	// Anchor creates a ConfigMap in each target namespace owning the target, so the garbage
+	// collector of Kubernetes deletes the target when the anchor is removed
+	Anchor bool `json:"anchor,omitempty"`
+
+	// RecreateImmutable deletes and creates again the targets that can not be updated in place, like immutable
+	// ConfigMaps and Secrets whose data differs from the source. Those targets are refused otherwise
+	RecreateImmutable bool `json:"recreateImmutable,omitempty"`
+
+	// DeletionPropagation decides whether the dependents of the targets are deleted along with them
+	// ('Background' or 'Foreground') or orphaned ('Orphan') when the Replika deletes its copies.
+	// The default policy of each kind is used when empty
+	//+kubebuilder:validation:Enum=Background;Foreground;Orphan
+	DeletionPropagation string `json:"deletionPropagation,omitempty"`
+
+	// Rollout defines how the source is rolled out across the targets
+	Rollout ReplikaRolloutSpec `json:"rollout,omitempty"`
+
+	// MaxTargets is the maximum number of namespaces the source can be replicated in.
+	// The synchronization is refused when exceeded. Zero means no limit other than the operator one
+	//+kubebuilder:validation:Minimum=0
+	MaxTargets int `json:"maxTargets,omitempty"`
+}
+
+// ReplikaOutputKeySpec defines a key of the data of the source copied into an output
+type ReplikaOutputKeySpec struct {
+	// From is the key in the data of the source
+	From string `json:"from"`
+
+	// To is the key in the output, the same as from when empty
+	To string `json:"to,omitempty"`
+}
+
+// ReplikaOutputSpec defines an object composed from the data of the source. The values are converted
+// between the kinds, so a key of a Secret is readable in a ConfigMap output and the other way around
+type ReplikaOutputSpec struct {
+	//+kubebuilder:validation:Enum=ConfigMap;Secret
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+
+	// Type of the Secret outputs, Opaque when empty
+	Type string `json:"type,omitempty"`
+
+	// Keys copied from the data of the source, all of them when empty. The synchronization fails when one is missing
+	Keys []ReplikaOutputKeySpec `json:"keys,omitempty"`
+}
+
+// ReplikaSourceSpec defines the spec of the source section of a Replika
+type ReplikaSourceSpec struct {
+	// Group and Version can be omitted for Secrets and ConfigMaps, being defaulted to core/v1
+	Group   string `json:"group,omitempty"`
+	Version string `json:"version,omitempty"`
+	//+kubebuilder:validation:MinLength=1
+	Kind string `json:"kind,omitempty"`
+	//+kubebuilder:validation:MinLength=1
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+
+	// Inline is a manifest embedded in the Replika, replicated instead of fetching the source from the cluster.
+	// Group, version, kind and name are taken from it when omitted
+	//+kubebuilder:pruning:PreserveUnknownFields
+	//+kubebuilder:validation:EmbeddedResource
+	Inline *runtime.RawExtension `json:"inline,omitempty"`
+
+	// Fields restricts the replication of spec.source to the subtrees selected by the paths,
+	// like .spec.template.metadata.labels. The whole object is replicated when empty
+	Fields []string `json:"fields,omitempty"`
+
+	// ReadyWhen is a CEL expression evaluated against the source, available as 'object', holding the replication
+	// until it returns true. Example: object.status.conditions.exists(c, c.type == 'Ready' && c.status == 'True')
+	ReadyWhen string `json:"readyWhen,omitempty"`
+
+	// ValidateTLS checks that the certificate of a 'kubernetes.io/tls' Secret parses, matches its key and is not expired
+	// before replicating it. The synchronization is refused otherwise
+	ValidateTLS bool `json:"validateTLS,omitempty"`
+
+	// ExpiryWarning sets the CertificateExpiringSoon condition when the certificate of a 'kubernetes.io/tls' Secret
+	// expires within the duration, like 720h. The days left are exported as a metric
+	//+kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|ms|s|m|h))+$`
+	ExpiryWarning string `json:"expiryWarning,omitempty"`
+
+	// SynchronizationTime is the time between two reads of this source. The Replika is synchronized at the
+	// shortest time of its sources, taking the sources read more recently than their own time from the last read
+	//+kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|ms|s|m|h))+$`
+	SynchronizationTime string `json:"synchronizationTime,omitempty"`
+
+	// OnDelete decides what happens to the targets once the source is deleted: 'Retain' keeps them and fails
+	// the synchronizations, 'Freeze' keeps them with the last content of the source until it is back, and
+	// 'DeleteTargets' deletes them once the grace period expires. Only read on spec.source
+	//+kubebuilder:validation:Enum=Retain;DeleteTargets;Freeze
+	OnDelete string `json:"onDelete,omitempty"`
+
+	// DeletionGracePeriod is the time the targets are kept once the source is missing with the 'DeleteTargets'
+	// policy, like 24h. It is required with that policy, so the targets are never deleted at once
+	DeletionGracePeriod string `json:"deletionGracePeriod,omitempty"`
+}
+
+// ReplikaMergePolicySpec defines how the data of several sources is merged into the targets
+type ReplikaMergePolicySpec struct {
+	// Precedence decides which source wins when a key is defined by several of them:
+	// 'First' or 'Last' in the order of spec.source followed by spec.sources
+	//+kubebuilder:validation:Enum=First;Last
+	//+kubebuilder:default=Last
+	Precedence string `json:"precedence,omitempty"`
+
+	// FailOnConflict refuses the synchronization when a key is defined with different values by several sources
+	FailOnConflict bool `json:"failOnConflict,omitempty"`
+}
+
+// ReplikaAggregationDestinationSpec defines the object where the collected sources are merged
+type ReplikaAggregationDestinationSpec struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// ReplikaAggregationSpec defines the collection of the sources across namespaces into a single destination.
+// The objects of the kind of spec.source named as spec.source.name are collected from the selected namespaces
+type ReplikaAggregationSpec struct {
+	// Namespaces where the sources are collected from. The destination namespace is never included
+	Namespaces ReplikaTargetNamespacesSpec `json:"namespaces"`
+
+	// Selector restricts the collected sources to those matching the labels
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Destination is the object where the sources are merged following spec.mergePolicy
+	Destination ReplikaAggregationDestinationSpec `json:"destination"`
+}
+
+// ReplikaHookSpec defines a Job run around the synchronization, once per revision of the source
+type ReplikaHookSpec struct {
+	// ServiceAccountName is the ServiceAccount of the namespace of the Replika running the Job.
+	// It must exist, so the Job only gets the permissions granted to it
+	ServiceAccountName string `json:"serviceAccountName"`
+
+	// Template of the Job, created in the namespace of the Replika
+	//+kubebuilder:validation:Schemaless
+	//+kubebuilder:pruning:PreserveUnknownFields
+	Template batchv1.JobTemplateSpec `json:"template"`
+}
+
+// ReplikaHTTPHookSpec defines an HTTP endpoint notified around each synchronization
+type ReplikaHTTPHookSpec struct {
+	// URL receiving a POST with the Replika, the phase, the outcome and the target namespaces as JSON
+	//+kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// Phases where the endpoint is called: PreSync and PostSync. Both of them when empty
+	Phases []string `json:"phases,omitempty"`
+
+	// Timeout of each call, 10s when empty and 30s at most
+	//+kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|ms|s|m|h))+$`
+	Timeout string `json:"timeout,omitempty"`
+
+	// FailurePolicy decides whether a failing call fails the synchronization ('Fail') or is only logged ('Ignore')
+	//+kubebuilder:validation:Enum=Fail;Ignore
+	//+kubebuilder:default=Ignore
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+}
+
+// HasPhase return true when the endpoint is called on the phase
+func (h *ReplikaHTTPHookSpec) HasPhase(phase string) bool {
+	if len(h.Phases) == 0 {
+		return true
+	}
+	for _, v := range h.Phases {
+		if strings.EqualFold(v, phase) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplikaHooksSpec defines the Jobs and HTTP endpoints run around the synchronization
+type ReplikaHooksSpec struct {
+	// PreSync must complete before the targets are written
+	PreSync *ReplikaHookSpec `json:"preSync,omitempty"`
+
+	// PostSync runs once all the targets are written
+	PostSync *ReplikaHookSpec `json:"postSync,omitempty"`
+
+	// HTTP endpoints notified before and after each synchronization
+	HTTP []ReplikaHTTPHookSpec `json:"http,omitempty"`
+}
+
+// ReplikaSpec defines the desired state of a Replika
+type ReplikaSpec struct {
+
+	// SynchronizationSpec defines the behavior of synchronization
+	Synchronization SynchronizationSpec `json:"synchronization"`
+
+	// ReplikaSourceSpec define the source resource
+	Source ReplikaSourceSpec `json:"source,omitempty"`
+
+	// Sources are merged into the targets after spec.source. They must have the same group and kind
+	Sources []ReplikaSourceSpec `json:"sources,omitempty"`
+
+	// MergePolicy defines how spec.source and spec.sources are merged
+	MergePolicy ReplikaMergePolicySpec `json:"mergePolicy,omitempty"`
+
+	// Aggregation collects the sources from several namespaces into a single destination instead of
+	// replicating spec.source into spec.target. The namespaces of spec.target are ignored when set
+	Aggregation *ReplikaAggregationSpec `json:"aggregation,omitempty"`
+
+	// ReplikaTargetSpec defines the target [...]
+	Target ReplikaTargetSpec `json:"target"`
+
+	// Hooks are the Jobs run around the synchronization. The Jobs only run when the operator enables them
+	Hooks ReplikaHooksSpec `json:"hooks,omitempty"`
+
+	// Priority of the Replika when several of them are waiting to be synchronized. Higher goes first
+	Priority int32 `json:"priority,omitempty"`
+}
+
+// ReplikaNamespaceStatus defines the state of the target inside a single namespace
+type ReplikaNamespaceStatus struct {
+	Namespace string `json:"namespace"`
+	Reason    string `json:"reason"`
+	Message   string `json:"message,omitempty"`
+
+	// Failures is the number of consecutive failures of the namespace while it is backed off on its own
+	Failures int `json:"failures,omitempty"`
+
+	// RetryTime is the time when the namespace is written again, the rest of the targets being synchronized meanwhile
+	RetryTime *metav1.Time `json:"retryTime,omitempty"`
+}
+
+// ReplikaConsumerStatus defines a workload referencing a target
+type ReplikaConsumerStatus struct {
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+}
+
+// ReplikaDriftStatus defines who modified a drifted target, as recorded in its managed fields
+type ReplikaDriftStatus struct {
+	Namespace string `json:"namespace"`
+
+	// Manager is the field manager of the last change not made by the controller
+	Manager   string       `json:"manager,omitempty"`
+	Operation string       `json:"operation,omitempty"`
+	Time      *metav1.Time `json:"time,omitempty"`
+}
+
+// ReplikaIntegrityStatus defines the result of the last audit of the targets
+type ReplikaIntegrityStatus struct {
+	LastAuditTime     metav1.Time `json:"lastAuditTime"`
+	Targets           int         `json:"targets"`
+	DriftedNamespaces []string    `json:"driftedNamespaces,omitempty"`
+	MissingNamespaces []string    `json:"missingNamespaces,omitempty"`
+
+	// DriftedTargets identifies who modified each drifted target
+	DriftedTargets []ReplikaDriftStatus `json:"driftedTargets,omitempty"`
+}
+
+// ReplikaCanaryStatus defines the state of the canary namespace
+type ReplikaCanaryStatus struct {
+	// Revision is the hash of the target written in the canary namespace
+	Revision string `json:"revision"`
+
+	// WriteTime is the time the revision was written in the canary namespace
+	WriteTime metav1.Time `json:"writeTime"`
+
+	// Verified is true when the revision passed the verification, so it can be rolled out
+	Verified bool `json:"verified"`
+}
+
+// ReplikaMergeConflictStatus defines a key defined with different values by several sources
+type ReplikaMergeConflictStatus struct {
+	Field string `json:"field"`
+	Key   string `json:"key"`
+
+	// Sources defining the key as namespace/name, the winning one being the last
+	Sources []string `json:"sources"`
+}
+
+// ReplikaInventoryEntry defines an object written by the controller for a Replika
+type ReplikaInventoryEntry struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+}
+
+// ReplikaDeletionPreviewStatus defines the objects deleted along with the Replika
+type ReplikaDeletionPreviewStatus struct {
+	// Time of the preview
+	Time metav1.Time `json:"time"`
+
+	// Targets is the number of objects that would be deleted
+	Targets int `json:"targets"`
+
+	// Objects that would be deleted as namespace/name. Only the first ones are listed on long lists
+	Objects []string `json:"objects,omitempty"`
+}
+
+// ReplikaStatus defines the observed state of a Replika
+type ReplikaStatus struct {
+
+	// Conditions represent the latest available observations of an object's state
+	Conditions []metav1.Condition `json:"conditions"`
+
+	// SourceRef identifies the replicated source as group/version/Kind/namespace/name
+	SourceRef string `json:"sourceRef,omitempty"`
+
+	// SourceHash is the hash of the source as it is distributed, once its fields are projected and its labels stripped
+	SourceHash string `json:"sourceHash,omitempty"`
+
+	// SourceImmutable is true when the distributed ConfigMap or Secret is immutable. Its targets can only
+	// change by recreating them, enabled by spec.target.recreateImmutable
+	SourceImmutable bool `json:"sourceImmutable,omitempty"`
+
+	// SourceMissingTime is the time the source was found missing, cleared once it is back
+	SourceMissingTime *metav1.Time `json:"sourceMissingTime,omitempty"`
+
+	// SyncedTargets is the number of targets written on the last synchronization
+	SyncedTargets int `json:"syncedTargets,omitempty"`
+
+	// TotalTargets is the number of targets computed on the last synchronization
+	TotalTargets int `json:"totalTargets,omitempty"`
+
+	// FailedTargets is the number of targets that failed on the last synchronization
+	FailedTargets int `json:"failedTargets,omitempty"`
+
+	// SyncedNamespaces lists the namespaces written on the last synchronization.
+	// Only the first ones are listed on long lists, syncedTargets keeping the total
+	SyncedNamespaces []string `json:"syncedNamespaces,omitempty"`
+
+	// FailedNamespaces lists the namespaces that failed on the last synchronization.
+	// Only the first ones are listed on long lists, failedTargets keeping the total
+	FailedNamespaces []string `json:"failedNamespaces,omitempty"`
+
+	// LastSyncTime is the last time the source was synchronized, whatever the result
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// LastSuccessfulSyncTime is the last time the source was successfully synchronized
+	LastSuccessfulSyncTime *metav1.Time `json:"lastSuccessfulSyncTime,omitempty"`
+
+	// LastError is the error of the last synchronization, empty when it succeeded
+	LastError string `json:"lastError,omitempty"`
+
+	// LastErrorTime is the time LastError happened
+	LastErrorTime *metav1.Time `json:"lastErrorTime,omitempty"`
+
+	// QueueWait is the time the Replika waited in the queue for a free worker before its last reconciliation.
+	// Long waits mean the controller needs more workers for the number of Replikas
+	QueueWait *metav1.Duration `json:"queueWait,omitempty"`
+
+	// ReconcileDuration is the time taken by the last reconciliation
+	ReconcileDuration *metav1.Duration `json:"reconcileDuration,omitempty"`
+
+	// RejectedNamespaces lists the namespaces where the target was rejected because of its size,
+	// during the dry-run validation or by an admission policy, with the message of the denial
+	RejectedNamespaces []ReplikaNamespaceStatus `json:"rejectedNamespaces,omitempty"`
+
+	// Consumers lists the workloads referencing the targets, discovered when spec.target.discoverConsumers is enabled.
+	// Only the first ones are listed, ConsumersCount holds the number of all of them
+	Consumers []ReplikaConsumerStatus `json:"consumers,omitempty"`
+
+	// ConsumersCount is the number of workloads referencing the targets
+	ConsumersCount int `json:"consumersCount,omitempty"`
+
+	// ConsumersScanTime is the last time the consumers were discovered
+	ConsumersScanTime *metav1.Time `json:"consumersScanTime,omitempty"`
+
+	// HookRevisions are the revisions of the source each hook phase completed for, so the hooks are not run
+	// again once their Jobs are removed. The 'http' key holds the revision of the targets last notified
+	// to the HTTP hooks
+	HookRevisions map[string]string `json:"hookRevisions,omitempty"`
+
+	// Integrity summarizes the last audit of the targets against the source
+	Integrity *ReplikaIntegrityStatus `json:"integrity,omitempty"`
+
+	// MergeConflicts lists the keys defined with different values by several sources on the last synchronization
+	MergeConflicts []ReplikaMergeConflictStatus `json:"mergeConflicts,omitempty"`
+
+	// Canary is the state of the canary namespace, when defined
+	Canary *ReplikaCanaryStatus `json:"canary,omitempty"`
+
+	// DeletionPreview lists the objects that would be deleted along with the Replika,
+	// computed while the annotation replika.prosimcorp.com/deletion-preview is 'true'
+	DeletionPreview *ReplikaDeletionPreviewStatus `json:"deletionPreview,omitempty"`
+
+	// Inventory lists the objects written by the controller and not deleted yet. The targets no longer
+	// computed are pruned from it, and all of them are deleted along with the Replika
+	Inventory []ReplikaInventoryEntry `json:"inventory,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Namespaced,categories={replikas}
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description=""
+//+kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[?(@.type==\"SourceSynced\")].reason",description=""
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description=""
+//+kubebuilder:printcolumn:name="Source",type="string",JSONPath=".status.sourceRef",priority=1,description=""
+//+kubebuilder:printcolumn:name="Synced",type="integer",JSONPath=".status.syncedTargets",priority=1,description=""
+//+kubebuilder:printcolumn:name="Targets",type="integer",JSONPath=".status.totalTargets",priority=1,description=""
+//+kubebuilder:printcolumn:name="Last Sync",type="date",JSONPath=".status.lastSyncTime",priority=1,description=""
+//+kubebuilder:printcolumn:name="Last Error",type="string",JSONPath=".status.lastError",priority=1,description=""
+
+// Replika is the Schema for the each Replika CR
+type Replika struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ReplikaSpec   `json:"spec,omitempty"`
+	Status ReplikaStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ReplikaList contains a list of Replika resources
+type ReplikaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Replika `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Replika{}, &ReplikaList{})
+}
+
+// SetSourceDefaults fill the group and version of the sources when omitted for the core kinds
+func (r *Replika) SetSourceDefaults() {
+	r.Spec.Source.SetDefaults()
+	for i := range r.Spec.Sources {
+		r.Spec.Sources[i].SetDefaults()
+	}
+}
+
+// SetDefaults fill the group and version of the source when omitted for the core kinds.
+// The identity of the inline sources is taken from their manifest
+func (s *ReplikaSourceSpec) SetDefaults() {
+	if inline, err := s.GetInlineObject(); err == nil && inline != nil {
+		gvk := inline.GroupVersionKind()
+		if s.Kind == "" {
+			s.Group, s.Version, s.Kind = gvk.Group, gvk.Version, gvk.Kind
+		}
+		if s.Name == "" {
+			s.Name = inline.GetName()
+		}
+	}
+
+	switch s.Kind {
+	case "Secret", "ConfigMap":
+		if s.Group == "" && s.Version == "" {
+			s.Version = "v1"
+		}
+	}
+}
+
+// GetInlineObject return the manifest embedded in the source, nil when the source is fetched from the cluster
+func (s *ReplikaSourceSpec) GetInlineObject() (inline *unstructured.Unstructured, err error) {
+	if s.Inline == nil || len(s.Inline.Raw) == 0 {
+		return inline, err
+	}
+
+	inline = &unstructured.Unstructured{}
+	err = inline.UnmarshalJSON(s.Inline.Raw)
+	if err != nil {
+		return nil, err
+	}
+	return inline, err
+}
+
+// GetGroupVersionKind return the kind of the source
+func (s *ReplikaSourceSpec) GetGroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: s.Group, Version: s.Version, Kind: s.Kind}
+}
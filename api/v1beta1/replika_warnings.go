@@ -0,0 +1,131 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// WarningWebhookPath is the path where the warnings of the Replikas are served
+	WarningWebhookPath = "/warn-replika-prosimcorp-com-replika"
+
+	deprecatedVersionWarning = "%s %s is deprecated, use %s instead"
+	missingNamespaceWarning  = "%s: namespace %s does not exist, no target is written there until it is created"
+)
+
+// deprecatedVersions maps the versions of the Replika no longer recommended to their replacement.
+// The stored version is never listed, it is only deprecated once another version is stored instead
+var deprecatedVersions = map[string]string{}
+
+// replikaWarning defines a check over the spec of a Replika producing an admission warning
+type replikaWarning struct {
+	isSet   func(replika *Replika) bool
+	message string
+}
+
+// replikaWarnings are checked on each creation or update of a Replika.
+// Deprecated fields are added here pointing to their replacement before being removed
+var replikaWarnings = []replikaWarning{
+	{
+		isSet: func(replika *Replika) bool {
+			return replika.Spec.Target.Namespaces.MatchAll && replika.Spec.Target.Namespaces.CELExpression != ""
+		},
+		message: "spec.target.namespaces.matchAll is ignored when spec.target.namespaces.celExpression is set",
+	},
+}
+
+// ReplikaWarningHandler returns admission warnings for deprecated versions and fields of the Replikas.
+// It never denies a request, so it can be registered for every version with failurePolicy=Ignore.
+// When the Reader is set, the namespaces listed by name that do not exist are warned too
+type ReplikaWarningHandler struct {
+	Reader client.Reader
+}
+
+//+kubebuilder:webhook:path=/warn-replika-prosimcorp-com-replika,mutating=false,failurePolicy=ignore,sideEffects=None,groups=replika.prosimcorp.com,resources=replikas,verbs=create;update,versions=*,name=wreplika.kb.io,admissionReviewVersions=v1
+
+var _ admission.Handler = &ReplikaWarningHandler{}
+
+// Handle implements admission.Handler
+func (h *ReplikaWarningHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+
+	response := admission.Allowed("")
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return response
+	}
+
+	if replacement, deprecated := deprecatedVersions[req.Kind.Version]; deprecated {
+		response = response.WithWarnings(fmt.Sprintf(deprecatedVersionWarning,
+			req.Kind.Group+"/"+req.Kind.Version, req.Kind.Kind, req.Kind.Group+"/"+replacement))
+	}
+
+	// Fields are compared on top of the current version, as previous ones are a subset of it
+	replika := &Replika{}
+	if err := json.Unmarshal(req.Object.Raw, replika); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	for _, warning := range replikaWarnings {
+		if warning.isSet(replika) {
+			response = response.WithWarnings(warning.message)
+		}
+	}
+
+	if warnings := h.missingNamespaceWarnings(ctx, replika); len(warnings) > 0 {
+		response = response.WithWarnings(warnings...)
+	}
+
+	return response
+}
+
+// missingNamespaceWarnings return a warning for each namespace listed in replicateIn or excludeFrom that does
+// not exist, catching the typos that would silently leave a namespace without its target. Namespaces that can
+// not be checked are not warned, as the warnings must never block the request
+func (h *ReplikaWarningHandler) missingNamespaceWarnings(ctx context.Context, replika *Replika) (warnings []string) {
+
+	if h.Reader == nil {
+		return warnings
+	}
+
+	check := func(path string, namespaces []string) {
+		for i, ns := range namespaces {
+			err := h.Reader.Get(ctx, types.NamespacedName{Name: ns}, &corev1.Namespace{})
+			if apierrors.IsNotFound(err) {
+				warnings = append(warnings, fmt.Sprintf(missingNamespaceWarning, fmt.Sprintf("%s[%d]", path, i), ns))
+			}
+		}
+	}
+
+	check("spec.target.namespaces.replicateIn", replika.Spec.Target.Namespaces.ReplicateIn)
+	check("spec.target.namespaces.excludeFrom", replika.Spec.Target.Namespaces.ExcludeFrom)
+	if replika.Spec.Aggregation != nil {
+		check("spec.aggregation.namespaces.replicateIn", replika.Spec.Aggregation.Namespaces.ReplicateIn)
+		check("spec.aggregation.namespaces.excludeFrom", replika.Spec.Aggregation.Namespaces.ExcludeFrom)
+	}
+
+	return warnings
+}
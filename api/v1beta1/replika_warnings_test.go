@@ -0,0 +1,96 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestReplikaWarningHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  string
+		edit     func(r *Replika)
+		expected []string
+	}{
+		{
+			name:    "stored version is not deprecated",
+			version: "v1beta1",
+			edit:    func(r *Replika) {},
+		},
+		{
+			name:    "served version is not deprecated",
+			version: "v1",
+			edit:    func(r *Replika) {},
+		},
+		{
+			name:    "missing namespace",
+			version: "v1beta1",
+			edit:    func(r *Replika) { r.Spec.Target.Namespaces.ReplicateIn = []string{"team-a", "team-c"} },
+			expected: []string{
+				"spec.target.namespaces.replicateIn[1]: namespace team-c does not exist, no target is written there until it is created",
+			},
+		},
+		{
+			name:    "matchAll ignored",
+			version: "v1beta1",
+			edit: func(r *Replika) {
+				r.Spec.Target.Namespaces = ReplikaTargetNamespacesSpec{MatchAll: true, CELExpression: "true"}
+			},
+			expected: []string{"spec.target.namespaces.matchAll is ignored when spec.target.namespaces.celExpression is set"},
+		},
+	}
+
+	reader := fake.NewClientBuilder().WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}},
+	).Build()
+	handler := &ReplikaWarningHandler{Reader: reader}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			replika := newReplika()
+			test.edit(replika)
+			raw, err := json.Marshal(replika)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			response := handler.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Create,
+				Kind:      metav1.GroupVersionKind{Group: GroupVersion.Group, Version: test.version, Kind: "Replika"},
+				Object:    runtime.RawExtension{Raw: raw},
+			}})
+			if !response.Allowed {
+				t.Fatalf("expected the request to be allowed, got %v", response.Result)
+			}
+			if !reflect.DeepEqual(response.Warnings, test.expected) {
+				t.Errorf("expected warnings %v, got %v", test.expected, response.Warnings)
+			}
+		})
+	}
+}
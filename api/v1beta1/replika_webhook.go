@@ -0,0 +1,390 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"regexp"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"prosimcorp.com/replika/pkg/celselector"
+	"prosimcorp.com/replika/pkg/replicator"
+)
+
+const (
+	namespaceRegularExpression = "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
+)
+
+// log is for logging in this package.
+var replikalog = logf.Log.WithName("replika-resource")
+
+// sourceMapper resolves the scope of the kinds of the sources. The scope is not checked when it is not set
+var sourceMapper meta.RESTMapper
+
+// SetupWebhookWithManager registers the webhooks of the Replika in the manager
+func (r *Replika) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	sourceMapper = mgr.GetRESTMapper()
+	mgr.GetWebhookServer().Register(WarningWebhookPath, &webhook.Admission{Handler: &ReplikaWarningHandler{Reader: mgr.GetClient()}})
+
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-replika-prosimcorp-com-v1beta1-replika,mutating=true,failurePolicy=fail,sideEffects=None,groups=replika.prosimcorp.com,resources=replikas,verbs=create;update,versions=v1beta1,name=mreplika.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &Replika{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *Replika) Default() {
+	replikalog.Info("default", "name", r.Name)
+
+	r.SetSourceDefaults()
+}
+
+//+kubebuilder:webhook:path=/validate-replika-prosimcorp-com-v1beta1-replika,mutating=false,failurePolicy=fail,sideEffects=None,groups=replika.prosimcorp.com,resources=replikas,verbs=create;update,versions=v1beta1,name=vreplika.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &Replika{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Replika) ValidateCreate() error {
+	replikalog.Info("validate create", "name", r.Name)
+
+	return r.validateReplika()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Replika) ValidateUpdate(old runtime.Object) error {
+	replikalog.Info("validate update", "name", r.Name)
+
+	// Let through the updates not touching the spec, like removing the finalizers of a deleted Replika,
+	// so the Replikas accepted under older rules are never stuck
+	oldReplika := old.(*Replika)
+	if r.DeletionTimestamp != nil || equality.Semantic.DeepEqual(oldReplika.Spec, r.Spec) {
+		return nil
+	}
+
+	err := r.validateReplika()
+	if err != nil {
+		return err
+	}
+
+	return r.validateImmutableUpdate(oldReplika)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Replika) ValidateDelete() error {
+	return nil
+}
+
+// validateReplika return an error listing all the invalid fields of the Replika
+func (r *Replika) validateReplika() error {
+	var allErrs field.ErrorList
+
+	specPath := field.NewPath("spec")
+
+	// Synchronization time must be a valid duration
+	if _, err := time.ParseDuration(r.Spec.Synchronization.Time); r.Spec.Synchronization.Time != "" && err != nil {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("synchronization", "time"),
+			r.Spec.Synchronization.Time, "must be a valid duration"))
+	}
+
+	// Deletion grace period of the source must be a valid duration
+	if _, err := time.ParseDuration(r.Spec.Source.DeletionGracePeriod); r.Spec.Source.DeletionGracePeriod != "" && err != nil {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("source", "deletionGracePeriod"),
+			r.Spec.Source.DeletionGracePeriod, "must be a valid duration"))
+	}
+
+	// Deleting the targets requires a grace period, so a source briefly missing never removes them at once
+	if r.Spec.Source.OnDelete == "DeleteTargets" {
+		gracePeriod, err := time.ParseDuration(r.Spec.Source.DeletionGracePeriod)
+		if r.Spec.Source.DeletionGracePeriod == "" {
+			allErrs = append(allErrs, field.Required(specPath.Child("source", "deletionGracePeriod"),
+				"must be set when onDelete is DeleteTargets"))
+		} else if err == nil && gracePeriod <= 0 {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("source", "deletionGracePeriod"),
+				r.Spec.Source.DeletionGracePeriod, "must be greater than zero when onDelete is DeleteTargets"))
+		}
+	}
+
+	// Request timeout must be a positive duration
+	if r.Spec.Synchronization.RequestTimeout != "" {
+		requestTimeout, err := time.ParseDuration(r.Spec.Synchronization.RequestTimeout)
+		if err != nil || requestTimeout <= 0 {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("synchronization", "requestTimeout"),
+				r.Spec.Synchronization.RequestTimeout, "must be a positive duration"))
+		}
+	}
+
+	// Synchronization window must have valid times and time zone
+	if window := r.Spec.Synchronization.Window; window != nil {
+		if _, _, err := window.GetEnd(time.Now()); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("synchronization", "window"),
+				*window, err.Error()))
+		}
+	}
+
+	// Sources must be identified, either by their kind and name or by an inline manifest
+	sourcePaths := []*field.Path{specPath.Child("source")}
+	sourceSpecs := []ReplikaSourceSpec{r.Spec.Source}
+	for i := range r.Spec.Sources {
+		sourcePaths = append(sourcePaths, specPath.Child("sources").Index(i))
+		sourceSpecs = append(sourceSpecs, r.Spec.Sources[i])
+	}
+	for i, source := range sourceSpecs {
+		source.SetDefaults()
+		if _, err := source.GetInlineObject(); err != nil {
+			allErrs = append(allErrs, field.Invalid(sourcePaths[i].Child("inline"), string(source.Inline.Raw), err.Error()))
+			continue
+		}
+		if source.Kind == "" {
+			allErrs = append(allErrs, field.Required(sourcePaths[i].Child("kind"), "must be set when the source is not inline"))
+		}
+		if source.Name == "" {
+			allErrs = append(allErrs, field.Required(sourcePaths[i].Child("name"), "must be set when the source is not inline"))
+		}
+
+		if source.ReadyWhen != "" {
+			if _, err := celselector.CompileFor(celselector.ObjectVariable, source.ReadyWhen); err != nil {
+				allErrs = append(allErrs, field.Invalid(sourcePaths[i].Child("readyWhen"), source.ReadyWhen, err.Error()))
+			}
+		}
+
+		// Cluster-scoped kinds have no namespace to be copied from or into. Unknown kinds are not refused,
+		// as their definition can be installed after the Replika
+		if source.Kind != "" && IsClusterScoped(sourceMapper, source.GetGroupVersionKind()) {
+			allErrs = append(allErrs, field.Invalid(sourcePaths[i].Child("kind"), source.Kind,
+				"is cluster-scoped, only namespaced kinds can be replicated across namespaces"))
+		}
+	}
+
+	// Field paths must be well formatted
+	for i, path := range r.Spec.Source.Fields {
+		if _, err := replicator.ParseFieldPath(path); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("source", "fields").Index(i), path, err.Error()))
+		}
+	}
+
+	// Merged sources must be of the same kind as the main one
+	for i, source := range r.Spec.Sources {
+		if source.Group != r.Spec.Source.Group || source.Kind != r.Spec.Source.Kind {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("sources").Index(i).Child("kind"),
+				source.Kind, "must have the same group and kind as spec.source"))
+		}
+	}
+
+	// Only the ConfigMaps and Secrets can be referenced by the Pods, so only them can be replicated on demand
+	if r.Spec.Target.OnDemand && (r.Spec.Source.Group != "" || (r.Spec.Source.Kind != "ConfigMap" && r.Spec.Source.Kind != "Secret")) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("target", "onDemand"), r.Spec.Target.OnDemand,
+			"is only supported for the ConfigMaps and Secrets"))
+	}
+
+	// Namespaces must be well formatted, and the source namespace is never a target
+	expression := regexp.MustCompile(namespaceRegularExpression)
+	namespacesPath := specPath.Child("target", "namespaces")
+
+	for i, ns := range r.Spec.Target.Namespaces.ReplicateIn {
+		if !expression.MatchString(ns) {
+			allErrs = append(allErrs, field.Invalid(namespacesPath.Child("replicateIn").Index(i), ns, "must be a valid namespace name"))
+		}
+		if ns == r.Spec.Source.Namespace {
+			allErrs = append(allErrs, field.Invalid(namespacesPath.Child("replicateIn").Index(i), ns, "must be different from the source namespace"))
+		}
+	}
+
+	for i, ns := range r.Spec.Target.Namespaces.ExcludeFrom {
+		if !expression.MatchString(ns) {
+			allErrs = append(allErrs, field.Invalid(namespacesPath.Child("excludeFrom").Index(i), ns, "must be a valid namespace name"))
+		}
+	}
+
+	// The lists of namespaces must not contradict each other
+	allErrs = append(allErrs, validateNamespacesSelection(namespacesPath, r.Spec.Target.Namespaces)...)
+	if r.Spec.Aggregation != nil {
+		allErrs = append(allErrs, validateNamespacesSelection(specPath.Child("aggregation", "namespaces"), r.Spec.Aggregation.Namespaces)...)
+	}
+
+	// The destination of the aggregation must be a valid namespace
+	if r.Spec.Aggregation != nil && !expression.MatchString(r.Spec.Aggregation.Destination.Namespace) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("aggregation", "destination", "namespace"),
+			r.Spec.Aggregation.Destination.Namespace, "must be a valid namespace name"))
+	}
+
+	if expression := r.Spec.Target.Namespaces.CELExpression; expression != "" {
+		if _, err := celselector.Compile(expression); err != nil {
+			allErrs = append(allErrs, field.Invalid(namespacesPath.Child("celExpression"), expression, err.Error()))
+		}
+	}
+
+	// Hook Jobs run as a ServiceAccount of the namespace of the Replika, never with access to the nodes
+	hooksPath := specPath.Child("hooks")
+	if r.Spec.Hooks.PreSync != nil {
+		allErrs = append(allErrs, validateHook(hooksPath.Child("preSync"), r.Spec.Hooks.PreSync)...)
+	}
+	if r.Spec.Hooks.PostSync != nil {
+		allErrs = append(allErrs, validateHook(hooksPath.Child("postSync"), r.Spec.Hooks.PostSync)...)
+	}
+
+	// HTTP hooks are called inside the synchronization, so they can never hold it for long
+	for i, hook := range r.Spec.Hooks.HTTP {
+		if hook.Timeout == "" {
+			continue
+		}
+		if timeout, err := time.ParseDuration(hook.Timeout); err != nil || timeout <= 0 || timeout > MaxHTTPHookTimeout {
+			allErrs = append(allErrs, field.Invalid(hooksPath.Child("http").Index(i).Child("timeout"), hook.Timeout,
+				"must be a duration greater than zero and up to "+MaxHTTPHookTimeout.String()))
+		}
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind("Replika").GroupKind(), r.Name, allErrs)
+}
+
+// validateHook return the errors of a hook running with other identity than its ServiceAccount,
+// or reaching the nodes through the host namespaces, the host paths or privileged containers
+func validateHook(path *field.Path, hook *ReplikaHookSpec) (allErrs field.ErrorList) {
+
+	serviceAccountPath := path.Child("serviceAccountName")
+	if hook.ServiceAccountName == "" {
+		allErrs = append(allErrs, field.Required(serviceAccountPath, "the Job runs as this ServiceAccount"))
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(hook.ServiceAccountName) {
+		allErrs = append(allErrs, field.Invalid(serviceAccountPath, hook.ServiceAccountName, msg))
+	}
+
+	podPath := path.Child("template", "spec", "template", "spec")
+	podSpec := hook.Template.Spec.Template.Spec
+	for _, name := range []string{podSpec.ServiceAccountName, podSpec.DeprecatedServiceAccount} {
+		if name != "" && name != hook.ServiceAccountName {
+			allErrs = append(allErrs, field.Forbidden(podPath.Child("serviceAccountName"),
+				"must be empty or equal to "+serviceAccountPath.String()))
+			break
+		}
+	}
+
+	if podSpec.HostNetwork || podSpec.HostPID || podSpec.HostIPC {
+		allErrs = append(allErrs, field.Forbidden(podPath, "the host namespaces can not be used by the hooks"))
+	}
+	for i, volume := range podSpec.Volumes {
+		if volume.HostPath != nil {
+			allErrs = append(allErrs, field.Forbidden(podPath.Child("volumes").Index(i).Child("hostPath"),
+				"the host paths can not be mounted by the hooks"))
+		}
+	}
+	checkPrivileged := func(child string, containers []corev1.Container) {
+		for i, container := range containers {
+			if container.SecurityContext != nil && container.SecurityContext.Privileged != nil && *container.SecurityContext.Privileged {
+				allErrs = append(allErrs, field.Forbidden(podPath.Child(child).Index(i).Child("securityContext", "privileged"),
+					"the hooks can not run privileged containers"))
+			}
+		}
+	}
+	checkPrivileged("initContainers", podSpec.InitContainers)
+	checkPrivileged("containers", podSpec.Containers)
+
+	return allErrs
+}
+
+// validateImmutableUpdate refuse the edits of the sources of a Replika distributing an immutable object that change
+// the distributed data, as they only take effect by recreating the targets. The rest of the fields, like the
+// synchronization time, are always accepted, and so is everything once spec.target.recreateImmutable is enabled
+func (r *Replika) validateImmutableUpdate(old *Replika) error {
+	if !old.Status.SourceImmutable || r.Spec.Target.RecreateImmutable {
+		return nil
+	}
+	if equality.Semantic.DeepEqual(getSourcesData(old), getSourcesData(r)) {
+		return nil
+	}
+
+	allErrs := field.ErrorList{field.Forbidden(field.NewPath("spec", "source"),
+		"the source is immutable, so the data of its targets can not be updated in place, enable spec.target.recreateImmutable to replace them")}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Replika").GroupKind(), r.Name, allErrs)
+}
+
+// getSourcesData return the sources of the Replika keeping only the fields that decide the distributed data
+func getSourcesData(replika *Replika) (sources []ReplikaSourceSpec) {
+	for _, source := range append([]ReplikaSourceSpec{replika.Spec.Source}, replika.Spec.Sources...) {
+		sources = append(sources, ReplikaSourceSpec{
+			Group:     source.Group,
+			Version:   source.Version,
+			Kind:      source.Kind,
+			Name:      source.Name,
+			Namespace: source.Namespace,
+			Inline:    source.Inline,
+			Fields:    source.Fields,
+		})
+	}
+	return sources
+}
+
+// validateNamespacesSelection return the errors of the namespaces spec whose fields contradict each other.
+// The excluded namespaces only apply to the namespaces selected by matchAll or celExpression, while
+// replicateIn is only used when none of them is set
+func validateNamespacesSelection(path *field.Path, namespaces ReplikaTargetNamespacesSpec) (allErrs field.ErrorList) {
+
+	selectsAll := namespaces.MatchAll || namespaces.CELExpression != ""
+
+	if len(namespaces.ExcludeFrom) > 0 && !selectsAll {
+		allErrs = append(allErrs, field.Forbidden(path.Child("excludeFrom"),
+			"only applies when matchAll or celExpression is set, list the namespaces in replicateIn instead"))
+	}
+
+	if len(namespaces.ReplicateIn) > 0 && selectsAll {
+		allErrs = append(allErrs, field.Forbidden(path.Child("replicateIn"),
+			"is ignored when matchAll or celExpression is set, use only one way of selecting the namespaces"))
+	}
+
+	excluded := map[string]bool{}
+	for _, ns := range namespaces.ExcludeFrom {
+		excluded[ns] = true
+	}
+	for i, ns := range namespaces.ReplicateIn {
+		if excluded[ns] {
+			allErrs = append(allErrs, field.Invalid(path.Child("replicateIn").Index(i), ns,
+				"is also listed in excludeFrom"))
+		}
+	}
+
+	return allErrs
+}
+
+// IsClusterScoped return true when the mapper knows the kind as cluster-scoped
+func IsClusterScoped(mapper meta.RESTMapper, gvk schema.GroupVersionKind) bool {
+	if mapper == nil {
+		return false
+	}
+
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return false
+	}
+	return mapping.Scope.Name() == meta.RESTScopeNameRoot
+}
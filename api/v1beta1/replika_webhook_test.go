@@ -0,0 +1,323 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// newReplika return a valid Replika copying a ConfigMap into two namespaces
+func newReplika() *Replika {
+	return &Replika{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default"},
+		Spec: ReplikaSpec{
+			Synchronization: SynchronizationSpec{Time: "30s"},
+			Source: ReplikaSourceSpec{
+				Version:   "v1",
+				Kind:      "ConfigMap",
+				Name:      "app-config",
+				Namespace: "default",
+			},
+			Target: ReplikaTargetSpec{
+				Namespaces: ReplikaTargetNamespacesSpec{ReplicateIn: []string{"team-a", "team-b"}},
+			},
+		},
+	}
+}
+
+func TestValidateHook(t *testing.T) {
+	privileged := true
+
+	tests := []struct {
+		name    string
+		edit    func(hook *ReplikaHookSpec)
+		invalid bool
+	}{
+		{
+			name: "valid",
+			edit: func(hook *ReplikaHookSpec) {},
+		},
+		{
+			name:    "missing ServiceAccount",
+			edit:    func(hook *ReplikaHookSpec) { hook.ServiceAccountName = "" },
+			invalid: true,
+		},
+		{
+			name:    "invalid ServiceAccount name",
+			edit:    func(hook *ReplikaHookSpec) { hook.ServiceAccountName = "Hook_Runner" },
+			invalid: true,
+		},
+		{
+			name:    "pod running as other ServiceAccount",
+			edit:    func(hook *ReplikaHookSpec) { hook.Template.Spec.Template.Spec.ServiceAccountName = "admin" },
+			invalid: true,
+		},
+		{
+			name: "pod running as the same ServiceAccount",
+			edit: func(hook *ReplikaHookSpec) { hook.Template.Spec.Template.Spec.ServiceAccountName = "hook-runner" },
+		},
+		{
+			name:    "host network",
+			edit:    func(hook *ReplikaHookSpec) { hook.Template.Spec.Template.Spec.HostNetwork = true },
+			invalid: true,
+		},
+		{
+			name: "host path",
+			edit: func(hook *ReplikaHookSpec) {
+				hook.Template.Spec.Template.Spec.Volumes = []corev1.Volume{{
+					Name:         "root",
+					VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/"}},
+				}}
+			},
+			invalid: true,
+		},
+		{
+			name: "privileged container",
+			edit: func(hook *ReplikaHookSpec) {
+				hook.Template.Spec.Template.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{Privileged: &privileged}
+			},
+			invalid: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hook := &ReplikaHookSpec{ServiceAccountName: "hook-runner"}
+			hook.Template.Spec.Template.Spec.Containers = []corev1.Container{{Name: "hook", Image: "busybox"}}
+			test.edit(hook)
+
+			allErrs := validateHook(field.NewPath("spec", "hooks", "preSync"), hook)
+			if (len(allErrs) > 0) != test.invalid {
+				t.Errorf("expected invalid %t, got %v", test.invalid, allErrs)
+			}
+		})
+	}
+}
+
+func TestValidateReplika(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(r *Replika)
+
+		// Path of the field refused, the Replika is valid when empty
+		invalidField string
+	}{
+		{
+			name: "valid",
+			edit: func(r *Replika) {},
+		},
+		{
+			name:         "invalid synchronization time",
+			edit:         func(r *Replika) { r.Spec.Synchronization.Time = "often" },
+			invalidField: "spec.synchronization.time",
+		},
+		{
+			name:         "non positive request timeout",
+			edit:         func(r *Replika) { r.Spec.Synchronization.RequestTimeout = "0s" },
+			invalidField: "spec.synchronization.requestTimeout",
+		},
+		{
+			name:         "source without name",
+			edit:         func(r *Replika) { r.Spec.Source.Name = "" },
+			invalidField: "spec.source.name",
+		},
+		{
+			name: "inline source",
+			edit: func(r *Replika) {
+				r.Spec.Source = ReplikaSourceSpec{Inline: &runtime.RawExtension{
+					Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"inline"}}`),
+				}}
+			},
+		},
+		{
+			name:         "invalid field path",
+			edit:         func(r *Replika) { r.Spec.Source.Fields = []string{"data"} },
+			invalidField: "spec.source.fields[0]",
+		},
+		{
+			name: "merged source of other kind",
+			edit: func(r *Replika) {
+				r.Spec.Sources = []ReplikaSourceSpec{{Version: "v1", Kind: "Secret", Name: "app-secret", Namespace: "default"}}
+			},
+			invalidField: "spec.sources[0].kind",
+		},
+		{
+			name:         "target in the source namespace",
+			edit:         func(r *Replika) { r.Spec.Target.Namespaces.ReplicateIn = []string{"default"} },
+			invalidField: "spec.target.namespaces.replicateIn[0]",
+		},
+		{
+			name:         "invalid namespace name",
+			edit:         func(r *Replika) { r.Spec.Target.Namespaces.ReplicateIn = []string{"Team_A"} },
+			invalidField: "spec.target.namespaces.replicateIn[0]",
+		},
+		{
+			name:         "excluded namespaces without selecting all",
+			edit:         func(r *Replika) { r.Spec.Target.Namespaces.ExcludeFrom = []string{"kube-system"} },
+			invalidField: "spec.target.namespaces.excludeFrom",
+		},
+		{
+			name: "listed namespaces while selecting all",
+			edit: func(r *Replika) {
+				r.Spec.Target.Namespaces.MatchAll = true
+			},
+			invalidField: "spec.target.namespaces.replicateIn",
+		},
+		{
+			name: "invalid CEL expression",
+			edit: func(r *Replika) {
+				r.Spec.Target.Namespaces = ReplikaTargetNamespacesSpec{CELExpression: "labels.team =="}
+			},
+			invalidField: "spec.target.namespaces.celExpression",
+		},
+		{
+			name: "HTTP hook timeout up to the maximum",
+			edit: func(r *Replika) {
+				r.Spec.Hooks.HTTP = []ReplikaHTTPHookSpec{{URL: "https://hooks.example.com", Timeout: "30s"}}
+			},
+		},
+		{
+			name: "HTTP hook timeout over the maximum",
+			edit: func(r *Replika) {
+				r.Spec.Hooks.HTTP = []ReplikaHTTPHookSpec{{URL: "https://hooks.example.com", Timeout: "1h"}}
+			},
+			invalidField: "spec.hooks.http[0].timeout",
+		},
+		{
+			name: "on-demand Secret",
+			edit: func(r *Replika) {
+				r.Spec.Source.Kind = "Secret"
+				r.Spec.Target.OnDemand = true
+			},
+		},
+		{
+			name: "on-demand kind not referenced by the Pods",
+			edit: func(r *Replika) {
+				r.Spec.Source.Kind = "ServiceAccount"
+				r.Spec.Target.OnDemand = true
+			},
+			invalidField: "spec.target.onDemand",
+		},
+		{
+			name: "deletion of the targets with a grace period",
+			edit: func(r *Replika) {
+				r.Spec.Source.OnDelete = "DeleteTargets"
+				r.Spec.Source.DeletionGracePeriod = "24h"
+			},
+		},
+		{
+			name:         "deletion of the targets without grace period",
+			edit:         func(r *Replika) { r.Spec.Source.OnDelete = "DeleteTargets" },
+			invalidField: "spec.source.deletionGracePeriod",
+		},
+		{
+			name: "deletion of the targets with a zero grace period",
+			edit: func(r *Replika) {
+				r.Spec.Source.OnDelete = "DeleteTargets"
+				r.Spec.Source.DeletionGracePeriod = "0s"
+			},
+			invalidField: "spec.source.deletionGracePeriod",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			replika := newReplika()
+			test.edit(replika)
+
+			err := replika.validateReplika()
+			if test.invalidField == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.invalidField+":") {
+				t.Errorf("expected %s to be refused, got %v", test.invalidField, err)
+			}
+		})
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     func(r *Replika)
+		edit    func(r *Replika)
+		invalid bool
+	}{
+		{
+			name: "unchanged spec of a Replika valid under older rules",
+			old:  func(r *Replika) { r.Spec.Source.Fields = []string{"data"} },
+			edit: func(r *Replika) { r.Labels = map[string]string{"team": "platform"} },
+		},
+		{
+			name: "Replika being deleted",
+			old:  func(r *Replika) { r.Spec.Source.Fields = []string{"data"} },
+			edit: func(r *Replika) {
+				now := metav1.Now()
+				r.DeletionTimestamp = &now
+				r.Spec.Synchronization.Time = "1m"
+			},
+		},
+		{
+			name:    "invalid change",
+			old:     func(r *Replika) {},
+			edit:    func(r *Replika) { r.Spec.Synchronization.Time = "often" },
+			invalid: true,
+		},
+		{
+			name:    "data change of an immutable source",
+			old:     func(r *Replika) { r.Status.SourceImmutable = true },
+			edit:    func(r *Replika) { r.Spec.Source.Name = "app-config-v2" },
+			invalid: true,
+		},
+		{
+			name: "other change of an immutable source",
+			old:  func(r *Replika) { r.Status.SourceImmutable = true },
+			edit: func(r *Replika) { r.Spec.Source.SynchronizationTime = "1m" },
+		},
+		{
+			name: "data change of an immutable source being recreated",
+			old:  func(r *Replika) { r.Status.SourceImmutable = true },
+			edit: func(r *Replika) {
+				r.Spec.Source.Name = "app-config-v2"
+				r.Spec.Target.RecreateImmutable = true
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			old := newReplika()
+			test.old(old)
+			replika := old.DeepCopy()
+			test.edit(replika)
+
+			err := replika.ValidateUpdate(old)
+			if (err != nil) != test.invalid {
+				t.Errorf("expected invalid %t, got %v", test.invalid, err)
+			}
+		})
+	}
+}
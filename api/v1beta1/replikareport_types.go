@@ -0,0 +1,84 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReplikaReportSpec defines the desired state of a ReplikaReport
+type ReplikaReportSpec struct {
+}
+
+// ReplikaReportEntry defines a Replika listed in the report
+type ReplikaReportEntry struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Duration  string `json:"duration"`
+}
+
+// ReplikaReportStatus defines the totals of all the Replikas in the cluster
+type ReplikaReportStatus struct {
+
+	// LastUpdateTime is the last time the report was generated
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+
+	// Replikas is the number of Replikas in the cluster
+	Replikas int `json:"replikas"`
+
+	// Targets is the number of targets managed by all the Replikas
+	Targets int `json:"targets"`
+
+	// FailedReplikas is the number of Replikas whose last synchronization failed
+	FailedReplikas int `json:"failedReplikas"`
+
+	// FailuresByReason counts the failed Replikas by the reason of their condition
+	FailuresByReason map[string]int `json:"failuresByReason,omitempty"`
+
+	// SlowestReplikas lists the Replikas with the longest synchronizations
+	SlowestReplikas []ReplikaReportEntry `json:"slowestReplikas,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster,categories={replikas}
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Replikas",type="integer",JSONPath=".status.replikas",description=""
+//+kubebuilder:printcolumn:name="Targets",type="integer",JSONPath=".status.targets",description=""
+//+kubebuilder:printcolumn:name="Failed",type="integer",JSONPath=".status.failedReplikas",description=""
+//+kubebuilder:printcolumn:name="Updated",type="date",JSONPath=".status.lastUpdateTime",description=""
+
+// ReplikaReport is the Schema for the cluster-wide report of the Replikas, maintained by the controller
+type ReplikaReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ReplikaReportSpec   `json:"spec,omitempty"`
+	Status ReplikaReportStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ReplikaReportList contains a list of ReplikaReport resources
+type ReplikaReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReplikaReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ReplikaReport{}, &ReplikaReportList{})
+}
@@ -53,6 +53,245 @@ func (in *Replika) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaAggregationDestinationSpec) DeepCopyInto(out *ReplikaAggregationDestinationSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaAggregationDestinationSpec.
+func (in *ReplikaAggregationDestinationSpec) DeepCopy() *ReplikaAggregationDestinationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaAggregationDestinationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaAggregationSpec) DeepCopyInto(out *ReplikaAggregationSpec) {
+	*out = *in
+	in.Namespaces.DeepCopyInto(&out.Namespaces)
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	out.Destination = in.Destination
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaAggregationSpec.
+func (in *ReplikaAggregationSpec) DeepCopy() *ReplikaAggregationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaAggregationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaCanarySpec) DeepCopyInto(out *ReplikaCanarySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaCanarySpec.
+func (in *ReplikaCanarySpec) DeepCopy() *ReplikaCanarySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaCanarySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaCanaryStatus) DeepCopyInto(out *ReplikaCanaryStatus) {
+	*out = *in
+	in.WriteTime.DeepCopyInto(&out.WriteTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaCanaryStatus.
+func (in *ReplikaCanaryStatus) DeepCopy() *ReplikaCanaryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaCanaryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaConsumerStatus) DeepCopyInto(out *ReplikaConsumerStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaConsumerStatus.
+func (in *ReplikaConsumerStatus) DeepCopy() *ReplikaConsumerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaConsumerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaDeletionPreviewStatus) DeepCopyInto(out *ReplikaDeletionPreviewStatus) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.Objects != nil {
+		in, out := &in.Objects, &out.Objects
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaDeletionPreviewStatus.
+func (in *ReplikaDeletionPreviewStatus) DeepCopy() *ReplikaDeletionPreviewStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaDeletionPreviewStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaDriftStatus) DeepCopyInto(out *ReplikaDriftStatus) {
+	*out = *in
+	if in.Time != nil {
+		in, out := &in.Time, &out.Time
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaDriftStatus.
+func (in *ReplikaDriftStatus) DeepCopy() *ReplikaDriftStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaDriftStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaHTTPHookSpec) DeepCopyInto(out *ReplikaHTTPHookSpec) {
+	*out = *in
+	if in.Phases != nil {
+		in, out := &in.Phases, &out.Phases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaHTTPHookSpec.
+func (in *ReplikaHTTPHookSpec) DeepCopy() *ReplikaHTTPHookSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaHTTPHookSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaHookSpec) DeepCopyInto(out *ReplikaHookSpec) {
+	*out = *in
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaHookSpec.
+func (in *ReplikaHookSpec) DeepCopy() *ReplikaHookSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaHookSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaHooksSpec) DeepCopyInto(out *ReplikaHooksSpec) {
+	*out = *in
+	if in.PreSync != nil {
+		in, out := &in.PreSync, &out.PreSync
+		*out = new(ReplikaHookSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PostSync != nil {
+		in, out := &in.PostSync, &out.PostSync
+		*out = new(ReplikaHookSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = make([]ReplikaHTTPHookSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaHooksSpec.
+func (in *ReplikaHooksSpec) DeepCopy() *ReplikaHooksSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaHooksSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaIntegrityStatus) DeepCopyInto(out *ReplikaIntegrityStatus) {
+	*out = *in
+	in.LastAuditTime.DeepCopyInto(&out.LastAuditTime)
+	if in.DriftedNamespaces != nil {
+		in, out := &in.DriftedNamespaces, &out.DriftedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MissingNamespaces != nil {
+		in, out := &in.MissingNamespaces, &out.MissingNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DriftedTargets != nil {
+		in, out := &in.DriftedTargets, &out.DriftedTargets
+		*out = make([]ReplikaDriftStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaIntegrityStatus.
+func (in *ReplikaIntegrityStatus) DeepCopy() *ReplikaIntegrityStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaIntegrityStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaInventoryEntry) DeepCopyInto(out *ReplikaInventoryEntry) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaInventoryEntry.
+func (in *ReplikaInventoryEntry) DeepCopy() *ReplikaInventoryEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaInventoryEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReplikaList) DeepCopyInto(out *ReplikaList) {
 	*out = *in
@@ -85,9 +324,265 @@ func (in *ReplikaList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaMergeConflictStatus) DeepCopyInto(out *ReplikaMergeConflictStatus) {
+	*out = *in
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaMergeConflictStatus.
+func (in *ReplikaMergeConflictStatus) DeepCopy() *ReplikaMergeConflictStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaMergeConflictStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaMergePolicySpec) DeepCopyInto(out *ReplikaMergePolicySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaMergePolicySpec.
+func (in *ReplikaMergePolicySpec) DeepCopy() *ReplikaMergePolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaMergePolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaNamespaceStatus) DeepCopyInto(out *ReplikaNamespaceStatus) {
+	*out = *in
+	if in.RetryTime != nil {
+		in, out := &in.RetryTime, &out.RetryTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaNamespaceStatus.
+func (in *ReplikaNamespaceStatus) DeepCopy() *ReplikaNamespaceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaNamespaceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaOutputKeySpec) DeepCopyInto(out *ReplikaOutputKeySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaOutputKeySpec.
+func (in *ReplikaOutputKeySpec) DeepCopy() *ReplikaOutputKeySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaOutputKeySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaOutputSpec) DeepCopyInto(out *ReplikaOutputSpec) {
+	*out = *in
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]ReplikaOutputKeySpec, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaOutputSpec.
+func (in *ReplikaOutputSpec) DeepCopy() *ReplikaOutputSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaOutputSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaReport) DeepCopyInto(out *ReplikaReport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaReport.
+func (in *ReplikaReport) DeepCopy() *ReplikaReport {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReplikaReport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaReportEntry) DeepCopyInto(out *ReplikaReportEntry) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaReportEntry.
+func (in *ReplikaReportEntry) DeepCopy() *ReplikaReportEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaReportEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaReportList) DeepCopyInto(out *ReplikaReportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ReplikaReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaReportList.
+func (in *ReplikaReportList) DeepCopy() *ReplikaReportList {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaReportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReplikaReportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaReportSpec) DeepCopyInto(out *ReplikaReportSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaReportSpec.
+func (in *ReplikaReportSpec) DeepCopy() *ReplikaReportSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaReportSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaReportStatus) DeepCopyInto(out *ReplikaReportStatus) {
+	*out = *in
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	if in.FailuresByReason != nil {
+		in, out := &in.FailuresByReason, &out.FailuresByReason
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SlowestReplikas != nil {
+		in, out := &in.SlowestReplikas, &out.SlowestReplikas
+		*out = make([]ReplikaReportEntry, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaReportStatus.
+func (in *ReplikaReportStatus) DeepCopy() *ReplikaReportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaReportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaRequiredResourceSpec) DeepCopyInto(out *ReplikaRequiredResourceSpec) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaRequiredResourceSpec.
+func (in *ReplikaRequiredResourceSpec) DeepCopy() *ReplikaRequiredResourceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaRequiredResourceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplikaRolloutSpec) DeepCopyInto(out *ReplikaRolloutSpec) {
+	*out = *in
+	if in.Canary != nil {
+		in, out := &in.Canary, &out.Canary
+		*out = new(ReplikaCanarySpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaRolloutSpec.
+func (in *ReplikaRolloutSpec) DeepCopy() *ReplikaRolloutSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplikaRolloutSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReplikaSourceSpec) DeepCopyInto(out *ReplikaSourceSpec) {
 	*out = *in
+	if in.Inline != nil {
+		in, out := &in.Inline, &out.Inline
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Fields != nil {
+		in, out := &in.Fields, &out.Fields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaSourceSpec.
@@ -103,9 +598,23 @@ func (in *ReplikaSourceSpec) DeepCopy() *ReplikaSourceSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReplikaSpec) DeepCopyInto(out *ReplikaSpec) {
 	*out = *in
-	out.Synchronization = in.Synchronization
-	out.Source = in.Source
+	in.Synchronization.DeepCopyInto(&out.Synchronization)
+	in.Source.DeepCopyInto(&out.Source)
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = make([]ReplikaSourceSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	out.MergePolicy = in.MergePolicy
+	if in.Aggregation != nil {
+		in, out := &in.Aggregation, &out.Aggregation
+		*out = new(ReplikaAggregationSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Target.DeepCopyInto(&out.Target)
+	in.Hooks.DeepCopyInto(&out.Hooks)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaSpec.
@@ -128,6 +637,92 @@ func (in *ReplikaStatus) DeepCopyInto(out *ReplikaStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.SourceMissingTime != nil {
+		in, out := &in.SourceMissingTime, &out.SourceMissingTime
+		*out = (*in).DeepCopy()
+	}
+	if in.SyncedNamespaces != nil {
+		in, out := &in.SyncedNamespaces, &out.SyncedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FailedNamespaces != nil {
+		in, out := &in.FailedNamespaces, &out.FailedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSuccessfulSyncTime != nil {
+		in, out := &in.LastSuccessfulSyncTime, &out.LastSuccessfulSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastErrorTime != nil {
+		in, out := &in.LastErrorTime, &out.LastErrorTime
+		*out = (*in).DeepCopy()
+	}
+	if in.QueueWait != nil {
+		in, out := &in.QueueWait, &out.QueueWait
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.ReconcileDuration != nil {
+		in, out := &in.ReconcileDuration, &out.ReconcileDuration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.RejectedNamespaces != nil {
+		in, out := &in.RejectedNamespaces, &out.RejectedNamespaces
+		*out = make([]ReplikaNamespaceStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Consumers != nil {
+		in, out := &in.Consumers, &out.Consumers
+		*out = make([]ReplikaConsumerStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.ConsumersScanTime != nil {
+		in, out := &in.ConsumersScanTime, &out.ConsumersScanTime
+		*out = (*in).DeepCopy()
+	}
+	if in.HookRevisions != nil {
+		in, out := &in.HookRevisions, &out.HookRevisions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Integrity != nil {
+		in, out := &in.Integrity, &out.Integrity
+		*out = new(ReplikaIntegrityStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MergeConflicts != nil {
+		in, out := &in.MergeConflicts, &out.MergeConflicts
+		*out = make([]ReplikaMergeConflictStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Canary != nil {
+		in, out := &in.Canary, &out.Canary
+		*out = new(ReplikaCanaryStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DeletionPreview != nil {
+		in, out := &in.DeletionPreview, &out.DeletionPreview
+		*out = new(ReplikaDeletionPreviewStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Inventory != nil {
+		in, out := &in.Inventory, &out.Inventory
+		*out = make([]ReplikaInventoryEntry, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaStatus.
@@ -153,6 +748,16 @@ func (in *ReplikaTargetNamespacesSpec) DeepCopyInto(out *ReplikaTargetNamespaces
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.RequireResource != nil {
+		in, out := &in.RequireResource, &out.RequireResource
+		*out = new(ReplikaRequiredResourceSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Order != nil {
+		in, out := &in.Order, &out.Order
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaTargetNamespacesSpec.
@@ -169,6 +774,26 @@ func (in *ReplikaTargetNamespacesSpec) DeepCopy() *ReplikaTargetNamespacesSpec {
 func (in *ReplikaTargetSpec) DeepCopyInto(out *ReplikaTargetSpec) {
 	*out = *in
 	in.Namespaces.DeepCopyInto(&out.Namespaces)
+	if in.StripLabels != nil {
+		in, out := &in.StripLabels, &out.StripLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RegistryRewrites != nil {
+		in, out := &in.RegistryRewrites, &out.RegistryRewrites
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Outputs != nil {
+		in, out := &in.Outputs, &out.Outputs
+		*out = make([]ReplikaOutputSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Rollout.DeepCopyInto(&out.Rollout)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplikaTargetSpec.
@@ -184,6 +809,11 @@ func (in *ReplikaTargetSpec) DeepCopy() *ReplikaTargetSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SynchronizationSpec) DeepCopyInto(out *SynchronizationSpec) {
 	*out = *in
+	if in.Window != nil {
+		in, out := &in.Window, &out.Window
+		*out = new(SynchronizationWindowSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SynchronizationSpec.
@@ -195,3 +825,18 @@ func (in *SynchronizationSpec) DeepCopy() *SynchronizationSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SynchronizationWindowSpec) DeepCopyInto(out *SynchronizationWindowSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SynchronizationWindowSpec.
+func (in *SynchronizationWindowSpec) DeepCopy() *SynchronizationWindowSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SynchronizationWindowSpec)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// loadgen fabricates Replikas and namespaces in a disposable cluster, like kind or envtest, and measures how long
+// the operator takes to synchronize and update all of them. It is a developer tool to tune the worker pool,
+// the caches and the scheduler with data, never to be run against a real cluster
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/controllers"
+	"prosimcorp.com/replika/pkg/conditions"
+)
+
+const (
+	// Label marking every object created by the load, so it can be cleaned even after an interrupted run
+	loadLabel = "replika.prosimcorp.com/loadgen"
+
+	// Interval between the checks of the progress of the Replikas
+	pollInterval = 2 * time.Second
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(replikav1beta1.AddToScheme(scheme))
+}
+
+// load defines the objects fabricated for a run
+type load struct {
+	client client.Client
+
+	prefix     string
+	replikas   int
+	namespaces int
+	dataSize   int
+	syncTime   string
+}
+
+func main() {
+	var l load
+	var timeout time.Duration
+	var cleanup bool
+
+	flag.StringVar(&l.prefix, "prefix", "loadgen", "Prefix of the names of the namespaces and the Replikas of the load.")
+	flag.IntVar(&l.replikas, "replikas", 100, "Number of Replikas, each one with its own source.")
+	flag.IntVar(&l.namespaces, "namespaces", 50, "Number of target namespaces shared by all the Replikas.")
+	flag.IntVar(&l.dataSize, "data-size", 1024, "Size in bytes of the data of each source.")
+	flag.StringVar(&l.syncTime, "synchronization-time", "5m", "Synchronization time of the Replikas.")
+	flag.DurationVar(&timeout, "timeout", 15*time.Minute, "Maximum time waiting for each phase of the load.")
+	flag.BoolVar(&cleanup, "cleanup", true, "Delete the Replikas and the namespaces of the load once finished.")
+	flag.Parse()
+
+	var err error
+	l.client, err = client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	err = l.run(ctx, timeout)
+	if cleanup {
+		if cleanupErr := l.cleanup(ctx, timeout); err == nil {
+			err = cleanupErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+// run create the load and report the time taken by the operator to synchronize it, and then to propagate
+// a change of every source
+func (l *load) run(ctx context.Context, timeout time.Duration) (err error) {
+
+	targets := l.replikas * l.namespaces
+	fmt.Printf("Creating %d Replikas replicated into %d namespaces, %d targets\n", l.replikas, l.namespaces, targets)
+
+	err = l.createNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = l.createReplikas(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = l.waitSynchronized(ctx, start, timeout)
+	if err != nil {
+		return err
+	}
+	report("initial synchronization", time.Since(start), l.replikas, targets)
+
+	start = time.Now()
+	err = l.updateSources(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = l.waitSynchronized(ctx, start, timeout)
+	if err != nil {
+		return err
+	}
+	report("source updates", time.Since(start), l.replikas, targets)
+
+	return err
+}
+
+// report print the throughput of a phase of the load
+func report(phase string, elapsed time.Duration, replikas, targets int) {
+	seconds := elapsed.Seconds()
+	fmt.Printf("%s: %s, %.2f Replikas/s, %.2f targets/s\n", phase, elapsed.Round(time.Millisecond),
+		float64(replikas)/seconds, float64(targets)/seconds)
+}
+
+// sourceNamespace return the namespace holding the sources and the Replikas
+func (l *load) sourceNamespace() string {
+	return l.prefix + "-sources"
+}
+
+// targetNamespaces return the names of the target namespaces
+func (l *load) targetNamespaces() (namespaces []string) {
+	for i := 0; i < l.namespaces; i++ {
+		namespaces = append(namespaces, fmt.Sprintf("%s-%d", l.prefix, i))
+	}
+	return namespaces
+}
+
+// createNamespaces create the namespace of the sources and the target ones
+func (l *load) createNamespaces(ctx context.Context) (err error) {
+
+	for _, name := range append([]string{l.sourceNamespace()}, l.targetNamespaces()...) {
+		namespace := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{loadLabel: l.prefix}},
+		}
+		err = client.IgnoreAlreadyExists(l.client.Create(ctx, namespace))
+		if err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// createReplikas create a source and a Replika for each one of the Replikas of the load
+func (l *load) createReplikas(ctx context.Context) (err error) {
+
+	for i := 0; i < l.replikas; i++ {
+		name := fmt.Sprintf("%s-%d", l.prefix, i)
+
+		source := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: l.sourceNamespace(), Name: name, Labels: map[string]string{loadLabel: l.prefix}},
+			Data:       map[string]string{"payload": strings.Repeat("x", l.dataSize)},
+		}
+		err = client.IgnoreAlreadyExists(l.client.Create(ctx, source))
+		if err != nil {
+			return err
+		}
+
+		replika := &replikav1beta1.Replika{
+			ObjectMeta: metav1.ObjectMeta{Namespace: l.sourceNamespace(), Name: name, Labels: map[string]string{loadLabel: l.prefix}},
+			Spec: replikav1beta1.ReplikaSpec{
+				Synchronization: replikav1beta1.SynchronizationSpec{Time: l.syncTime},
+				Source: replikav1beta1.ReplikaSourceSpec{
+					Version:   "v1",
+					Kind:      "ConfigMap",
+					Name:      name,
+					Namespace: l.sourceNamespace(),
+				},
+				Target: replikav1beta1.ReplikaTargetSpec{
+					Namespaces: replikav1beta1.ReplikaTargetNamespacesSpec{ReplicateIn: l.targetNamespaces()},
+				},
+			},
+		}
+		err = client.IgnoreAlreadyExists(l.client.Create(ctx, replika))
+		if err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// updateSources change the data of all the sources, so every target is written again
+func (l *load) updateSources(ctx context.Context) (err error) {
+
+	for i := 0; i < l.replikas; i++ {
+		source := &corev1.ConfigMap{}
+		err = l.client.Get(ctx, client.ObjectKey{Namespace: l.sourceNamespace(), Name: fmt.Sprintf("%s-%d", l.prefix, i)}, source)
+		if err != nil {
+			return err
+		}
+
+		source.Data["payload"] = strings.Repeat("y", l.dataSize)
+		err = l.client.Update(ctx, source)
+		if err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// waitSynchronized wait until every Replika of the load is ready and was synchronized after the time
+func (l *load) waitSynchronized(ctx context.Context, after time.Time, timeout time.Duration) (err error) {
+
+	reported := -1
+	err = wait.PollImmediate(pollInterval, timeout, func() (done bool, err error) {
+
+		replikaList := &replikav1beta1.ReplikaList{}
+		err = l.client.List(ctx, replikaList, client.InNamespace(l.sourceNamespace()), client.MatchingLabels{loadLabel: l.prefix})
+		if err != nil {
+			return done, err
+		}
+
+		synchronized := 0
+		for _, replika := range replikaList.Items {
+			syncTime := replika.Status.LastSuccessfulSyncTime
+			if conditions.IsTrue(replika.Status.Conditions, controllers.ConditionTypeReady) && syncTime != nil && !syncTime.Time.Before(after.Truncate(time.Second)) {
+				synchronized++
+			}
+		}
+
+		if synchronized != reported {
+			fmt.Printf("  %d/%d Replikas synchronized after %s\n", synchronized, l.replikas, time.Since(after).Round(time.Second))
+			reported = synchronized
+		}
+		return synchronized == l.replikas, err
+	})
+
+	return err
+}
+
+// cleanup delete the Replikas of the load, waiting for their targets to be deleted, and then the namespaces
+func (l *load) cleanup(ctx context.Context, timeout time.Duration) (err error) {
+
+	fmt.Println("Deleting the load")
+
+	err = client.IgnoreNotFound(l.client.DeleteAllOf(ctx, &replikav1beta1.Replika{},
+		client.InNamespace(l.sourceNamespace()), client.MatchingLabels{loadLabel: l.prefix}))
+	if err != nil {
+		return err
+	}
+
+	err = wait.PollImmediate(pollInterval, timeout, func() (done bool, err error) {
+		replikaList := &replikav1beta1.ReplikaList{}
+		err = l.client.List(ctx, replikaList, client.InNamespace(l.sourceNamespace()), client.MatchingLabels{loadLabel: l.prefix})
+		return len(replikaList.Items) == 0, err
+	})
+	if err != nil {
+		return err
+	}
+
+	namespaceList := &corev1.NamespaceList{}
+	err = l.client.List(ctx, namespaceList, client.MatchingLabels{loadLabel: l.prefix})
+	if err != nil {
+		return err
+	}
+	for i := range namespaceList.Items {
+		err = client.IgnoreNotFound(l.client.Delete(ctx, &namespaceList.Items[i]))
+		if err != nil {
+			return err
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/controllers"
+)
+
+const (
+	// Value replacing the data of the Secrets printed by the simulation
+	redactedValue = "<redacted>"
+
+	usage = `Usage: replikactl <command> [flags]
+
+Commands:
+  simulate -f replika.yaml    Print the targets a Replika would produce, without writing anything
+  rbac --kinds Secret,...     Print a ClusterRole granting the replication of exactly those kinds
+  selector --kind Secret ...  Print the label selector matching all the copies of a source
+`
+)
+
+var (
+	scheme = runtime.NewScheme()
+
+	// errReadOnly is returned by the client of the simulation on any write
+	errReadOnly = errors.New("the simulation is read-only")
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(replikav1beta1.AddToScheme(scheme))
+}
+
+// readOnlyClient refuses all the writes, so the simulation never changes the cluster
+type readOnlyClient struct {
+	client.Client
+}
+
+func (c readOnlyClient) Create(context.Context, client.Object, ...client.CreateOption) error {
+	return errReadOnly
+}
+
+func (c readOnlyClient) Update(context.Context, client.Object, ...client.UpdateOption) error {
+	return errReadOnly
+}
+
+func (c readOnlyClient) Patch(context.Context, client.Object, client.Patch, ...client.PatchOption) error {
+	return errReadOnly
+}
+
+func (c readOnlyClient) Delete(context.Context, client.Object, ...client.DeleteOption) error {
+	return errReadOnly
+}
+
+func (c readOnlyClient) DeleteAllOf(context.Context, client.Object, ...client.DeleteAllOfOption) error {
+	return errReadOnly
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "simulate":
+		err := simulate(os.Args[2:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+	case "rbac":
+		err := rbac(os.Args[2:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+	case "selector":
+		err := selector(os.Args[2:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// simulate compute the targets of the Replika in the file with the same logic as the controller,
+// printing them with the data of the Secrets redacted
+func simulate(args []string) (err error) {
+
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	file := flags.String("f", "", "File holding the Replika to simulate.")
+	_ = flags.Parse(args)
+	if *file == "" {
+		err = errors.New("the file of the Replika is required, set it with -f")
+		return err
+	}
+
+	var content []byte
+	content, err = os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+
+	replika := &replikav1beta1.Replika{}
+	err = yaml.UnmarshalStrict(content, replika)
+	if err != nil {
+		return err
+	}
+	replika.SetSourceDefaults()
+
+	var c client.Client
+	c, err = client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	reconciler := &controllers.ReplikaReconciler{
+		Client: readOnlyClient{Client: c},
+		Scheme: scheme,
+	}
+
+	var targets []unstructured.Unstructured
+	targets, err = reconciler.BuildTargets(context.Background(), replika)
+	if err != nil {
+		return err
+	}
+
+	for i := range targets {
+		RedactTarget(&targets[i])
+
+		var output []byte
+		output, err = yaml.Marshal(targets[i].Object)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("---\n%s", output)
+	}
+
+	return err
+}
+
+// rbac print a ClusterRole granting the replication of exactly the kinds allowed as sources,
+// resolving their resources in the cluster
+func rbac(args []string) (err error) {
+
+	flags := flag.NewFlagSet("rbac", flag.ExitOnError)
+	kinds := flags.String("kinds", "", "Kinds allowed as sources separated by commas, as 'Kind' or 'group/Kind'.")
+	name := flags.String("name", "replika-allowed-kinds", "Name of the ClusterRole.")
+	_ = flags.Parse(args)
+	if *kinds == "" {
+		err = errors.New("the allowed kinds are required, set them with --kinds")
+		return err
+	}
+
+	var mapper meta.RESTMapper
+	mapper, err = apiutil.NewDynamicRESTMapper(ctrl.GetConfigOrDie())
+	if err != nil {
+		return err
+	}
+
+	var clusterRole *rbacv1.ClusterRole
+	clusterRole, err = controllers.AllowedKindsClusterRole(mapper, *name, controllers.ParseKindList(*kinds))
+	if err != nil {
+		return err
+	}
+
+	var output []byte
+	output, err = yaml.Marshal(clusterRole)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("---\n%s", output)
+
+	return err
+}
+
+// selector print the label selector matching all the targets copied from a source, across all the Replikas
+func selector(args []string) (err error) {
+
+	flags := flag.NewFlagSet("selector", flag.ExitOnError)
+	group := flags.String("group", "", "Group of the source, empty for the core kinds.")
+	kind := flags.String("kind", "", "Kind of the source.")
+	namespace := flags.String("namespace", "", "Namespace of the source.")
+	name := flags.String("name", "", "Name of the source.")
+	_ = flags.Parse(args)
+	if *kind == "" || *name == "" {
+		err = errors.New("the kind and the name of the source are required, set them with --kind and --name")
+		return err
+	}
+
+	fmt.Println(controllers.GetSourceIDSelector(*group, *kind, *namespace, *name))
+	return err
+}
+
+// RedactTarget replace the values of the data of the Secrets, so the output can be kept in CI logs
+func RedactTarget(target *unstructured.Unstructured) {
+	if target.GetAPIVersion() != "v1" || target.GetKind() != "Secret" {
+		return
+	}
+
+	for _, field := range []string{"data", "stringData"} {
+		data, found, _ := unstructured.NestedMap(target.Object, field)
+		if !found {
+			continue
+		}
+		for key := range data {
+			data[key] = redactedValue
+		}
+		_ = unstructured.SetNestedMap(target.Object, data, field)
+	}
+}
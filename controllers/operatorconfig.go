@@ -0,0 +1,326 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// Keys read from the operator configuration ConfigMap
+	operatorConfigKeyDefaultSynchronizationTime = "defaultSynchronizationTime"
+	operatorConfigKeyProtectedNamespaces        = "protectedNamespaces"
+	operatorConfigKeyAllowedKinds               = "allowedKinds"
+	operatorConfigKeyConcurrency                = "concurrency"
+	operatorConfigKeyPaused                     = "paused"
+
+	operatorConfigParseError = "Can not parse the key %s of the operator configuration: %s"
+	operatorConfigReloaded   = "Operator configuration reloaded from %s"
+)
+
+var (
+	// errPaused is returned while the operator is paused by its configuration
+	errPaused = &PendingError{reason: "the operator is paused"}
+
+	// defaultAllowedKinds are the allowed kinds when the operator configuration does not define them
+	defaultAllowedKinds []string
+
+	// defaultConcurrency is the concurrency when the operator configuration does not define it
+	defaultConcurrency = 1
+)
+
+// OperatorSettings defines the defaults and policies applied to every Replika
+type OperatorSettings struct {
+
+	// DefaultSynchronizationTime is used when a Replika does not define spec.synchronization.time
+	DefaultSynchronizationTime time.Duration
+
+	// ProtectedNamespaces are never used as targets
+	ProtectedNamespaces []string
+
+	// AllowedKinds restricts the kinds of the sources, as 'Kind' or 'group/Kind'. Empty allows all of them
+	AllowedKinds []string
+
+	// Concurrency is the maximum number of Replikas synchronized at the same time
+	Concurrency int
+
+	// Paused halts the writes and deletions of the targets of all the Replikas, while their status is still updated.
+	// Used during cluster upgrades and etcd maintenance windows
+	Paused bool
+}
+
+// OperatorConfig holds the settings of the operator, which can be reloaded at runtime
+type OperatorConfig struct {
+	mutex    sync.Mutex
+	wakeup   chan struct{}
+	active   int
+	waiting  map[int32]int
+	settings OperatorSettings
+}
+
+// NewOperatorConfig return an OperatorConfig with the default settings
+func NewOperatorConfig() *OperatorConfig {
+	return &OperatorConfig{
+		wakeup:   make(chan struct{}),
+		waiting:  map[int32]int{},
+		settings: DefaultOperatorSettings(),
+	}
+}
+
+// DefaultOperatorSettings return the settings used when the operator configuration is not present
+func DefaultOperatorSettings() OperatorSettings {
+	return OperatorSettings{
+		DefaultSynchronizationTime: defaultSynchronizationTime,
+		AllowedKinds:               defaultAllowedKinds,
+		Concurrency:                defaultConcurrency,
+	}
+}
+
+// SetDefaultAllowedKinds change the allowed kinds used when the operator configuration does not define them.
+// It must be called before loading the operator configuration
+func SetDefaultAllowedKinds(kinds []string) {
+	defaultAllowedKinds = kinds
+}
+
+// SetDefaultConcurrency change the concurrency used when the operator configuration does not define it,
+// usually to the number of workers, so none of them waits for a slot by default.
+// It must be called before creating the operator configuration
+func SetDefaultConcurrency(concurrency int) {
+	defaultConcurrency = concurrency
+}
+
+// Settings return a copy of the current settings
+func (c *OperatorConfig) Settings() OperatorSettings {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.settings
+}
+
+// SetSettings replace the current settings, waking up the syncs waiting for a slot
+func (c *OperatorConfig) SetSettings(settings OperatorSettings) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.settings = settings
+	c.broadcast()
+}
+
+// broadcast wake up all the syncs waiting for a slot. The mutex must be held
+func (c *OperatorConfig) broadcast() {
+	close(c.wakeup)
+	c.wakeup = make(chan struct{})
+}
+
+// Acquire wait until the number of active syncs is under the configured concurrency.
+// Waiting syncs with a higher priority always take the free slots first.
+// The error of the context is returned when it is done before getting a slot, so the workers are never blocked
+// on shutdown
+func (c *OperatorConfig) Acquire(ctx context.Context, priority int32) (err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.waiting[priority]++
+	for c.settings.Concurrency > 0 && (c.active >= c.settings.Concurrency || c.isHigherPriorityWaiting(priority)) {
+		wakeup := c.wakeup
+		c.mutex.Unlock()
+		select {
+		case <-wakeup:
+		case <-ctx.Done():
+		}
+		c.mutex.Lock()
+
+		// Syncs with a lower priority may be waiting for this one to leave
+		if err = ctx.Err(); err != nil {
+			c.waiting[priority]--
+			c.broadcast()
+			return err
+		}
+	}
+	c.waiting[priority]--
+	c.active++
+
+	return err
+}
+
+// isHigherPriorityWaiting return true when a sync with a higher priority is waiting for a slot
+func (c *OperatorConfig) isHigherPriorityWaiting(priority int32) bool {
+	for p, count := range c.waiting {
+		if p > priority && count > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Release free the slot taken by Acquire
+func (c *OperatorConfig) Release() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.active--
+	c.broadcast()
+}
+
+// IsNamespaceProtected return true when the namespace can never be a target
+func (s *OperatorSettings) IsNamespaceProtected(namespace string) bool {
+	for _, v := range s.ProtectedNamespaces {
+		if v == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// IsKindAllowed return true when the kind can be used as a source
+func (s *OperatorSettings) IsKindAllowed(group, kind string) bool {
+	if len(s.AllowedKinds) == 0 {
+		return true
+	}
+	for _, v := range s.AllowedKinds {
+		if v == kind || v == group+"/"+kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseOperatorSettings return the settings defined in the data of a ConfigMap.
+// Keys not present keep their default values
+func ParseOperatorSettings(data map[string]string) (settings OperatorSettings, err error) {
+
+	settings = DefaultOperatorSettings()
+
+	if value, ok := data[operatorConfigKeyDefaultSynchronizationTime]; ok {
+		settings.DefaultSynchronizationTime, err = time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			err = NewErrorf(operatorConfigParseError, operatorConfigKeyDefaultSynchronizationTime, err.Error())
+			return settings, err
+		}
+	}
+
+	if value, ok := data[operatorConfigKeyConcurrency]; ok {
+		settings.Concurrency, err = strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			err = NewErrorf(operatorConfigParseError, operatorConfigKeyConcurrency, err.Error())
+			return settings, err
+		}
+	}
+
+	if value, ok := data[operatorConfigKeyPaused]; ok {
+		settings.Paused, err = strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			err = NewErrorf(operatorConfigParseError, operatorConfigKeyPaused, err.Error())
+			return settings, err
+		}
+	}
+
+	settings.ProtectedNamespaces = parseOperatorConfigList(data[operatorConfigKeyProtectedNamespaces])
+	if value, ok := data[operatorConfigKeyAllowedKinds]; ok {
+		settings.AllowedKinds = parseOperatorConfigList(value)
+	}
+
+	return settings, err
+}
+
+// ParseKindList return the kinds of a list separated by commas or new lines, as 'Kind' or 'group/Kind'
+func ParseKindList(value string) (kinds []string) {
+	return parseOperatorConfigList(value)
+}
+
+// parseOperatorConfigList split a list separated by commas or new lines
+func parseOperatorConfigList(value string) (items []string) {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '\n'
+	})
+	for _, v := range fields {
+		if v = strings.TrimSpace(v); v != "" {
+			items = append(items, v)
+		}
+	}
+	return items
+}
+
+// OperatorConfigReconciler reloads the operator configuration each time its ConfigMap changes
+type OperatorConfigReconciler struct {
+	client.Client
+
+	// ConfigMap is the namespaced name of the ConfigMap holding the configuration
+	ConfigMap types.NamespacedName
+
+	Config *OperatorConfig
+
+	// reader reads the ConfigMap from the cache holding only that object. The client is used when not set
+	reader client.Reader
+}
+
+// Reconcile parse the ConfigMap and replace the current settings. Defaults are restored when it is deleted
+func (r *OperatorConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+
+	var reader client.Reader = r.Client
+	if r.reader != nil {
+		reader = r.reader
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err = reader.Get(ctx, req.NamespacedName, configMap)
+	if err != nil {
+		if err = client.IgnoreNotFound(err); err == nil {
+			r.Config.SetSettings(DefaultOperatorSettings())
+		}
+		return result, err
+	}
+
+	var settings OperatorSettings
+	settings, err = ParseOperatorSettings(configMap.Data)
+	if err != nil {
+		LogInfof(ctx, "%s", err.Error())
+		return result, nil
+	}
+
+	r.Config.SetSettings(settings)
+	LogInfof(ctx, operatorConfigReloaded, req.NamespacedName.String())
+
+	return result, err
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// The ConfigMap is watched through its own cache, restricted to its namespace and name, so the ConfigMaps
+// of the whole cluster are never cached
+func (r *OperatorConfigReconciler) SetupWithManager(mgr ctrl.Manager) (err error) {
+
+	var configMapCache cache.Cache
+	configMapCache, err = cache.New(mgr.GetConfig(), cache.Options{
+		Scheme:    mgr.GetScheme(),
+		Mapper:    mgr.GetRESTMapper(),
+		Namespace: r.ConfigMap.Namespace,
+		SelectorsByObject: cache.SelectorsByObject{
+			&corev1.ConfigMap{}: {Field: fields.OneTermEqualSelector("metadata.name", r.ConfigMap.Name)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	err = mgr.Add(configMapCache)
+	if err != nil {
+		return err
+	}
+	r.reader = configMapCache
+
+	var configController controller.Controller
+	configController, err = controller.New("operatorconfig", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	return configController.Watch(source.NewKindWithCache(&corev1.ConfigMap{}, configMapCache), &handler.EnqueueRequestForObject{})
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"sort"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// replicationVerbs are the verbs the controller needs on the kinds it replicates
+var replicationVerbs = []string{"create", "delete", "get", "list", "patch", "update", "watch"}
+
+// AllowedKindsPolicyRules return the rules granting the replication of exactly the allowed kinds,
+// given as 'Kind' or 'group/Kind'. The resources of the kinds are resolved with the mapper
+func AllowedKindsPolicyRules(mapper meta.RESTMapper, kinds []string) (rules []rbacv1.PolicyRule, err error) {
+
+	resources := map[string][]string{}
+	for _, kind := range kinds {
+		groupKind := schema.GroupKind{Kind: kind}
+		if group, name, found := strings.Cut(kind, "/"); found {
+			groupKind = schema.GroupKind{Group: group, Kind: name}
+		}
+
+		var mappings []*meta.RESTMapping
+		mappings, err = mapper.RESTMappings(groupKind)
+		if err != nil {
+			return rules, err
+		}
+		for _, mapping := range mappings {
+			resources[groupKind.Group] = appendUnique(resources[groupKind.Group], mapping.Resource.Resource)
+		}
+	}
+
+	groups := make([]string, 0, len(resources))
+	for group := range resources {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	for _, group := range groups {
+		sort.Strings(resources[group])
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{group},
+			Resources: resources[group],
+			Verbs:     replicationVerbs,
+		})
+	}
+	return rules, err
+}
+
+// AllowedKindsClusterRole return a ClusterRole granting the replication of exactly the allowed kinds
+func AllowedKindsClusterRole(mapper meta.RESTMapper, name string, kinds []string) (clusterRole *rbacv1.ClusterRole, err error) {
+
+	clusterRole = &rbacv1.ClusterRole{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}
+	clusterRole.Rules, err = AllowedKindsPolicyRules(mapper, kinds)
+	return clusterRole, err
+}
+
+// appendUnique append the value when it is not in the list yet
+func appendUnique(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOperatorConfigAcquire(t *testing.T) {
+	config := NewOperatorConfig()
+	config.SetSettings(OperatorSettings{Concurrency: 1})
+
+	if err := config.Acquire(context.Background(), 0); err != nil {
+		t.Fatalf("unexpected error acquiring a free slot: %v", err)
+	}
+
+	// The syncs waiting for a slot give up once their context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := config.Acquire(ctx, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline exceeded, got %v", err)
+	}
+
+	// The slot is taken once released
+	acquired := make(chan error)
+	go func() {
+		acquired <- config.Acquire(context.Background(), 0)
+	}()
+	config.Release()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatalf("unexpected error acquiring a released slot: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("the released slot was not taken")
+	}
+	config.Release()
+}
+
+func TestOperatorConfigAcquirePriority(t *testing.T) {
+	config := NewOperatorConfig()
+	config.SetSettings(OperatorSettings{Concurrency: 1})
+
+	if err := config.Acquire(context.Background(), 0); err != nil {
+		t.Fatalf("unexpected error acquiring a free slot: %v", err)
+	}
+
+	// A waiting sync with a higher priority takes the slot first, even when the lower one gives up meanwhile
+	order := make(chan int32, 2)
+	lowCtx, cancelLow := context.WithCancel(context.Background())
+	go func() {
+		if config.Acquire(lowCtx, 0) == nil {
+			order <- 0
+		}
+	}()
+	go func() {
+		if config.Acquire(context.Background(), 10) == nil {
+			order <- 10
+		}
+	}()
+
+	// Wait for both syncs to be waiting before freeing the slot
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		config.mutex.Lock()
+		waiting := config.waiting[0] + config.waiting[10]
+		config.mutex.Unlock()
+		if waiting == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the syncs are not waiting for the slot")
+		}
+	}
+
+	config.Release()
+	if priority := <-order; priority != 10 {
+		t.Errorf("expected the higher priority first, got %d", priority)
+	}
+	cancelLow()
+	config.Release()
+}
+
+func TestParseOperatorSettingsDefaultConcurrency(t *testing.T) {
+	SetDefaultConcurrency(10)
+	defer SetDefaultConcurrency(1)
+
+	tests := []struct {
+		name     string
+		data     map[string]string
+		expected int
+	}{
+		{name: "not configured", data: map[string]string{}, expected: 10},
+		{name: "configured", data: map[string]string{operatorConfigKeyConcurrency: "2"}, expected: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings, err := ParseOperatorSettings(test.data)
+			if err != nil || settings.Concurrency != test.expected {
+				t.Errorf("expected the concurrency %d, got %d: %v", test.expected, settings.Concurrency, err)
+			}
+		})
+	}
+
+	if concurrency := NewOperatorConfig().Settings().Concurrency; concurrency != 10 {
+		t.Errorf("expected the default concurrency 10, got %d", concurrency)
+	}
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/conditions"
+	"prosimcorp.com/replika/pkg/replicator"
+)
+
+// GetAggregatedSources return the sources collected from the namespaces selected by the aggregation of the Replika.
+// Namespaces without the source, or whose source does not match the selector, are skipped
+func (r *ReplikaReconciler) GetAggregatedSources(ctx context.Context, replika *replikav1beta1.Replika) (sources []*unstructured.Unstructured, err error) {
+
+	aggregation := replika.Spec.Aggregation
+
+	selector := labels.Everything()
+	if aggregation.Selector != nil {
+		selector, err = metav1.LabelSelectorAsSelector(aggregation.Selector)
+		if err != nil {
+			err = NewPermanentErrorf(aggregationSelectorError, err.Error())
+			return sources, err
+		}
+	}
+
+	var namespaces []string
+	namespaces, err = r.SelectNamespaces(ctx, aggregation.Namespaces, aggregation.Destination.Namespace)
+	if err != nil {
+		return sources, err
+	}
+
+	for _, ns := range namespaces {
+		sourceSpec := replika.Spec.Source
+		sourceSpec.Namespace = ns
+
+		var source *unstructured.Unstructured
+		requestCtx, cancel := r.requestContext(ctx, replika)
+		source, err = r.GetSourceObject(requestCtx, sourceSpec)
+		cancel()
+		if apierrors.IsNotFound(err) {
+			err = nil
+			continue
+		}
+		if err != nil {
+			return sources, err
+		}
+
+		if !selector.Matches(labels.Set(source.GetLabels())) {
+			continue
+		}
+		sources = append(sources, source)
+	}
+
+	return sources, err
+}
+
+// BuildAggregatedTargets return the single target where the sources collected across namespaces are merged.
+// The destination is checked as any other target namespace, so it is never protected by the operator settings
+func (r *ReplikaReconciler) BuildAggregatedTargets(ctx context.Context, replika *replikav1beta1.Replika) (targets []unstructured.Unstructured, err error) {
+
+	replika.Status.MergeConflicts = nil
+
+	destination := replika.Spec.Aggregation.Destination
+	settings := r.settings()
+	if settings.IsNamespaceProtected(destination.Namespace) {
+		err = NewPermanentErrorf(protectedNamespaceError, destination.Namespace)
+		return targets, err
+	}
+	err = r.CheckMaxTargets(replika, 1)
+	if err != nil {
+		return targets, err
+	}
+
+	var sources []*unstructured.Unstructured
+	sources, err = r.GetAggregatedSources(ctx, replika)
+	if err == nil && len(sources) == 0 {
+		err = NewErrorf(aggregationEmptyError, replika.Spec.Source.Name)
+	}
+	if err != nil {
+		reason, message := GetFailureReason(err, ConditionReasonSourceNotFound, ConditionReasonSourceNotFoundMessage)
+		r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+			metav1.ConditionFalse,
+			reason,
+			message,
+		))
+		return targets, err
+	}
+
+	var source *unstructured.Unstructured
+	source, err = r.MergeSources(replika, sources)
+	if err != nil {
+		return targets, err
+	}
+
+	// The merged object is renamed as the destination
+	source.SetName(destination.Name)
+	replicator.StripLabels(source, replika.Spec.Target.StripLabels)
+	targets = r.replicator().BuildTargets(source, []string{destination.Namespace}, map[string]string{
+		resourceReplikaLabelCreatedKey:         resourceReplikaLabelCreatedValue,
+		resourceReplikaLabelPartOfKey:          replika.Name,
+		resourceReplikaLabelPartOfNamespaceKey: replika.Namespace,
+	})
+
+	return targets, err
+}
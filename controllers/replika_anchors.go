@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/auditlog"
+)
+
+const (
+	// Prefix of the name of the anchor ConfigMaps
+	anchorNamePrefix = "replika-anchor-"
+
+	// Anchors are labeled apart from the targets with the UID of their Replika, so they are never taken
+	// as one of them, nor as the anchors of a Replika with the same name in another namespace
+	resourceReplikaLabelAnchorKey = "replika.prosimcorp.com/anchor-of"
+
+	// Length of the hash suffixing the names of the anchors too long to be valid
+	anchorNameHashLength = 10
+)
+
+// GetAnchorName return the name of the anchor of a Replika in each target namespace, as
+// replika-anchor-<namespace>.<name>. Namespaces never have dots, so Replikas never share their anchors
+func GetAnchorName(replika *replikav1beta1.Replika) string {
+	name := anchorNamePrefix + replika.Namespace + "." + replika.Name
+	if len(name) <= validation.DNS1123SubdomainMaxLength {
+		return name
+	}
+
+	hash := sha256.Sum256([]byte(name))
+	suffix := hex.EncodeToString(hash[:])[:anchorNameHashLength]
+	return strings.TrimRight(name[:validation.DNS1123SubdomainMaxLength-len(suffix)-1], "-.") + "-" + suffix
+}
+
+// GetAnchorLabels return the labels of the anchors of a Replika
+func GetAnchorLabels(replika *replikav1beta1.Replika) map[string]string {
+	return map[string]string{
+		resourceReplikaLabelCreatedKey: resourceReplikaLabelCreatedValue,
+		resourceReplikaLabelAnchorKey:  string(replika.UID),
+	}
+}
+
+// EnsureAnchor return the anchor of the Replika in a namespace, creating it when not existent
+func (r *ReplikaReconciler) EnsureAnchor(ctx context.Context, replika *replikav1beta1.Replika, namespace string) (anchor *corev1.ConfigMap, err error) {
+
+	anchor = &corev1.ConfigMap{}
+	err = r.uncachedReader().Get(ctx, client.ObjectKey{Namespace: namespace, Name: GetAnchorName(replika)}, anchor)
+	if !apierrors.IsNotFound(err) {
+		return anchor, err
+	}
+
+	anchor = &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      GetAnchorName(replika),
+			Labels:    GetAnchorLabels(replika),
+		},
+	}
+	err = r.Create(ctx, anchor)
+	if err != nil {
+		return anchor, err
+	}
+	r.RecordObjectAuditLog(ctx, replika, corev1.SchemeGroupVersion.WithKind("ConfigMap"), anchor, auditlog.ActionCreate, auditReasonAnchor)
+
+	return anchor, err
+}
+
+// SetAnchors make the anchor of each target namespace the owner of the target,
+// so the garbage collector of Kubernetes deletes the target when its anchor is removed
+func (r *ReplikaReconciler) SetAnchors(ctx context.Context, replika *replikav1beta1.Replika, targets []unstructured.Unstructured) (err error) {
+
+	for i := range targets {
+		var anchor *corev1.ConfigMap
+		anchor, err = r.EnsureAnchor(ctx, replika, targets[i].GetNamespace())
+		if err != nil {
+			return err
+		}
+
+		targets[i].SetOwnerReferences(append(targets[i].GetOwnerReferences(), metav1.OwnerReference{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+			Name:       anchor.Name,
+			UID:        anchor.UID,
+		}))
+	}
+
+	return err
+}
+
+// DeleteAnchors delete the anchors of the Replika in all the namespaces
+func (r *ReplikaReconciler) DeleteAnchors(ctx context.Context, replika *replikav1beta1.Replika) (err error) {
+
+	anchors := &corev1.ConfigMapList{}
+	err = r.uncachedReader().List(ctx, anchors, client.MatchingLabels(GetAnchorLabels(replika)))
+	if err != nil {
+		return err
+	}
+
+	for i := range anchors.Items {
+		uid := anchors.Items[i].UID
+		err = client.IgnoreNotFound(r.Delete(ctx, &anchors.Items[i], client.Preconditions{UID: &uid}))
+		if err != nil {
+			return err
+		}
+		r.RecordObjectAuditLog(ctx, replika, corev1.SchemeGroupVersion.WithKind("ConfigMap"), &anchors.Items[i],
+			auditlog.ActionDelete, auditReasonAnchor)
+	}
+
+	return err
+}
+
+// PruneAnchors delete the anchors of the namespaces the Replika does not target anymore, so their targets,
+// already pruned, are never kept by them. The namespaces rejected on this synchronization keep their anchors,
+// as they keep their targets
+func (r *ReplikaReconciler) PruneAnchors(ctx context.Context, replika *replikav1beta1.Replika, targets []unstructured.Unstructured) (err error) {
+
+	desired := map[string]bool{}
+	for i := range targets {
+		desired[targets[i].GetNamespace()] = true
+	}
+	for _, v := range replika.Status.RejectedNamespaces {
+		desired[v.Namespace] = true
+	}
+
+	anchors := &corev1.ConfigMapList{}
+	err = r.uncachedReader().List(ctx, anchors, client.MatchingLabels(GetAnchorLabels(replika)))
+	if err != nil {
+		return err
+	}
+
+	for i := range anchors.Items {
+		if desired[anchors.Items[i].Namespace] {
+			continue
+		}
+
+		uid := anchors.Items[i].UID
+		err = client.IgnoreNotFound(r.Delete(ctx, &anchors.Items[i], client.Preconditions{UID: &uid}))
+		if err != nil {
+			return err
+		}
+		LogInfof(ctx, anchorPruned, anchors.Items[i].Namespace, anchors.Items[i].Name)
+		r.RecordObjectAuditLog(ctx, replika, corev1.SchemeGroupVersion.WithKind("ConfigMap"), &anchors.Items[i],
+			auditlog.ActionDelete, auditReasonAnchor)
+	}
+
+	return err
+}
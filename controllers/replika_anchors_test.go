@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+func TestGetAnchorName(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		replika   string
+		expected  string
+	}{
+		{
+			name:      "short name",
+			namespace: "default",
+			replika:   "app-config",
+			expected:  "replika-anchor-default.app-config",
+		},
+		{
+			name:      "name over the limit",
+			namespace: "default",
+			replika:   strings.Repeat("a", 250),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: test.namespace, Name: test.replika}}
+
+			name := GetAnchorName(replika)
+			if test.expected != "" && name != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, name)
+			}
+			if msgs := validation.IsDNS1123Subdomain(name); len(msgs) > 0 {
+				t.Errorf("invalid name %s: %v", name, msgs)
+			}
+		})
+	}
+
+	// Replikas with the same name in other namespaces, or whose truncated names match, never share their anchors
+	first := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "app-config"}}
+	second := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "app-config"}}
+	if GetAnchorName(first) == GetAnchorName(second) {
+		t.Errorf("Replikas of different namespaces share the anchor %s", GetAnchorName(first))
+	}
+	first.Name, second.Name = strings.Repeat("a", 250)+"1", strings.Repeat("a", 250)+"2"
+	if GetAnchorName(first) == GetAnchorName(second) {
+		t.Errorf("truncated names share the anchor %s", GetAnchorName(first))
+	}
+}
+
+func TestGetAnchorLabels(t *testing.T) {
+	replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-config", UID: "1234"}}
+
+	labels := GetAnchorLabels(replika)
+	if labels[resourceReplikaLabelAnchorKey] != "1234" {
+		t.Errorf("expected the anchors labeled by the UID, got %v", labels)
+	}
+	if !IsCreatedByController(&metav1.ObjectMeta{Labels: labels}) {
+		t.Errorf("expected the anchors labeled as created by the controller, got %v", labels)
+	}
+}
+
+func TestEnsureAnchorReadsWithoutCache(t *testing.T) {
+	replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-config", UID: "uid"}}
+	existing := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "team-a",
+		Name:      GetAnchorName(replika),
+		Labels:    GetAnchorLabels(replika),
+		UID:       "anchor-uid",
+	}}
+
+	// The anchor is only known by the API server, the cached client has none
+	r := &ReplikaReconciler{
+		Client:    fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(),
+		APIReader: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(existing).Build(),
+	}
+
+	anchor, err := r.EnsureAnchor(context.Background(), replika, "team-a")
+	if err != nil {
+		t.Fatalf("unexpected error ensuring the anchor: %v", err)
+	}
+	if anchor.UID != existing.UID {
+		t.Errorf("expected the existing anchor read from the API server, got %v", anchor.UID)
+	}
+
+	list := &corev1.ConfigMapList{}
+	if err = r.List(context.Background(), list); err != nil || len(list.Items) != 0 {
+		t.Errorf("expected no anchor created, got %d: %v", len(list.Items), err)
+	}
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/replicator"
+)
+
+// GetTargetHash return a hash of the target restricted to the fields defined on the reference.
+// Fields set by the API server or by other controllers are not part of the hash, so only real drifts are detected
+func GetTargetHash(target, reference *unstructured.Unstructured) (hash string, err error) {
+
+	content := map[string]interface{}{}
+	for k := range reference.Object {
+		if k == "metadata" || k == "status" {
+			continue
+		}
+		content[k] = target.Object[k]
+	}
+
+	labels := map[string]string{}
+	for k := range reference.GetLabels() {
+		labels[k] = target.GetLabels()[k]
+	}
+	annotations := map[string]string{}
+	for k := range reference.GetAnnotations() {
+		annotations[k] = target.GetAnnotations()[k]
+	}
+	content["labels"] = labels
+	content["annotations"] = annotations
+
+	var contentJSON []byte
+	contentJSON, err = json.Marshal(content)
+	if err != nil {
+		return hash, err
+	}
+
+	sum := sha256.Sum256(contentJSON)
+	hash = hex.EncodeToString(sum[:])
+	return hash, err
+}
+
+// GetTargetDrift return who modified the target, being the most recent entry of its managed fields
+// not written by the controller. The user is not recorded there, only the field manager
+func GetTargetDrift(target *unstructured.Unstructured) (drift replikav1beta1.ReplikaDriftStatus) {
+
+	drift.Namespace = target.GetNamespace()
+
+	for _, entry := range target.GetManagedFields() {
+		if entry.Manager == replicator.FieldManager || entry.Time == nil {
+			continue
+		}
+		if drift.Time != nil && !drift.Time.Before(entry.Time) {
+			continue
+		}
+		drift.Manager = entry.Manager
+		drift.Operation = string(entry.Operation)
+		drift.Time = entry.Time.DeepCopy()
+	}
+
+	return drift
+}
+
+// RecordTargetDrift emit an Event on the Replika identifying who modified a drifted target
+func (r *ReplikaReconciler) RecordTargetDrift(replika *replikav1beta1.Replika, drift replikav1beta1.ReplikaDriftStatus) {
+	if r.Recorder == nil {
+		return
+	}
+
+	if drift.Manager == "" {
+		r.Recorder.Eventf(replika, corev1.EventTypeWarning, ConditionReasonTargetsDrifted, targetDriftUnknownEvent, drift.Namespace)
+		return
+	}
+	r.Recorder.Eventf(replika, corev1.EventTypeWarning, ConditionReasonTargetsDrifted, targetDriftEvent,
+		drift.Namespace, drift.Manager, drift.Operation, drift.Time.String())
+}
+
+// RecordTargetWrite emit an Event in the namespace of the target each time it is created or replaced,
+// identifying the Replika and the revision of the source
+func (r *ReplikaReconciler) RecordTargetWrite(replika *replikav1beta1.Replika, target *unstructured.Unstructured, result replicator.Result) {
+	if r.Recorder == nil {
+		return
+	}
+
+	revision, err := GetTargetHash(target, target)
+	if err != nil {
+		return
+	}
+
+	r.Recorder.Eventf(target, corev1.EventTypeNormal, targetWriteEventReason+string(result), targetWriteEvent,
+		replika.Namespace, replika.Name, strings.ToLower(string(result)), GetSourceRef(replika), revision[:12])
+}
+
+// IsAuditDue return true when the targets of the Replika must be audited on this synchronization
+func (r *ReplikaReconciler) IsAuditDue(replika *replikav1beta1.Replika) bool {
+	if replika.Spec.Synchronization.AuditOnly {
+		return true
+	}
+	if r.AuditInterval <= 0 {
+		return false
+	}
+	if replika.Status.Integrity == nil {
+		return true
+	}
+	return time.Since(replika.Status.Integrity.LastAuditTime.Time) >= r.AuditInterval
+}
+
+// AuditTargets compare the hash of the existing targets against the ones derived from the source,
+// recording the drifted and missing targets in the status of the Replika. Nothing is modified on the targets
+func (r *ReplikaReconciler) AuditTargets(ctx context.Context, replika *replikav1beta1.Replika, targets []unstructured.Unstructured) (err error) {
+
+	integrity := &replikav1beta1.ReplikaIntegrityStatus{
+		LastAuditTime: metav1.Now(),
+		Targets:       len(targets),
+	}
+
+	for i := range targets {
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(targets[i].GroupVersionKind())
+		err = r.Get(ctx, client.ObjectKey{
+			Namespace: targets[i].GetNamespace(),
+			Name:      targets[i].GetName(),
+		}, existing)
+
+		if apierrors.IsNotFound(err) {
+			integrity.MissingNamespaces = append(integrity.MissingNamespaces, targets[i].GetNamespace())
+			err = nil
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		var desiredHash, existingHash string
+		desiredHash, err = GetTargetHash(&targets[i], &targets[i])
+		if err != nil {
+			return err
+		}
+		existingHash, err = GetTargetHash(existing, &targets[i])
+		if err != nil {
+			return err
+		}
+
+		if desiredHash != existingHash {
+			integrity.DriftedNamespaces = append(integrity.DriftedNamespaces, targets[i].GetNamespace())
+
+			drift := GetTargetDrift(existing)
+			integrity.DriftedTargets = append(integrity.DriftedTargets, drift)
+			r.RecordTargetDrift(replika, drift)
+		}
+	}
+
+	replika.Status.Integrity = integrity
+
+	healthy := integrity.Targets - len(integrity.DriftedNamespaces) - len(integrity.MissingNamespaces)
+	setReplikaGauge(integrityTargets, replika.Namespace, replika.Name, float64(healthy), integrityStateSynced)
+	setReplikaGauge(integrityTargets, replika.Namespace, replika.Name, float64(len(integrity.DriftedNamespaces)), integrityStateDrifted)
+	setReplikaGauge(integrityTargets, replika.Namespace, replika.Name, float64(len(integrity.MissingNamespaces)), integrityStateMissing)
+
+	if len(integrity.DriftedNamespaces) > 0 || len(integrity.MissingNamespaces) > 0 {
+		LogInfof(ctx, auditDriftDetected, replika.Name, len(integrity.DriftedNamespaces), len(integrity.MissingNamespaces))
+	}
+
+	return err
+}
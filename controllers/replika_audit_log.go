@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/auditlog"
+	"prosimcorp.com/replika/pkg/replicator"
+)
+
+const (
+	// Reasons of the writes recorded in the audit log
+	auditReasonSynchronization = "synchronization"
+	auditReasonDeletion        = "deletion"
+	auditReasonUnconsumed      = "unconsumed"
+	auditReasonPruned          = "pruned"
+	auditReasonReload          = "reload"
+	auditReasonSwitch          = "switch"
+	auditReasonAnchor          = "anchor"
+	auditReasonHook            = "hook"
+)
+
+// auditActions maps the changes made by UpdateTarget to the actions of the audit log
+var auditActions = map[replicator.Result]string{
+	replicator.ResultCreated:   auditlog.ActionCreate,
+	replicator.ResultUpdated:   auditlog.ActionUpdate,
+	replicator.ResultRecreated: auditlog.ActionUpdate,
+}
+
+// RecordAuditLog write a change made on a target in the audit log, when enabled.
+// The records dropped by the sink are counted and logged, never breaking the synchronization
+func (r *ReplikaReconciler) RecordAuditLog(ctx context.Context, replika *replikav1beta1.Replika, target *unstructured.Unstructured, action, reason string) {
+	if r.AuditLog == nil || action == "" {
+		return
+	}
+
+	record := newAuditRecord(replika, target.GroupVersionKind(), target, action, reason)
+	if action != auditlog.ActionDelete {
+		record.Revision, _ = GetTargetHash(target, target)
+	}
+	r.writeAuditRecord(ctx, record)
+}
+
+// RecordObjectAuditLog write a change made on an object other than the targets in the audit log, when enabled,
+// like the workloads patched, the anchors or the hook Jobs. The kind is given, as typed objects usually lack it
+func (r *ReplikaReconciler) RecordObjectAuditLog(ctx context.Context, replika *replikav1beta1.Replika, gvk schema.GroupVersionKind, object metav1.Object, action, reason string) {
+	if r.AuditLog == nil || action == "" {
+		return
+	}
+	r.writeAuditRecord(ctx, newAuditRecord(replika, gvk, object, action, reason))
+}
+
+// newAuditRecord return the record of a change made on an object for a Replika
+func newAuditRecord(replika *replikav1beta1.Replika, gvk schema.GroupVersionKind, object metav1.Object, action, reason string) auditlog.Record {
+	return auditlog.Record{
+		Time:      time.Now(),
+		Actor:     replicator.FieldManager,
+		Action:    action,
+		Reason:    reason,
+		Replika:   replika.Namespace + "/" + replika.Name,
+		Source:    GetSourceRef(replika),
+		Kind:      gvk.GroupVersion().String() + "/" + gvk.Kind,
+		Namespace: object.GetNamespace(),
+		Name:      object.GetName(),
+	}
+}
+
+// writeAuditRecord write a record in the audit log, counting the records dropped by the sink
+func (r *ReplikaReconciler) writeAuditRecord(ctx context.Context, record auditlog.Record) {
+	err := r.AuditLog.Write(ctx, record)
+	if err != nil {
+		if errors.Is(err, auditlog.ErrBufferFull) {
+			droppedAuditRecords.Inc()
+		}
+		LogErrorDedupf(ctx, auditLogError, err.Error())
+	}
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/auditlog"
+)
+
+// memorySink stores the records written
+type memorySink struct {
+	records []auditlog.Record
+}
+
+func (s *memorySink) Write(ctx context.Context, record auditlog.Record) (err error) {
+	s.records = append(s.records, record)
+	return err
+}
+
+func TestAuditLogAnchors(t *testing.T) {
+	replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-config", UID: "uid"}}
+	sink := &memorySink{}
+	r := &ReplikaReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(), AuditLog: sink}
+
+	if _, err := r.EnsureAnchor(context.Background(), replika, "team-a"); err != nil {
+		t.Fatalf("unexpected error ensuring the anchor: %v", err)
+	}
+	if err := r.DeleteAnchors(context.Background(), replika); err != nil {
+		t.Fatalf("unexpected error deleting the anchors: %v", err)
+	}
+
+	expected := []string{auditlog.ActionCreate, auditlog.ActionDelete}
+	if len(sink.records) != len(expected) {
+		t.Fatalf("expected %d records, got %+v", len(expected), sink.records)
+	}
+	for i, record := range sink.records {
+		if record.Action != expected[i] || record.Reason != auditReasonAnchor || record.Kind != "v1/ConfigMap" ||
+			record.Namespace != "team-a" || record.Name != GetAnchorName(replika) || record.Replika != "default/app-config" {
+			t.Errorf("unexpected record %d: %+v", i, record)
+		}
+	}
+}
+
+func TestAuditLogBufferFull(t *testing.T) {
+	replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-config"}}
+	object := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "app-config"}}
+
+	// The buffer is never drained, the second record is dropped
+	r := &ReplikaReconciler{AuditLog: auditlog.NewBuffered(&memorySink{}, 1)}
+	dropped := testutil.ToFloat64(droppedAuditRecords)
+	for i := 0; i < 2; i++ {
+		r.RecordObjectAuditLog(context.Background(), replika, corev1.SchemeGroupVersion.WithKind("ConfigMap"), object,
+			auditlog.ActionUpdate, auditReasonReload)
+	}
+
+	if count := testutil.ToFloat64(droppedAuditRecords) - dropped; count != 1 {
+		t.Errorf("expected 1 record dropped, got %v", count)
+	}
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+// newAuditTarget return a ConfigMap target in the namespace with the data
+func newAuditTarget(namespace, value string) *unstructured.Unstructured {
+	target := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"data":       map[string]interface{}{"key": value},
+	}}
+	target.SetNamespace(namespace)
+	target.SetName("app-config")
+	target.SetLabels(map[string]string{"app": "web"})
+	return target
+}
+
+func TestGetTargetHash(t *testing.T) {
+	reference := newAuditTarget("team-a", "value")
+
+	// Fields not defined on the reference are ignored
+	existing := reference.DeepCopy()
+	existing.SetResourceVersion("42")
+	existing.SetLabels(map[string]string{"app": "web", "team": "a"})
+	existing.Object["binaryData"] = map[string]interface{}{"other": "dmFsdWU="}
+
+	desiredHash, err := GetTargetHash(reference, reference)
+	if err != nil {
+		t.Fatalf("unexpected error hashing the target: %v", err)
+	}
+	existingHash, err := GetTargetHash(existing, reference)
+	if err != nil {
+		t.Fatalf("unexpected error hashing the target: %v", err)
+	}
+	if desiredHash != existingHash {
+		t.Errorf("expected the fields not managed ignored")
+	}
+
+	// The fields defined on the reference are compared
+	drifted := newAuditTarget("team-a", "changed")
+	driftedHash, err := GetTargetHash(drifted, reference)
+	if err != nil {
+		t.Fatalf("unexpected error hashing the target: %v", err)
+	}
+	if driftedHash == desiredHash {
+		t.Errorf("expected the drift detected")
+	}
+}
+
+func TestIsAuditDue(t *testing.T) {
+	recent := &replikav1beta1.ReplikaIntegrityStatus{LastAuditTime: metav1.NewTime(time.Now().Add(-time.Minute))}
+	old := &replikav1beta1.ReplikaIntegrityStatus{LastAuditTime: metav1.NewTime(time.Now().Add(-time.Hour))}
+
+	tests := []struct {
+		name      string
+		interval  time.Duration
+		auditOnly bool
+		integrity *replikav1beta1.ReplikaIntegrityStatus
+		expected  bool
+	}{
+		{name: "audit disabled"},
+		{name: "audit-only Replika", auditOnly: true, expected: true},
+		{name: "never audited", interval: 10 * time.Minute, expected: true},
+		{name: "audited recently", interval: 10 * time.Minute, integrity: recent},
+		{name: "audit expired", interval: 10 * time.Minute, integrity: old, expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			replika := &replikav1beta1.Replika{}
+			replika.Spec.Synchronization.AuditOnly = test.auditOnly
+			replika.Status.Integrity = test.integrity
+			r := &ReplikaReconciler{AuditInterval: test.interval}
+			if due := r.IsAuditDue(replika); due != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, due)
+			}
+		})
+	}
+}
+
+func TestAuditTargets(t *testing.T) {
+	replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-config"}}
+
+	// team-a is synchronized, team-b drifted and team-c missing
+	existing := []*corev1.ConfigMap{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "app-config", Labels: map[string]string{"app": "web"}}, Data: map[string]string{"key": "value"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "app-config", Labels: map[string]string{"app": "web"}}, Data: map[string]string{"key": "changed"}},
+	}
+	builder := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+	for _, configMap := range existing {
+		builder = builder.WithObjects(configMap)
+	}
+	r := &ReplikaReconciler{Client: builder.Build()}
+
+	targets := []unstructured.Unstructured{
+		*newAuditTarget("team-a", "value"),
+		*newAuditTarget("team-b", "value"),
+		*newAuditTarget("team-c", "value"),
+	}
+	if err := r.AuditTargets(context.Background(), replika, targets); err != nil {
+		t.Fatalf("unexpected error auditing the targets: %v", err)
+	}
+
+	integrity := replika.Status.Integrity
+	if integrity == nil || integrity.Targets != 3 {
+		t.Fatalf("expected the 3 targets audited, got %+v", integrity)
+	}
+	if len(integrity.DriftedNamespaces) != 1 || integrity.DriftedNamespaces[0] != "team-b" {
+		t.Errorf("expected team-b drifted, got %v", integrity.DriftedNamespaces)
+	}
+	if len(integrity.MissingNamespaces) != 1 || integrity.MissingNamespaces[0] != "team-c" {
+		t.Errorf("expected team-c missing, got %v", integrity.MissingNamespaces)
+	}
+}
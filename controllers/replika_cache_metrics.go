@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+const (
+	cacheMetricsError = "Can not measure the cached %s: %s"
+)
+
+// CacheMetrics measures periodically the objects held by the informers of the controller,
+// so the capacity of large clusters can be planned from the metrics. Only the informers always
+// started by the controller and the watches of the sources are measured, as listing any other
+// kind would start its informer
+type CacheMetrics struct {
+	Client        client.Reader
+	SourceWatcher *SourceWatcher
+
+	// Pods measures the informer of the Pods, only started when the consumer Pods are watched
+	Pods bool
+
+	// Interval is the time between two measures
+	Interval time.Duration
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, as every replica holds its own cache
+func (c *CacheMetrics) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable, measuring the informers until the manager stops
+func (c *CacheMetrics) Start(ctx context.Context) (err error) {
+
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return err
+		case <-ticker.C:
+			c.Measure(ctx)
+		}
+	}
+}
+
+// Measure update the metrics of the informers
+func (c *CacheMetrics) Measure(ctx context.Context) {
+
+	cacheObjects.Reset()
+	cacheEstimatedBytes.Reset()
+
+	lists := map[string]client.ObjectList{
+		"replika.prosimcorp.com/v1beta1/Replika": &replikav1beta1.ReplikaList{},
+		"v1/Namespace":                           &corev1.NamespaceList{},
+	}
+	if c.Pods {
+		lists["v1/Pod"] = &corev1.PodList{}
+	}
+	for informer, list := range lists {
+		err := c.Client.List(ctx, list)
+		if err != nil {
+			LogErrorDedupf(ctx, cacheMetricsError, informer, err.Error())
+			continue
+		}
+
+		var objects []interface{}
+		switch items := list.(type) {
+		case *replikav1beta1.ReplikaList:
+			for i := range items.Items {
+				objects = append(objects, &items.Items[i])
+			}
+		case *corev1.NamespaceList:
+			for i := range items.Items {
+				objects = append(objects, &items.Items[i])
+			}
+		case *corev1.PodList:
+			for i := range items.Items {
+				objects = append(objects, &items.Items[i])
+			}
+		}
+		setCacheMetrics(informer, objects)
+	}
+
+	if c.SourceWatcher != nil {
+		for gvk, objects := range c.SourceWatcher.GetCachedObjects() {
+			setCacheMetrics(gvk.GroupVersion().String()+"/"+gvk.Kind, objects)
+		}
+	}
+}
+
+// setCacheMetrics set the number and the estimated size of the objects of an informer
+func setCacheMetrics(informer string, objects []interface{}) {
+	size := 0
+	for _, object := range objects {
+		size += estimateSize(object)
+	}
+	cacheObjects.WithLabelValues(informer).Set(float64(len(objects)))
+	cacheEstimatedBytes.WithLabelValues(informer).Set(float64(size))
+}
+
+// estimateSize return the serialized size of an object, the protobuf one when available as it is cheaper to compute
+func estimateSize(object interface{}) int {
+	if sizer, ok := object.(interface{ Size() int }); ok {
+		return sizer.Size()
+	}
+
+	content, err := json.Marshal(object)
+	if err != nil {
+		return 0
+	}
+	return len(content)
+}
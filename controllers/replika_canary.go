@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/conditions"
+	"prosimcorp.com/replika/pkg/replicator"
+)
+
+// errCanaryPending is returned while the canary namespace is waiting for its verification
+var errCanaryPending = &PendingError{reason: "the canary namespace is waiting for its verification"}
+
+// GetJobResult return whether the Job completed or failed after the given time
+func GetJobResult(job *batchv1.Job, after time.Time) (completed, failed bool) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue || condition.LastTransitionTime.Time.Before(after) {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			completed = true
+		case batchv1.JobFailed:
+			failed = true
+		}
+	}
+	return completed, failed
+}
+
+// VerifyCanary check the target written in the canary namespace matches the source, and that the verification
+// Job completed after the revision was written, when defined
+func (r *ReplikaReconciler) VerifyCanary(ctx context.Context, replika *replikav1beta1.Replika, target *unstructured.Unstructured) (err error) {
+
+	canary := replika.Spec.Target.Rollout.Canary
+
+	// Read the target back, as mutating admission controllers may change it
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(target.GroupVersionKind())
+	err = r.Get(ctx, client.ObjectKey{Namespace: target.GetNamespace(), Name: target.GetName()}, existing)
+	if err != nil {
+		return err
+	}
+
+	var desiredHash, existingHash string
+	desiredHash, err = GetTargetHash(target, target)
+	if err != nil {
+		return err
+	}
+	existingHash, err = GetTargetHash(existing, target)
+	if err != nil {
+		return err
+	}
+	if desiredHash != existingHash {
+		err = NewErrorf(canaryMismatchError, canary.Namespace)
+		return err
+	}
+
+	// A new revision must be verified again
+	if replika.Status.Canary == nil || replika.Status.Canary.Revision != desiredHash {
+		replika.Status.Canary = &replikav1beta1.ReplikaCanaryStatus{
+			Revision:  desiredHash,
+			WriteTime: metav1.Now(),
+		}
+	}
+	if replika.Status.Canary.Verified || canary.VerificationJob == "" {
+		replika.Status.Canary.Verified = true
+		return err
+	}
+
+	job := &batchv1.Job{}
+	err = r.Get(ctx, client.ObjectKey{Namespace: canary.Namespace, Name: canary.VerificationJob}, job)
+	if apierrors.IsNotFound(err) {
+		return errCanaryPending
+	}
+	if err != nil {
+		return err
+	}
+
+	completed, failed := GetJobResult(job, replika.Status.Canary.WriteTime.Time)
+	if failed {
+		err = NewErrorf(canaryJobFailedError, canary.VerificationJob, canary.Namespace)
+		return err
+	}
+	if !completed {
+		return errCanaryPending
+	}
+
+	replika.Status.Canary.Verified = true
+	return err
+}
+
+// RolloutCanary write and verify the target of the canary namespace, returning the rest of the targets.
+// errCanaryPending is returned while the verification is not finished
+func (r *ReplikaReconciler) RolloutCanary(ctx context.Context, replika *replikav1beta1.Replika, targets []unstructured.Unstructured) (pending []unstructured.Unstructured, err error) {
+
+	canary := replika.Spec.Target.Rollout.Canary
+
+	canaryIndex := -1
+	for i := range targets {
+		if targets[i].GetNamespace() == canary.Namespace {
+			canaryIndex = i
+			continue
+		}
+		pending = append(pending, targets[i])
+	}
+	if canaryIndex < 0 {
+		err = NewPermanentErrorf(canaryNotTargetError, canary.Namespace)
+		return pending, err
+	}
+
+	var result replicator.Result
+	requestCtx, cancel := r.requestContext(ctx, replika)
+	result, err = r.UpdateTarget(requestCtx, replika, &targets[canaryIndex], false)
+	cancel()
+	observeTargetWrite(replika.Namespace, replika.Name, &targets[canaryIndex], result, err)
+	if err == nil {
+		AddSyncedNamespace(replika, canary.Namespace)
+		AddInventoryEntries(replika, NewInventoryEntry(&targets[canaryIndex]))
+		r.RecordAuditLog(ctx, replika, &targets[canaryIndex], auditActions[result], auditReasonSynchronization)
+		err = r.VerifyCanary(ctx, replika, &targets[canaryIndex])
+	} else {
+		AddFailedNamespace(replika, canary.Namespace)
+	}
+
+	switch {
+	case errors.Is(err, errCanaryPending):
+		r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+			metav1.ConditionFalse,
+			ConditionReasonCanaryPending,
+			ConditionReasonCanaryPendingMessage,
+		))
+	case err != nil:
+		reason, message := GetFailureReason(err, ConditionReasonCanaryFailed, ConditionReasonCanaryFailedMessage)
+		r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+			metav1.ConditionFalse,
+			reason,
+			message,
+		))
+	}
+
+	return pending, err
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"crypto/x509"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/conditions"
+	"prosimcorp.com/replika/pkg/replicator"
+)
+
+// CheckCertificateExpiry set the CertificateExpiringSoon condition of the Replika when the certificate of its
+// TLS source expires within the warning, exporting the days left as a metric
+func (r *ReplikaReconciler) CheckCertificateExpiry(replika *replikav1beta1.Replika, source *unstructured.Unstructured, now time.Time) (err error) {
+
+	var warning time.Duration
+	warning, err = time.ParseDuration(replika.Spec.Source.ExpiryWarning)
+	if err != nil {
+		return err
+	}
+
+	var certificate *x509.Certificate
+	certificate, err = replicator.GetTLSCertificate(source)
+	if err != nil {
+		return err
+	}
+
+	left := certificate.NotAfter.Sub(now)
+	setCertificateExpiryDays(replika.Namespace, replika.Name, left.Hours()/24)
+
+	expiry := certificate.NotAfter.Format(time.RFC3339)
+	condition := conditions.New(ConditionTypeCertificateExpiringSoon,
+		metav1.ConditionFalse,
+		ConditionReasonCertificateValid,
+		ConditionReasonCertificateValidMessage, expiry,
+	)
+	if left <= warning {
+		condition = conditions.New(ConditionTypeCertificateExpiringSoon,
+			metav1.ConditionTrue,
+			ConditionReasonCertificateExpiring,
+			ConditionReasonCertificateExpiringMessage, expiry,
+		)
+	}
+	r.SetReplikaCondition(replika, condition)
+
+	return err
+}
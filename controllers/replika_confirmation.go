@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/conditions"
+)
+
+const (
+	// Annotation acknowledging the operations with a high blast radius, as <operation>/<generation>
+	confirmationAnnotation = "replika.prosimcorp.com/confirmed"
+
+	// Operations waiting for the confirmation
+	confirmationOperationSync          = "sync"
+	confirmationOperationDelete        = "delete"
+	confirmationOperationDeleteTargets = "delete-targets"
+)
+
+// errConfirmationPending is returned while an operation waits for the confirmation annotation
+var errConfirmationPending = &PendingError{reason: "the operation waits for the confirmation annotation"}
+
+// GetConfirmationValue return the value of the confirmation annotation approving the operation on the current
+// generation of the Replika, so a confirmation never approves a later spec or another operation
+func GetConfirmationValue(replika *replikav1beta1.Replika, operation string) string {
+	return fmt.Sprintf("%s/%d", operation, replika.Generation)
+}
+
+// IsConfirmed return true when the confirmation annotation approves the operation on the current generation
+func IsConfirmed(replika *replikav1beta1.Replika, operation string) bool {
+	return replika.GetAnnotations()[confirmationAnnotation] == GetConfirmationValue(replika, operation)
+}
+
+// ClearConfirmation remove the confirmation annotation once the operation it approved is done,
+// so it is never used again
+func (r *ReplikaReconciler) ClearConfirmation(ctx context.Context, replika *replikav1beta1.Replika) (err error) {
+
+	if _, found := replika.GetAnnotations()[confirmationAnnotation]; !found {
+		return err
+	}
+
+	// The copy is patched so the status computed on the Replika is kept
+	patched := replika.DeepCopy()
+	delete(patched.Annotations, confirmationAnnotation)
+	err = r.Patch(ctx, patched, client.MergeFrom(replika))
+	if err != nil {
+		return err
+	}
+
+	replika.SetAnnotations(patched.GetAnnotations())
+	replika.SetResourceVersion(patched.GetResourceVersion())
+	return err
+}
+
+// CheckSyncConfirmation return errConfirmationPending when the Replika replicates a Secret in every namespace
+// it can target without being confirmed, and the operator requires it. The namespaces resolved for the targets
+// are counted, whatever selected them
+func (r *ReplikaReconciler) CheckSyncConfirmation(ctx context.Context, replika *replikav1beta1.Replika, targets []unstructured.Unstructured) (err error) {
+
+	if !r.ConfirmSecretsInAllNamespaces || replika.Spec.Source.Kind != "Secret" {
+		return err
+	}
+	if IsConfirmed(replika, confirmationOperationSync) {
+		return err
+	}
+
+	// Namespaces resolved for the targets, including the rejected ones
+	resolved := map[string]bool{}
+	for i := range targets {
+		resolved[targets[i].GetNamespace()] = true
+	}
+	for _, rejected := range replika.Status.RejectedNamespaces {
+		resolved[rejected.Namespace] = true
+	}
+	if len(resolved) == 0 {
+		return err
+	}
+
+	// Namespaces the Replika can target
+	namespaceList := &corev1.NamespaceList{}
+	err = r.List(ctx, namespaceList)
+	if err != nil {
+		return err
+	}
+	settings := r.settings()
+	for _, namespace := range namespaceList.Items {
+		if namespace.Name == replika.Spec.Source.Namespace || !namespace.DeletionTimestamp.IsZero() ||
+			settings.IsNamespaceProtected(namespace.Name) {
+			continue
+		}
+		if !resolved[namespace.Name] {
+			return err
+		}
+	}
+
+	r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+		metav1.ConditionFalse,
+		ConditionReasonPendingConfirmation,
+		ConditionReasonPendingSyncConfirmationMessage, confirmationAnnotation,
+		GetConfirmationValue(replika, confirmationOperationSync),
+	))
+	return errConfirmationPending
+}
+
+// CheckDeletionConfirmation return errConfirmationPending when deleting the Replika would remove more targets
+// than the confirmation threshold without being confirmed
+func (r *ReplikaReconciler) CheckDeletionConfirmation(ctx context.Context, replika *replikav1beta1.Replika) (err error) {
+	return r.checkTargetsDeletionConfirmation(ctx, replika, confirmationOperationDelete,
+		ConditionReasonPendingDeletionConfirmationMessage)
+}
+
+// CheckSourceDeletionConfirmation return errConfirmationPending when deleting the targets of a missing source
+// would remove more of them than the confirmation threshold without being confirmed
+func (r *ReplikaReconciler) CheckSourceDeletionConfirmation(ctx context.Context, replika *replikav1beta1.Replika) (err error) {
+	return r.checkTargetsDeletionConfirmation(ctx, replika, confirmationOperationDeleteTargets,
+		ConditionReasonPendingSourceDeletionConfirmationMessage)
+}
+
+// checkTargetsDeletionConfirmation return errConfirmationPending when the targets of the Replika exceed the
+// confirmation threshold without the operation being confirmed, reporting it with the message
+func (r *ReplikaReconciler) checkTargetsDeletionConfirmation(ctx context.Context, replika *replikav1beta1.Replika, operation, message string) (err error) {
+
+	if r.ConfirmationThreshold <= 0 || IsConfirmed(replika, operation) {
+		return err
+	}
+
+	var targets []unstructured.Unstructured
+	targets, _, err = r.ListTargets(ctx, replika)
+	if err != nil || len(targets) <= r.ConfirmationThreshold {
+		return err
+	}
+
+	r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+		metav1.ConditionFalse,
+		ConditionReasonPendingConfirmation,
+		message, len(targets), confirmationAnnotation, GetConfirmationValue(replika, operation),
+	))
+	return errConfirmationPending
+}
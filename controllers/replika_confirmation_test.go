@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+func TestIsConfirmed(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		operation string
+		expected  bool
+	}{
+		{name: "current generation", value: "sync/3", operation: confirmationOperationSync, expected: true},
+		{name: "previous generation", value: "sync/2", operation: confirmationOperationSync},
+		{name: "another operation", value: "delete/3", operation: confirmationOperationSync},
+		{name: "deletion of the targets", value: "delete-targets/3", operation: confirmationOperationDeleteTargets, expected: true},
+		{name: "not tied to an operation", value: "true", operation: confirmationOperationDelete},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{
+				Generation:  3,
+				Annotations: map[string]string{confirmationAnnotation: test.value},
+			}}
+			if confirmed := IsConfirmed(replika, test.operation); confirmed != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, confirmed)
+			}
+		})
+	}
+}
+
+func TestCheckSyncConfirmation(t *testing.T) {
+	tests := []struct {
+		name       string
+		kind       string
+		namespaces []string
+		rejected   []string
+		confirmed  string
+		pending    bool
+	}{
+		{name: "Secret in every namespace", kind: "Secret", namespaces: []string{"team-a", "team-b"}, pending: true},
+		{name: "Secret in every namespace, one rejected", kind: "Secret", namespaces: []string{"team-a"}, rejected: []string{"team-b"}, pending: true},
+		{name: "Secret in some namespaces", kind: "Secret", namespaces: []string{"team-a"}},
+		{name: "ConfigMap in every namespace", kind: "ConfigMap", namespaces: []string{"team-a", "team-b"}},
+		{name: "confirmed generation", kind: "Secret", namespaces: []string{"team-a", "team-b"}, confirmed: "sync/2"},
+		{name: "previous generation confirmed", kind: "Secret", namespaces: []string{"team-a", "team-b"}, confirmed: "sync/1", pending: true},
+	}
+
+	var namespaces []client.Object
+	for _, name := range []string{"default", "team-a", "team-b"} {
+		namespaces = append(namespaces, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app", Generation: 2}}
+			if test.confirmed != "" {
+				replika.Annotations = map[string]string{confirmationAnnotation: test.confirmed}
+			}
+			replika.Spec.Source = replikav1beta1.ReplikaSourceSpec{Version: "v1", Kind: test.kind, Name: "app", Namespace: "default"}
+			for _, namespace := range test.rejected {
+				replika.Status.RejectedNamespaces = append(replika.Status.RejectedNamespaces,
+					replikav1beta1.ReplikaNamespaceStatus{Namespace: namespace})
+			}
+
+			var targets []unstructured.Unstructured
+			for _, namespace := range test.namespaces {
+				target := unstructured.Unstructured{}
+				target.SetNamespace(namespace)
+				targets = append(targets, target)
+			}
+
+			r := &ReplikaReconciler{
+				Client:                        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(namespaces...).Build(),
+				ConfirmSecretsInAllNamespaces: true,
+			}
+			err := r.CheckSyncConfirmation(context.Background(), replika, targets)
+			if pending := err == errConfirmationPending; pending != test.pending {
+				t.Errorf("expected pending %t, got %v", test.pending, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/auditlog"
+)
+
+const (
+	// Minimum time between two scans of the consumers of the same Replika
+	consumersScanInterval = 5 * time.Minute
+)
+
+// PodSpecPullsWithTarget return true when the target is used as an image pull secret on the pod spec
+func PodSpecPullsWithTarget(podSpec *corev1.PodSpec, target *unstructured.Unstructured) bool {
+
+	if !IsReloadableTarget(target) || target.GetKind() != "Secret" {
+		return false
+	}
+
+	for _, pullSecret := range podSpec.ImagePullSecrets {
+		if pullSecret.Name == target.GetName() {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTargetConsumers return the workloads referencing the target inside its namespace.
+// Pods are only considered when they are not owned by another object, as their owners are already listed
+func (r *ReplikaReconciler) GetTargetConsumers(ctx context.Context, target *unstructured.Unstructured) (consumers []replikav1beta1.ReplikaConsumerStatus, err error) {
+
+	namespace := client.InNamespace(target.GetNamespace())
+	references := func(podSpec *corev1.PodSpec) bool {
+		return PodSpecReferencesTarget(podSpec, target) || PodSpecPullsWithTarget(podSpec, target)
+	}
+	appendConsumer := func(kind, name string) {
+		consumers = append(consumers, replikav1beta1.ReplikaConsumerStatus{
+			Namespace: target.GetNamespace(),
+			Kind:      kind,
+			Name:      name,
+		})
+	}
+
+	deployments := &appsv1.DeploymentList{}
+	err = r.List(ctx, deployments, namespace)
+	if err != nil {
+		return consumers, err
+	}
+	for _, v := range deployments.Items {
+		if references(&v.Spec.Template.Spec) {
+			appendConsumer("Deployment", v.Name)
+		}
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	err = r.List(ctx, statefulSets, namespace)
+	if err != nil {
+		return consumers, err
+	}
+	for _, v := range statefulSets.Items {
+		if references(&v.Spec.Template.Spec) {
+			appendConsumer("StatefulSet", v.Name)
+		}
+	}
+
+	daemonSets := &appsv1.DaemonSetList{}
+	err = r.List(ctx, daemonSets, namespace)
+	if err != nil {
+		return consumers, err
+	}
+	for _, v := range daemonSets.Items {
+		if references(&v.Spec.Template.Spec) {
+			appendConsumer("DaemonSet", v.Name)
+		}
+	}
+
+	pods := &corev1.PodList{}
+	err = r.List(ctx, pods, namespace)
+	if err != nil {
+		return consumers, err
+	}
+	for _, v := range pods.Items {
+		if len(v.OwnerReferences) == 0 && references(&v.Spec) {
+			appendConsumer("Pod", v.Name)
+		}
+	}
+
+	return consumers, err
+}
+
+// UpdateConsumers discover the workloads referencing the targets and record them in the status.
+// The scan is done from the informers cache and is skipped until consumersScanInterval is reached.
+// Only the first consumers are listed, so the status stays small on large clusters
+func (r *ReplikaReconciler) UpdateConsumers(ctx context.Context, replika *replikav1beta1.Replika, targets []unstructured.Unstructured) (err error) {
+
+	if !replika.Spec.Target.DiscoverConsumers {
+		replika.Status.Consumers = nil
+		replika.Status.ConsumersCount = 0
+		replika.Status.ConsumersScanTime = nil
+		return err
+	}
+
+	scanTime := replika.Status.ConsumersScanTime
+	if scanTime != nil && time.Since(scanTime.Time) < consumersScanInterval {
+		return err
+	}
+
+	var consumers []replikav1beta1.ReplikaConsumerStatus
+	for i := range targets {
+		var targetConsumers []replikav1beta1.ReplikaConsumerStatus
+		targetConsumers, err = r.GetTargetConsumers(ctx, &targets[i])
+		if err != nil {
+			return err
+		}
+		consumers = append(consumers, targetConsumers...)
+	}
+
+	now := metav1.Now()
+	replika.Status.ConsumersCount = len(consumers)
+	if len(consumers) > maxStatusNamespaces {
+		consumers = consumers[:maxStatusNamespaces]
+	}
+	replika.Status.Consumers = consumers
+	replika.Status.ConsumersScanTime = &now
+
+	return err
+}
+
+// GetConsumingNamespaces return the namespaces where a workload or Pod references an object with the kind
+// and name of the source. Pods are included even when owned, as they may be failing to start because of it
+func (r *ReplikaReconciler) GetConsumingNamespaces(ctx context.Context, source *unstructured.Unstructured) (namespaces map[string]bool, err error) {
+
+	namespaces = map[string]bool{}
+	references := func(podSpec *corev1.PodSpec) bool {
+		return PodSpecReferencesTarget(podSpec, source) || PodSpecPullsWithTarget(podSpec, source)
+	}
+
+	deployments := &appsv1.DeploymentList{}
+	err = r.List(ctx, deployments)
+	if err != nil {
+		return namespaces, err
+	}
+	for _, v := range deployments.Items {
+		if references(&v.Spec.Template.Spec) {
+			namespaces[v.Namespace] = true
+		}
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	err = r.List(ctx, statefulSets)
+	if err != nil {
+		return namespaces, err
+	}
+	for _, v := range statefulSets.Items {
+		if references(&v.Spec.Template.Spec) {
+			namespaces[v.Namespace] = true
+		}
+	}
+
+	daemonSets := &appsv1.DaemonSetList{}
+	err = r.List(ctx, daemonSets)
+	if err != nil {
+		return namespaces, err
+	}
+	for _, v := range daemonSets.Items {
+		if references(&v.Spec.Template.Spec) {
+			namespaces[v.Namespace] = true
+		}
+	}
+
+	pods := &corev1.PodList{}
+	err = r.List(ctx, pods)
+	if err != nil {
+		return namespaces, err
+	}
+	for _, v := range pods.Items {
+		if references(&v.Spec) {
+			namespaces[v.Namespace] = true
+		}
+	}
+
+	return namespaces, err
+}
+
+// FilterNamespacesByConsumers return the namespaces where the source has consumers
+func (r *ReplikaReconciler) FilterNamespacesByConsumers(ctx context.Context, namespaces []string, source *unstructured.Unstructured) (filtered []string, err error) {
+
+	var consuming map[string]bool
+	consuming, err = r.GetConsumingNamespaces(ctx, source)
+	if err != nil {
+		return filtered, err
+	}
+
+	for _, ns := range namespaces {
+		if consuming[ns] {
+			filtered = append(filtered, ns)
+		}
+	}
+	return filtered, err
+}
+
+// PruneUnconsumedTargets delete the targets created in namespaces that are not targets anymore,
+// as their last consumer disappeared. The namespaces rejected on this synchronization keep their targets,
+// as they were not written for other reasons than their consumers
+func (r *ReplikaReconciler) PruneUnconsumedTargets(ctx context.Context, replika *replikav1beta1.Replika, targets []unstructured.Unstructured) (err error) {
+
+	desired := map[string]bool{}
+	for i := range targets {
+		desired[targets[i].GetNamespace()] = true
+	}
+	for _, v := range replika.Status.RejectedNamespaces {
+		desired[v.Namespace] = true
+	}
+
+	var existing []unstructured.Unstructured
+	existing, _, err = r.ListTargets(ctx, replika)
+	if err != nil {
+		return err
+	}
+
+	for i := range existing {
+		if desired[existing[i].GetNamespace()] {
+			continue
+		}
+
+		uid := existing[i].GetUID()
+		err = client.IgnoreNotFound(r.Delete(ctx, &existing[i], client.Preconditions{UID: &uid}))
+		if err != nil {
+			return err
+		}
+		deleted := NewInventoryEntry(&existing[i])
+		RemoveInventoryEntries(replika, func(entry replikav1beta1.ReplikaInventoryEntry) bool {
+			return entry == deleted
+		})
+		LogInfof(ctx, unconsumedTargetDeleted, existing[i].GetNamespace())
+		r.RecordAuditLog(ctx, replika, &existing[i], auditlog.ActionDelete, auditReasonUnconsumed)
+	}
+
+	return err
+}
+
+// MapPodToReplikas return the on-demand Replikas whose source is referenced by the Pod,
+// so a new consumer gets its target without waiting for the next synchronization
+func (r *ReplikaReconciler) MapPodToReplikas(object client.Object) (requests []reconcile.Request) {
+
+	pod, ok := object.(*corev1.Pod)
+	if !ok {
+		return requests
+	}
+
+	replikaList := &replikav1beta1.ReplikaList{}
+	err := r.List(context.Background(), replikaList)
+	if err != nil {
+		return requests
+	}
+
+	for _, replika := range replikaList.Items {
+		if !replika.Spec.Target.OnDemand {
+			continue
+		}
+		replika.SetSourceDefaults()
+
+		source := &unstructured.Unstructured{}
+		source.SetGroupVersionKind(replika.Spec.Source.GetGroupVersionKind())
+		source.SetName(replika.Spec.Source.Name)
+		if PodSpecReferencesTarget(&pod.Spec, source) || PodSpecPullsWithTarget(&pod.Spec, source) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: replika.Namespace, Name: replika.Name},
+			})
+		}
+	}
+
+	return requests
+}
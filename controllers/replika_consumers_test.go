@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+func newConsumedTarget(group, kind, name string) *unstructured.Unstructured {
+	target := &unstructured.Unstructured{}
+	target.SetGroupVersionKind(schema.GroupVersionKind{Group: group, Version: "v1", Kind: kind})
+	target.SetName(name)
+	return target
+}
+
+func TestPodSpecConsumesTarget(t *testing.T) {
+	podSpec := &corev1.PodSpec{
+		ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+		Volumes: []corev1.Volume{
+			{Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"},
+			}}},
+			{Name: "secret", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "app-secret"}}},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		target     *unstructured.Unstructured
+		references bool
+		pulls      bool
+	}{
+		{name: "mounted ConfigMap", target: newConsumedTarget("", "ConfigMap", "app-config"), references: true},
+		{name: "mounted Secret", target: newConsumedTarget("", "Secret", "app-secret"), references: true},
+		{name: "image pull Secret", target: newConsumedTarget("", "Secret", "registry"), pulls: true},
+		{name: "ConfigMap not referenced", target: newConsumedTarget("", "ConfigMap", "app-secret")},
+		{name: "other kind named as a Secret", target: newConsumedTarget("", "ServiceAccount", "app-secret")},
+		{name: "other kind named as a pull Secret", target: newConsumedTarget("", "ServiceAccount", "registry")},
+		{name: "Secret of another group", target: newConsumedTarget("example.com", "Secret", "registry")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if references := PodSpecReferencesTarget(podSpec, test.target); references != test.references {
+				t.Errorf("expected referenced %t, got %t", test.references, references)
+			}
+			if pulls := PodSpecPullsWithTarget(podSpec, test.target); pulls != test.pulls {
+				t.Errorf("expected pulled %t, got %t", test.pulls, pulls)
+			}
+		})
+	}
+}
+
+func TestPruneUnconsumedTargets(t *testing.T) {
+	replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-config"}}
+	replika.Spec.Source = replikav1beta1.ReplikaSourceSpec{
+		Version:   "v1",
+		Kind:      "ConfigMap",
+		Name:      "app-config",
+		Namespace: "default",
+	}
+	replika.Spec.Target.OnDemand = true
+	replika.Status.RejectedNamespaces = []replikav1beta1.ReplikaNamespaceStatus{{Namespace: "rejected"}}
+
+	var objects []client.Object
+	for _, namespace := range []string{"consuming", "unconsumed", "rejected"} {
+		objects = append(objects, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      "app-config",
+			Labels: map[string]string{
+				resourceReplikaLabelCreatedKey:         resourceReplikaLabelCreatedValue,
+				resourceReplikaLabelPartOfKey:          replika.Name,
+				resourceReplikaLabelPartOfNamespaceKey: replika.Namespace,
+			},
+		}})
+	}
+	r := &ReplikaReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()}
+
+	desired := newConsumedTarget("", "ConfigMap", "app-config")
+	desired.SetNamespace("consuming")
+	err := r.PruneUnconsumedTargets(context.Background(), replika, []unstructured.Unstructured{*desired})
+	if err != nil {
+		t.Fatalf("unexpected error pruning the targets: %v", err)
+	}
+
+	list := &corev1.ConfigMapList{}
+	if err = r.List(context.Background(), list); err != nil {
+		t.Fatalf("unexpected error listing the targets: %v", err)
+	}
+	kept := map[string]bool{}
+	for i := range list.Items {
+		kept[list.Items[i].Namespace] = true
+	}
+	if len(kept) != 2 || !kept["consuming"] || !kept["rejected"] {
+		t.Errorf("expected the targets of the consuming and rejected namespaces kept, got %v", kept)
+	}
+}
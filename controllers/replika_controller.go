@@ -18,13 +18,25 @@ package controllers
 
 import (
 	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/auditlog"
+	"prosimcorp.com/replika/pkg/conditions"
 )
 
 const (
@@ -42,13 +54,92 @@ const (
 type ReplikaReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// Resync spreads the reconciliation of all the Replikas after acquiring the leadership. Optional
+	Resync *LeaderResync
+
+	// Config holds the operator settings reloaded at runtime. Defaults are used when not set
+	Config *OperatorConfig
+
+	// MaxConcurrentReconciles is the number of workers. The active ones are limited by the operator settings
+	MaxConcurrentReconciles int
+
+	// Failures counts the consecutive failures of each Replika. Optional
+	Failures *FailureTracker
+
+	// MaxTargets is the maximum number of namespaces any Replika can be replicated in. Zero means no limit
+	MaxTargets int
+
+	// AuditInterval is the time between two integrity audits of the targets of a Replika. Zero disables them
+	AuditInterval time.Duration
+
+	// Recorder emits the Events of the Replikas. Optional
+	Recorder record.EventRecorder
+
+	// Scheduler enqueues the periodical synchronizations. Each reconciliation requeues itself when not set
+	Scheduler *SyncScheduler
+
+	// ConfirmSecretsInAllNamespaces holds the Replikas replicating a Secret in all the namespaces until they are confirmed
+	ConfirmSecretsInAllNamespaces bool
+
+	// ConfirmationThreshold holds the deletion of the Replikas removing more targets until they are confirmed.
+	// Zero disables the confirmation
+	ConfirmationThreshold int
+
+	// AuditLog records every write performed on the targets. Optional
+	AuditLog auditlog.Sink
+
+	// SourceWatcher synchronizes the Replikas as soon as their sources change. Optional
+	SourceWatcher *SourceWatcher
+
+	// DebounceWindow is the minimum time between two synchronizations of a Replika, coalescing the bursts
+	// of changes into a single one. Zero disables it
+	DebounceWindow time.Duration
+
+	// Drainer lets the in-flight synchronizations finish on shutdown. Optional
+	Drainer *ShutdownDrainer
+
+	// SourceCache keeps the sources with their own synchronization time between their reads.
+	// They are read on every synchronization when not set
+	SourceCache *SourceCache
+
+	// RequestTimeout bounds each request reading a source or writing a target of the Replikas not setting their own.
+	// Zero means no timeout
+	RequestTimeout time.Duration
+
+	// Queue records when the Replikas are enqueued, measuring their wait for a free worker. Optional
+	Queue *QueueTracker
+
+	// APIReader reads the existing targets, the anchors and the lookup ConfigMaps without informers, so the Secrets
+	// and ConfigMaps of the whole cluster are never cached. The cached client is used when not set
+	APIReader client.Reader
+
+	// HTTPHookAllowedHosts are the hosts the HTTP hooks can call, as 'host' or '*.domain'.
+	// The HTTP hooks are refused when empty
+	HTTPHookAllowedHosts []string
+
+	// HookJobs lets the Replikas run their pre-sync and post-sync hook Jobs. Those run any Pod as a ServiceAccount
+	// of the namespace of the Replika, so the hooks fail when it is not set
+	HookJobs bool
+
+	// WatchConsumerPods synchronizes the on-demand Replikas as soon as a Pod consuming their source is created.
+	// Otherwise the new consumers get their targets on the next synchronization
+	WatchConsumerPods bool
 }
 
 //+kubebuilder:rbac:groups=replika.prosimcorp.com,resources=replikas,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=replika.prosimcorp.com,resources=replikas/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=replika.prosimcorp.com,resources=replikas/finalizers,verbs=update
+//+kubebuilder:rbac:groups=replika.prosimcorp.com,resources=replikareports,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=replika.prosimcorp.com,resources=replikareports/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=secrets;configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch;patch
+//+kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -56,6 +147,34 @@ type ReplikaReconciler struct {
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.11.0/pkg/reconcile
 func (r *ReplikaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 
+	// 0. Measure the wait for a free worker, and mark the requeue of this reconciliation as the next enqueue.
+	// The retries after a failure are delayed by the backoff on purpose, so they are not measured
+	startTime := time.Now()
+	queueWait, queueWaitFound := r.Queue.Dequeued(req.NamespacedName, startTime)
+	defer func() {
+		if err == nil && result.RequeueAfter > 0 {
+			r.Queue.Enqueued(req.NamespacedName, time.Now().Add(result.RequeueAfter))
+		}
+	}()
+
+	// 0.1 Finish the synchronization and the update of the status even when the manager is stopping.
+	// The requests arriving while draining are left to the next leader
+	if r.Drainer != nil {
+		if !r.Drainer.Begin() {
+			return result, err
+		}
+		defer r.Drainer.Done()
+
+		var cancel context.CancelFunc
+		ctx, cancel = r.Drainer.Context(ctx)
+		defer cancel()
+	}
+
+	// 0.2 Account the request for the full resync done after acquiring the leadership
+	if r.Resync != nil {
+		defer r.Resync.MarkSynced(ctx, req.NamespacedName)
+	}
+
 	//1. Get the content of the Replika
 	replikaManifest := &replikav1beta1.Replika{}
 	err = r.Get(ctx, req.NamespacedName, replikaManifest)
@@ -66,6 +185,20 @@ func (r *ReplikaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (re
 		// 2.1 It does NOT exist: manage removal
 		if err = client.IgnoreNotFound(err); err == nil {
 			LogInfof(ctx, replikaNotFoundError)
+			DeleteReplikaMetrics(req.Namespace, req.Name)
+			if r.Failures != nil {
+				r.Failures.Forget(req.NamespacedName)
+			}
+			if r.Scheduler != nil {
+				r.Scheduler.Unschedule(req.NamespacedName)
+			}
+			if r.SourceWatcher != nil {
+				r.SourceWatcher.Unwatch(req.NamespacedName)
+			}
+			if r.SourceCache != nil {
+				r.SourceCache.Forget(req.NamespacedName)
+			}
+			r.Queue.Forget(req.NamespacedName)
 			return result, err
 		}
 
@@ -74,16 +207,80 @@ func (r *ReplikaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (re
 		return result, err
 	}
 
+	// 2.3 Fill the defaults of the source, as done by the defaulting webhook when it is not deployed
+	replikaManifest.SetSourceDefaults()
+
+	// 2.4 Account the result of the synchronization. Replikas failing several times in a row are degraded
+	priority := replikaManifest.Spec.Priority
+	if r.Failures != nil {
+		priority = r.Failures.GetPriority(req.NamespacedName, priority)
+		defer func() {
+			r.Failures.Record(req.NamespacedName, err)
+		}()
+	}
+
+	// 2.5 Wait for a free slot according to the concurrency of the operator settings and the priority.
+	// Degraded Replikas never take a slot while healthy ones are waiting
+	if r.Config != nil {
+		err = r.Config.Acquire(ctx, priority)
+		if err != nil {
+			return result, err
+		}
+		defer r.Config.Release()
+	}
+
 	// 3. Check if the Replika instance is marked to be deleted: indicated by the deletion timestamp being set
 	if !replikaManifest.DeletionTimestamp.IsZero() {
+		if r.Scheduler != nil {
+			r.Scheduler.Unschedule(req.NamespacedName)
+		}
+		if r.SourceWatcher != nil {
+			r.SourceWatcher.Unwatch(req.NamespacedName)
+		}
+		if r.SourceCache != nil {
+			r.SourceCache.Forget(req.NamespacedName)
+		}
 		if controllerutil.ContainsFinalizer(replikaManifest, replikaFinalizer) {
-			// Delete all created targets
-			err = r.DeleteTargets(ctx, replikaManifest)
+			// Wait for the confirmation when too many targets would be removed, or for the operator to be resumed
+			err = r.CheckDeletionConfirmation(ctx, replikaManifest)
+			if err == nil && r.settings().Paused {
+				r.SetReplikaCondition(replikaManifest, conditions.New(ConditionTypeSourceSynced,
+					metav1.ConditionFalse,
+					ConditionReasonPaused,
+					ConditionReasonPausedMessage,
+				))
+				err = errPaused
+			}
+			if IsPendingError(err) {
+				r.UpdateReadyCondition(replikaManifest, nil)
+				err = r.Status().Update(ctx, replikaManifest)
+				result = ctrl.Result{RequeueAfter: pendingPollInterval}
+				return result, err
+			}
 			if err != nil {
 				LogInfof(ctx, targetsDeletionError)
 				return result, err
 			}
 
+			// Delete the created targets by batches, reporting the progress until none remains
+			var remaining int
+			remaining, err = r.DeleteTargets(ctx, replikaManifest)
+			if err != nil {
+				LogInfof(ctx, targetsDeletionError)
+				return result, err
+			}
+			if remaining > 0 {
+				r.SetReplikaCondition(replikaManifest, conditions.New(ConditionTypeSourceSynced,
+					metav1.ConditionFalse,
+					ConditionReasonTargetsDeleting,
+					ConditionReasonTargetsDeletingMessage, remaining,
+				))
+				r.UpdateReadyCondition(replikaManifest, nil)
+				err = r.Status().Update(ctx, replikaManifest)
+				result = ctrl.Result{Requeue: true}
+				return result, err
+			}
+
 			// Remove the finalizers on Replika CR
 			controllerutil.RemoveFinalizer(replikaManifest, replikaFinalizer)
 			err = r.Update(ctx, replikaManifest)
@@ -96,55 +293,249 @@ func (r *ReplikaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (re
 		return result, err
 	}
 
-	// 4. Add finalizer to the Replika CR
+	// 4. Add finalizer to the Replika CR. It is patched, so the defaults filled in memory are not written,
+	// and the synchronization starts over from the patched object, as the status update at the end
+	// would conflict with the resourceVersion read before the patch
 	if !controllerutil.ContainsFinalizer(replikaManifest, replikaFinalizer) {
+		patch := client.MergeFrom(replikaManifest.DeepCopy())
 		controllerutil.AddFinalizer(replikaManifest, replikaFinalizer)
-		err = r.Update(ctx, replikaManifest)
+		err = r.Patch(ctx, replikaManifest, patch)
 		if err != nil {
 			return result, err
 		}
+		result = ctrl.Result{Requeue: true}
+		return result, err
 	}
 
-	// 5. Update the status before the requeue
+	// 4.1 Watch the sources, so their changes are synchronized without waiting for the schedule
+	if r.SourceWatcher != nil {
+		r.SourceWatcher.Watch(replikaManifest)
+	}
+
+	// 4.2 Coalesce the bursts of changes, synchronizing at most once per debounce window
+	if delay := r.GetDebounceDelay(replikaManifest); delay > 0 {
+		result = ctrl.Result{RequeueAfter: delay}
+		return result, err
+	}
+
+	// 5. Update the status before the requeue, keeping the error of the synchronization
+	syncTime := metav1.Now()
+	replikaManifest.Status.SourceRef = GetSourceRef(replikaManifest)
+	replikaManifest.Status.LastSyncTime = &syncTime
+	replikaManifest.Status.LastError = ""
+	replikaManifest.Status.LastErrorTime = nil
 	defer func() {
-		err = r.Status().Update(ctx, replikaManifest)
 		if err != nil {
-			LogInfof(ctx, replikaConditionUpdateError, req.Name)
+			SetLastError(replikaManifest, err)
+		}
+		r.UpdateReadyCondition(replikaManifest, err)
+		SetReconcileStats(replikaManifest, queueWait, queueWaitFound, time.Since(startTime))
+
+		statusErr := r.Status().Update(ctx, replikaManifest)
+		if statusErr != nil {
+			LogErrorDedupf(ctx, replikaConditionUpdateError, req.Name)
+			if err == nil {
+				err = statusErr
+			}
 		}
 	}()
 
-	// 6. Schedule periodical request
-	RequeueTime, err := r.GetSynchronizationTime(replikaManifest)
+	// 5.1 Forget the results of the target namespaces deleted since the last synchronization
+	err = r.PruneDeletedNamespaces(ctx, replikaManifest)
 	if err != nil {
-		LogInfof(ctx, replikaSyncTimeRetrievalError, replikaManifest.Name)
-		return result, err
+		LogErrorDedupf(ctx, deletedNamespacesPruneError, replikaManifest.Name, err.Error())
+		err = nil
 	}
+
+	// 5.2 Preview the objects deleted along with the Replika when requested
+	err = r.PreviewDeletion(ctx, replikaManifest)
+	if err != nil {
+		LogErrorDedupf(ctx, deletionPreviewError, replikaManifest.Name, err.Error())
+		err = nil
+	}
+
+	// 6. Schedule periodical request, on the shared scheduler when available
+	RequeueTime, err := r.GetSynchronizationTime(replikaManifest)
 	result = ctrl.Result{
 		RequeueAfter: RequeueTime,
 	}
+	if r.Scheduler != nil {
+		r.Scheduler.Schedule(req.NamespacedName, RequeueTime)
+		result = ctrl.Result{}
+	}
+	if err != nil {
+		LogErrorDedupf(ctx, replikaSyncTimeRetrievalError, replikaManifest.Name)
+		err = r.HandlePermanentError(replikaManifest, err)
+		return result, err
+	}
+
+	// 6.1 Defer the synchronization while the window of the Replika is open
+	var windowEnd time.Time
+	windowEnd, err = r.GetSynchronizationWindowEnd(replikaManifest)
+	if err != nil {
+		LogErrorDedupf(ctx, synchronizationWindowError, replikaManifest.Name, err.Error())
+		err = r.HandlePermanentError(replikaManifest, err)
+		return result, err
+	}
+	if !windowEnd.IsZero() {
+		r.SetReplikaCondition(replikaManifest, conditions.New(ConditionTypeSourceSynced,
+			metav1.ConditionFalse,
+			ConditionReasonSyncDeferred,
+			ConditionReasonSyncDeferredMessage, windowEnd.Format(time.RFC3339),
+		))
+		LogInfof(ctx, syncDeferred, windowEnd.Format(time.RFC3339))
+		result.RequeueAfter = time.Until(windowEnd)
+		return result, err
+	}
 
 	// 7. The Replika CR already exist: manage the update
 	err = r.UpdateTargets(ctx, replikaManifest)
+	if IsPendingError(err) {
+		result.RequeueAfter = pendingPollInterval
+		err = nil
+		return result, err
+	}
 	if err != nil {
-		LogInfof(ctx, updateTargetsError, replikaManifest.Name)
+		LogErrorDedupf(ctx, updateTargetsError, replikaManifest.Name)
+		err = r.HandlePermanentError(replikaManifest, err)
+		return result, err
+	}
 
+	// 8. Success, update the status. Audit-only Replikas already reported the result of the audit
+	replikaManifest.Status.LastSuccessfulSyncTime = &syncTime
+	if replikaManifest.Spec.Synchronization.AuditOnly {
 		return result, err
 	}
+	r.SetSyncedCondition(replikaManifest)
 
-	// 8. Success, update the status
-	r.UpdateReplikaCondition(replikaManifest, r.NewReplikaCondition(ConditionTypeSourceSynced,
-		metav1.ConditionTrue,
-		ConditionReasonSourceSynced,
-		ConditionReasonSourceSyncedMessage,
+	LogInfof(ctx, scheduleSynchronization, RequeueTime.String())
+	return result, err
+}
+
+// HandlePermanentError return nil for the errors caused by the spec of the Replika, reflecting them in its status.
+// This way they are retried on the next synchronization instead of being requeued with backoff in a hot loop.
+// Transient errors are returned as they are
+func (r *ReplikaReconciler) HandlePermanentError(replika *replikav1beta1.Replika, err error) error {
+	if !IsPermanentError(err) {
+		return err
+	}
+
+	SetLastError(replika, err)
+	r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+		metav1.ConditionFalse,
+		ConditionReasonInvalidSpec,
+		ConditionReasonInvalidSpecMessage, err.Error(),
 	))
+	return nil
+}
 
-	LogInfof(ctx, scheduleSynchronization, result.RequeueAfter.String())
-	return result, err
+// GetDebounceDelay return the time left until the Replika can be synchronized again, zero when it can be right now
+func (r *ReplikaReconciler) GetDebounceDelay(replika *replikav1beta1.Replika) time.Duration {
+	if r.DebounceWindow <= 0 || replika.Status.LastSyncTime == nil {
+		return 0
+	}
+
+	elapsed := time.Since(replika.Status.LastSyncTime.Time)
+	if elapsed < 0 || elapsed >= r.DebounceWindow {
+		return 0
+	}
+	return r.DebounceWindow - elapsed
+}
+
+// GetSynchronizationWindowEnd return the end of the window of the Replika when it is open, zero otherwise
+func (r *ReplikaReconciler) GetSynchronizationWindowEnd(replika *replikav1beta1.Replika) (end time.Time, err error) {
+	if replika.Spec.Synchronization.Window == nil {
+		return end, err
+	}
+
+	var inside bool
+	end, inside, err = replika.Spec.Synchronization.Window.GetEnd(time.Now())
+	if err != nil {
+		err = NewPermanentErrorf(synchronizationWindowError, replika.Name, err.Error())
+		return time.Time{}, err
+	}
+	if !inside {
+		end = time.Time{}
+	}
+	return end, err
+}
+
+// SetLastError record the error of the synchronization in the status of the Replika
+func SetLastError(replika *replikav1beta1.Replika, err error) {
+	now := metav1.Now()
+	replika.Status.LastError = err.Error()
+	replika.Status.LastErrorTime = &now
 }
 
 // SetupWithManager sets up the controller with the Manager.
-func (r *ReplikaReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&replikav1beta1.Replika{}).
-		Complete(r)
+func (r *ReplikaReconciler) SetupWithManager(mgr ctrl.Manager) (err error) {
+
+	options := controller.Options{
+		MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+		RateLimiter:             NewReplikaRateLimiter(),
+	}
+
+	controllerBuilder := ctrl.NewControllerManagedBy(mgr).
+		WithOptions(options)
+
+	// The status written on each synchronization never triggers another one, only the changes of the spec
+	// and the annotations do
+	specChanged := predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{})
+
+	if r.Resync == nil {
+		controllerBuilder = controllerBuilder.For(&replikav1beta1.Replika{}, builder.WithPredicates(specChanged, r.Queue.Predicate()))
+	} else {
+		err = mgr.Add(r.Resync)
+		if err != nil {
+			return err
+		}
+		controllerBuilder = controllerBuilder.
+			For(&replikav1beta1.Replika{}, builder.WithPredicates(specChanged, r.Resync.Predicate(), r.Queue.Predicate())).
+			Watches(&source.Channel{Source: r.Resync.Events()}, &handler.EnqueueRequestForObject{},
+				builder.WithPredicates(r.Queue.Predicate()))
+	}
+
+	if r.Scheduler != nil {
+		err = mgr.Add(r.Scheduler)
+		if err != nil {
+			return err
+		}
+		controllerBuilder = controllerBuilder.
+			Watches(&source.Channel{Source: r.Scheduler.Events()}, &handler.EnqueueRequestForObject{},
+				builder.WithPredicates(r.Queue.Predicate()))
+	}
+
+	if r.SourceWatcher != nil {
+		err = mgr.Add(r.SourceWatcher)
+		if err != nil {
+			return err
+		}
+		controllerBuilder = controllerBuilder.
+			Watches(&source.Channel{Source: r.SourceWatcher.Events()}, &handler.EnqueueRequestForObject{},
+				builder.WithPredicates(r.Queue.Predicate()))
+	}
+
+	// Pods starting to consume the source of an on-demand Replika get its target right away.
+	// Watching them caches every Pod of the cluster, so it is only done when requested
+	if r.WatchConsumerPods {
+		controllerBuilder = controllerBuilder.
+			Watches(&source.Kind{Type: &corev1.Pod{}}, handler.EnqueueRequestsFromMapFunc(r.Queue.MapFunc(r.MapPodToReplikas)),
+				builder.WithPredicates(predicate.Funcs{
+					UpdateFunc: func(event.UpdateEvent) bool { return false },
+				}))
+	}
+
+	controllerBuilder = controllerBuilder.
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, r.NamespaceEventHandler(),
+			builder.WithPredicates(predicate.Or(namespaceDeletionPredicate, namespaceRefreshPredicate)))
+
+	return controllerBuilder.Complete(r)
+}
+
+// settings return the current operator settings, or the defaults when no configuration is set
+func (r *ReplikaReconciler) settings() OperatorSettings {
+	if r.Config == nil {
+		return DefaultOperatorSettings()
+	}
+	return r.Config.Settings()
 }
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+const (
+	// Time between two checks of a Replika waiting for an external verification
+	pendingPollInterval = 15 * time.Second
+)
+
+// PendingError defines a synchronization waiting for an external verification, like a Job.
+// It is not a failure, so the Replika is checked again without backoff
+type PendingError struct {
+	reason string
+}
+
+// Error implements error
+func (e *PendingError) Error() string {
+	return e.reason
+}
+
+// IsPendingError return true when the synchronization is waiting for an external verification
+func IsPendingError(err error) bool {
+	var pendingError *PendingError
+	return errors.As(err, &pendingError)
+}
+
+// PermanentError defines an error caused by the spec of a Replika.
+// Retrying the synchronization does not fix it, so it is not requeued with backoff
+type PermanentError struct {
+	err error
+}
+
+// Error implements error
+func (e *PermanentError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap return the wrapped error
+func (e *PermanentError) Unwrap() error {
+	return e.err
+}
+
+// NewPermanentErrorf return a PermanentError with the message already formatted from parameters
+func NewPermanentErrorf(msg string, params ...interface{}) error {
+	return &PermanentError{err: fmt.Errorf(msg, params...)}
+}
+
+// NewPermanentError return the error as a PermanentError
+func NewPermanentError(err error) error {
+	return &PermanentError{err: err}
+}
+
+// IsPermanentError return true when the error is caused by the spec of a Replika.
+// The rest of them (conflicts, timeouts, unavailable API server...) are considered transient
+func IsPermanentError(err error) bool {
+	var permanentError *PermanentError
+	if errors.As(err, &permanentError) {
+		return true
+	}
+
+	// The group, version or kind of the source is not served by the cluster
+	return meta.IsNoMatchError(err)
+}
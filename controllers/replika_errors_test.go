@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/conditions"
+)
+
+func TestIsPermanentError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "spec error", err: NewPermanentErrorf(namespaceFormatError, "Team_A"), expected: true},
+		{name: "wrapped spec error", err: fmt.Errorf("syncing: %w", NewPermanentError(errors.New("invalid"))), expected: true},
+		{name: "kind not served", err: &meta.NoKindMatchError{GroupKind: schema.GroupKind{Group: "example.com", Kind: "Widget"}}, expected: true},
+		{name: "transient error", err: errors.New("connection refused")},
+		{name: "pending", err: errConfirmationPending},
+		{name: "no error"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if permanent := IsPermanentError(test.err); permanent != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, permanent)
+			}
+		})
+	}
+}
+
+func TestHandlePermanentError(t *testing.T) {
+	r := &ReplikaReconciler{}
+
+	// The transient errors are returned to be retried with backoff
+	transient := errors.New("connection refused")
+	replika := &replikav1beta1.Replika{}
+	if err := r.HandlePermanentError(replika, transient); err != transient {
+		t.Errorf("expected the transient error returned, got %v", err)
+	}
+
+	// The spec errors are only reflected in the status
+	replika = &replikav1beta1.Replika{}
+	if err := r.HandlePermanentError(replika, NewPermanentErrorf(namespaceFormatError, "Team_A")); err != nil {
+		t.Errorf("expected the permanent error handled, got %v", err)
+	}
+	condition := conditions.Get(replika.Status.Conditions, ConditionTypeSourceSynced)
+	if condition == nil || condition.Reason != ConditionReasonInvalidSpec {
+		t.Errorf("expected the reason %s, got %v", ConditionReasonInvalidSpec, condition)
+	}
+	if replika.Status.LastError == "" {
+		t.Errorf("expected the last error recorded")
+	}
+}
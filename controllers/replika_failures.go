@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// Backoff applied to each Replika independently when its synchronization fails
+	failureBaseDelay = 1 * time.Second
+	failureMaxDelay  = 5 * time.Minute
+
+	// Consecutive failures needed to consider a Replika as degraded
+	degradedFailuresThreshold = 3
+
+	// Priority used by degraded Replikas, so they never take a slot while healthy ones are waiting
+	degradedPriority = math.MinInt32
+
+	// Backoff applied to each target namespace independently when its resource quota is exceeded
+	quotaBaseDelay = 30 * time.Second
+	quotaMaxDelay  = 30 * time.Minute
+
+	// Backoff applied to each target namespace independently when an admission webhook denies or fails its target
+	admissionBaseDelay = 10 * time.Second
+	admissionMaxDelay  = 10 * time.Minute
+)
+
+// namespaceBackoff defines the exponential backoff of a target namespace
+type namespaceBackoff struct {
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+// namespaceBackoffs maps the reasons of the failures of a target namespace to its backoff.
+// The rest of the failures fail the whole synchronization, so they are backed off along with the Replika
+var namespaceBackoffs = map[string]namespaceBackoff{
+	ConditionReasonQuotaExceeded:   {baseDelay: quotaBaseDelay, maxDelay: quotaMaxDelay},
+	ConditionReasonAdmissionDenied: {baseDelay: admissionBaseDelay, maxDelay: admissionMaxDelay},
+	ConditionReasonAdmissionFailed: {baseDelay: admissionBaseDelay, maxDelay: admissionMaxDelay},
+}
+
+// NewReplikaRateLimiter return a rate limiter where each Replika has its own exponential backoff.
+// There is no shared bucket, so a Replika failing on each loop can not delay the rest of them
+func NewReplikaRateLimiter() workqueue.RateLimiter {
+	return workqueue.NewItemExponentialFailureRateLimiter(failureBaseDelay, failureMaxDelay)
+}
+
+// FailureTracker counts the consecutive failures of each Replika, and backs off the target namespaces
+// failing on their own, like the exceeded quotas or the denials of a broken webhook, so they are not
+// written on every synchronization while the rest of them are synchronized at the normal rate
+type FailureTracker struct {
+	mutex    sync.Mutex
+	failures map[types.NamespacedName]int
+
+	namespaceRetries map[namespaceKey]NamespaceRetry
+}
+
+// namespaceKey identifies a target namespace of a Replika
+type namespaceKey struct {
+	replika   types.NamespacedName
+	namespace string
+}
+
+// NamespaceRetry defines the backoff state of a target namespace of a Replika
+type NamespaceRetry struct {
+	Reason    string
+	Message   string
+	Failures  int
+	RetryTime time.Time
+}
+
+// NewFailureTracker return an empty FailureTracker
+func NewFailureTracker() *FailureTracker {
+	return &FailureTracker{
+		failures:         map[types.NamespacedName]int{},
+		namespaceRetries: map[namespaceKey]NamespaceRetry{},
+	}
+}
+
+// Record account the result of a synchronization, resetting the counter on success
+func (f *FailureTracker) Record(key types.NamespacedName, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err == nil {
+		delete(f.failures, key)
+		return
+	}
+	f.failures[key]++
+}
+
+// Forget remove a Replika from the tracker
+func (f *FailureTracker) Forget(key types.NamespacedName) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.failures, key)
+
+	for k := range f.namespaceRetries {
+		if k.replika == key {
+			delete(f.namespaceRetries, k)
+		}
+	}
+}
+
+// RecordNamespaceFailure back off the target namespace of a Replika, exponentially while it keeps failing
+// for the reason. The delay is capped by the backoff of the reason. It return the new backoff state
+func (f *FailureTracker) RecordNamespaceFailure(key types.NamespacedName, namespace, reason, message string) (retry NamespaceRetry) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	backoff, found := namespaceBackoffs[reason]
+	if !found {
+		backoff = namespaceBackoff{baseDelay: failureBaseDelay, maxDelay: failureMaxDelay}
+	}
+
+	k := namespaceKey{replika: key, namespace: namespace}
+	retry = f.namespaceRetries[k]
+	retry.Reason = reason
+	retry.Message = message
+	retry.Failures++
+
+	delay := backoff.maxDelay
+	if exponent := retry.Failures - 1; exponent < 32 {
+		delay = time.Duration(math.Min(float64(backoff.baseDelay)*math.Pow(2, float64(exponent)), float64(backoff.maxDelay)))
+	}
+	retry.RetryTime = time.Now().Add(delay)
+
+	f.namespaceRetries[k] = retry
+	return retry
+}
+
+// ForgetNamespaceFailures reset the backoff of the target namespace of a Replika once it is written
+func (f *FailureTracker) ForgetNamespaceFailures(key types.NamespacedName, namespace string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.namespaceRetries, namespaceKey{replika: key, namespace: namespace})
+}
+
+// GetNamespaceRetry return the backoff state of the target namespace of a Replika,
+// and whether it is still backing off
+func (f *FailureTracker) GetNamespaceRetry(key types.NamespacedName, namespace string) (retry NamespaceRetry, backingOff bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	retry, found := f.namespaceRetries[namespaceKey{replika: key, namespace: namespace}]
+	return retry, found && time.Now().Before(retry.RetryTime)
+}
+
+// IsDegraded return true when the Replika failed several times in a row
+func (f *FailureTracker) IsDegraded(key types.NamespacedName) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.failures[key] >= degradedFailuresThreshold
+}
+
+// GetPriority return the priority used by the Replika to take a synchronization slot
+func (f *FailureTracker) GetPriority(key types.NamespacedName, priority int32) int32 {
+	if f.IsDegraded(key) {
+		return degradedPriority
+	}
+	return priority
+}
@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+func TestFailureTracker(t *testing.T) {
+	failing := types.NamespacedName{Namespace: "default", Name: "failing"}
+	healthy := types.NamespacedName{Namespace: "default", Name: "healthy"}
+	tracker := NewFailureTracker()
+
+	for i := 0; i < degradedFailuresThreshold; i++ {
+		if tracker.IsDegraded(failing) {
+			t.Fatalf("expected not degraded after %d failures", i)
+		}
+		tracker.Record(failing, errors.New("failed"))
+		tracker.Record(healthy, nil)
+	}
+
+	if !tracker.IsDegraded(failing) {
+		t.Errorf("expected degraded after %d failures", degradedFailuresThreshold)
+	}
+	if priority := tracker.GetPriority(failing, 10); priority != degradedPriority {
+		t.Errorf("expected the degraded priority, got %d", priority)
+	}
+	if priority := tracker.GetPriority(healthy, 10); priority != 10 {
+		t.Errorf("expected the priority of the healthy Replika kept, got %d", priority)
+	}
+
+	// A success resets the counter
+	tracker.Record(failing, nil)
+	if tracker.IsDegraded(failing) {
+		t.Errorf("expected not degraded after a success")
+	}
+
+	tracker.Record(failing, errors.New("failed"))
+	tracker.Forget(failing)
+	if tracker.failures[failing] != 0 {
+		t.Errorf("expected the failures forgotten, got %d", tracker.failures[failing])
+	}
+}
+
+func TestReplikaRateLimiter(t *testing.T) {
+	limiter := NewReplikaRateLimiter()
+	failing := types.NamespacedName{Namespace: "default", Name: "failing"}
+	healthy := types.NamespacedName{Namespace: "default", Name: "healthy"}
+
+	var delay time.Duration
+	for i := 0; i < 5; i++ {
+		delay = limiter.When(failing)
+	}
+	if delay <= failureBaseDelay {
+		t.Errorf("expected the failing Replika backed off, got %v", delay)
+	}
+
+	// The backoff of a failing Replika never delays the rest of them
+	if delay = limiter.When(healthy); delay != failureBaseDelay {
+		t.Errorf("expected the base delay for the healthy Replika, got %v", delay)
+	}
+
+	limiter.Forget(failing)
+	if delay = limiter.When(failing); delay != failureBaseDelay {
+		t.Errorf("expected the base delay once forgotten, got %v", delay)
+	}
+}
+
+func TestIsQuotaExceeded(t *testing.T) {
+	resource := schema.GroupResource{Resource: "configmaps"}
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "exceeded quota", err: apierrors.NewForbidden(resource, "app-config", errors.New("exceeded quota: objects, requested: configmaps=1")), expected: true},
+		{name: "forbidden by RBAC", err: apierrors.NewForbidden(resource, "app-config", errors.New("cannot create resource"))},
+		{name: "other error", err: errors.New("exceeded quota")},
+		{name: "no error"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if exceeded := IsQuotaExceeded(test.err); exceeded != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, exceeded)
+			}
+		})
+	}
+}
+
+func TestRecordNamespaceFailureQuota(t *testing.T) {
+	key := types.NamespacedName{Namespace: "default", Name: "app-config"}
+	tracker := NewFailureTracker()
+
+	// The backoff doubles while the quota keeps being exceeded
+	first := tracker.RecordNamespaceFailure(key, "team-a", ConditionReasonQuotaExceeded, "exceeded quota")
+	second := tracker.RecordNamespaceFailure(key, "team-a", ConditionReasonQuotaExceeded, "exceeded quota")
+	if first.Failures != 1 || second.Failures != 2 {
+		t.Errorf("expected the failures counted, got %d and %d", first.Failures, second.Failures)
+	}
+	if delay := time.Until(first.RetryTime); delay <= 0 || delay > quotaBaseDelay {
+		t.Errorf("expected the first retry within %v, got %v", quotaBaseDelay, delay)
+	}
+	if delay := time.Until(second.RetryTime); delay <= quotaBaseDelay || delay > 2*quotaBaseDelay {
+		t.Errorf("expected the second retry within %v, got %v", 2*quotaBaseDelay, delay)
+	}
+
+	// Only the namespace exceeding its quota is backed off
+	if _, backingOff := tracker.GetNamespaceRetry(key, "team-a"); !backingOff {
+		t.Errorf("expected team-a backing off")
+	}
+	if _, backingOff := tracker.GetNamespaceRetry(key, "team-b"); backingOff {
+		t.Errorf("expected team-b not backing off")
+	}
+
+	// Writing the namespace resets its backoff
+	tracker.ForgetNamespaceFailures(key, "team-a")
+	if _, backingOff := tracker.GetNamespaceRetry(key, "team-a"); backingOff {
+		t.Errorf("expected team-a not backing off once written")
+	}
+}
+
+func TestIsAdmissionFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "unreachable webhook", err: errors.New(`Internal error occurred: failed calling webhook "policy.example.com": connection refused`), expected: true},
+		{name: "denied by a webhook", err: errors.New(`admission webhook "policy.example.com" denied the request`)},
+		{name: "no error"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if failure := IsAdmissionFailure(test.err); failure != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, failure)
+			}
+		})
+	}
+}
+
+func TestRecordNamespaceFailureAdmission(t *testing.T) {
+	key := types.NamespacedName{Namespace: "default", Name: "app-config"}
+	tracker := NewFailureTracker()
+
+	// The backoff of a broken webhook is capped
+	var retry NamespaceRetry
+	for i := 0; i < 40; i++ {
+		retry = tracker.RecordNamespaceFailure(key, "team-a", ConditionReasonAdmissionFailed, "failed calling webhook")
+	}
+	if retry.Failures != 40 || retry.Reason != ConditionReasonAdmissionFailed {
+		t.Errorf("unexpected retry state %+v", retry)
+	}
+	if delay := time.Until(retry.RetryTime); delay <= admissionMaxDelay-time.Minute || delay > admissionMaxDelay {
+		t.Errorf("expected the retry capped at %v, got %v", admissionMaxDelay, delay)
+	}
+
+	// Forgetting the Replika resets the backoff of its namespaces
+	tracker.Forget(key)
+	if _, backingOff := tracker.GetNamespaceRetry(key, "team-a"); backingOff {
+		t.Errorf("expected the namespace not backing off once the Replika is forgotten")
+	}
+}
+
+func TestSkipBackoffTargets(t *testing.T) {
+	replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-config"}}
+	r := &ReplikaReconciler{Failures: NewFailureTracker()}
+	r.RejectNamespace(replika, "team-a", ConditionReasonAdmissionDenied, "denied the request")
+
+	var targets []unstructured.Unstructured
+	for _, namespace := range []string{"team-a", "team-b"} {
+		target := unstructured.Unstructured{}
+		target.SetNamespace(namespace)
+		targets = append(targets, target)
+	}
+
+	// The namespace backing off is held and reported with its retry state
+	replika.Status.RejectedNamespaces = nil
+	pending := r.SkipBackoffTargets(context.Background(), replika, targets)
+	if len(pending) != 1 || pending[0].GetNamespace() != "team-b" {
+		t.Fatalf("expected only team-b written, got %v", pending)
+	}
+	if len(replika.Status.RejectedNamespaces) != 1 {
+		t.Fatalf("expected team-a rejected, got %v", replika.Status.RejectedNamespaces)
+	}
+	status := replika.Status.RejectedNamespaces[0]
+	if status.Namespace != "team-a" || status.Reason != ConditionReasonAdmissionDenied || status.Failures != 1 || status.RetryTime == nil {
+		t.Errorf("unexpected status of the namespace %+v", status)
+	}
+}
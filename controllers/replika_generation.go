@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/auditlog"
+)
+
+const (
+	// Annotations recording the Replika that wrote the target and the generation of its spec
+	targetReplikaUIDAnnotation        = "replika.prosimcorp.com/replika-uid"
+	targetReplikaGenerationAnnotation = "replika.prosimcorp.com/replika-generation"
+)
+
+// StampTargets annotate the targets with the UID and the generation of the Replika writing them
+func StampTargets(replika *replikav1beta1.Replika, targets []unstructured.Unstructured) {
+	for i := range targets {
+		annotations := targets[i].GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[targetReplikaUIDAnnotation] = string(replika.UID)
+		annotations[targetReplikaGenerationAnnotation] = strconv.FormatInt(replika.Generation, 10)
+		targets[i].SetAnnotations(annotations)
+	}
+}
+
+// IsStaleTarget return true when the target was written by an older generation of the Replika, or by a previous
+// Replika with the same namespace and name. The targets without the annotations, like the adopted ones, and the
+// ones part of another Replika are never stale
+func IsStaleTarget(replika *replikav1beta1.Replika, target *unstructured.Unstructured) bool {
+	if !IsPartOfReplika(replika, target) {
+		return false
+	}
+
+	annotations := target.GetAnnotations()
+	uid, found := annotations[targetReplikaUIDAnnotation]
+	if !found {
+		return false
+	}
+	return uid != string(replika.UID) || annotations[targetReplikaGenerationAnnotation] != strconv.FormatInt(replika.Generation, 10)
+}
+
+// PruneStaleTargets delete the targets not rewritten by the current generation of the Replika once all of them
+// were synchronized, even when their names did not change. The namespaces rejected on this synchronization keep
+// their targets, as they were not written
+func (r *ReplikaReconciler) PruneStaleTargets(ctx context.Context, replika *replikav1beta1.Replika) (err error) {
+
+	rejected := map[string]bool{}
+	for _, v := range replika.Status.RejectedNamespaces {
+		rejected[v.Namespace] = true
+	}
+
+	var existing []unstructured.Unstructured
+	existing, _, err = r.ListTargets(ctx, replika)
+	if err != nil {
+		return err
+	}
+
+	for i := range existing {
+		if rejected[existing[i].GetNamespace()] || !IsStaleTarget(replika, &existing[i]) {
+			continue
+		}
+
+		// Keep the previous versions of the hashed targets while they are referenced
+		var referenced bool
+		referenced, err = r.IsReferencedVersion(ctx, replika, &existing[i])
+		if err != nil {
+			return err
+		}
+		if referenced {
+			continue
+		}
+
+		uid := existing[i].GetUID()
+		err = client.IgnoreNotFound(r.Delete(ctx, &existing[i], client.Preconditions{UID: &uid}))
+		if err != nil {
+			return err
+		}
+		stale := NewInventoryEntry(&existing[i])
+		RemoveInventoryEntries(replika, func(entry replikav1beta1.ReplikaInventoryEntry) bool {
+			return entry == stale
+		})
+		LogInfof(ctx, staleTargetPruned, existing[i].GetNamespace(), existing[i].GetAnnotations()[targetReplikaGenerationAnnotation])
+		r.RecordAuditLog(ctx, replika, &existing[i], auditlog.ActionDelete, auditReasonPruned)
+	}
+
+	return err
+}
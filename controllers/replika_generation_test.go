@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+func newGenerationReplika(namespace string, uid types.UID, generation int64) *replikav1beta1.Replika {
+	replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{
+		Namespace:  namespace,
+		Name:       "app-config",
+		UID:        uid,
+		Generation: generation,
+	}}
+	replika.Spec.Source = replikav1beta1.ReplikaSourceSpec{
+		Version:   "v1",
+		Kind:      "ConfigMap",
+		Name:      "app-config",
+		Namespace: namespace,
+	}
+	return replika
+}
+
+func newGenerationTarget(replika *replikav1beta1.Replika, namespace string) *corev1.ConfigMap {
+	target := &unstructured.Unstructured{}
+	target.SetNamespace(namespace)
+	target.SetName(replika.Name)
+	target.SetLabels(map[string]string{
+		resourceReplikaLabelCreatedKey:         resourceReplikaLabelCreatedValue,
+		resourceReplikaLabelPartOfKey:          replika.Name,
+		resourceReplikaLabelPartOfNamespaceKey: replika.Namespace,
+	})
+	StampTargets(replika, []unstructured.Unstructured{*target})
+
+	return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   target.GetNamespace(),
+		Name:        target.GetName(),
+		Labels:      target.GetLabels(),
+		Annotations: target.GetAnnotations(),
+	}}
+}
+
+func TestIsStaleTarget(t *testing.T) {
+	replika := newGenerationReplika("team-a", "uid-a", 2)
+
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		expected    bool
+	}{
+		{
+			name:        "current generation",
+			labels:      map[string]string{resourceReplikaLabelPartOfKey: "app-config", resourceReplikaLabelPartOfNamespaceKey: "team-a"},
+			annotations: map[string]string{targetReplikaUIDAnnotation: "uid-a", targetReplikaGenerationAnnotation: "2"},
+		},
+		{
+			name:        "older generation",
+			labels:      map[string]string{resourceReplikaLabelPartOfKey: "app-config", resourceReplikaLabelPartOfNamespaceKey: "team-a"},
+			annotations: map[string]string{targetReplikaUIDAnnotation: "uid-a", targetReplikaGenerationAnnotation: "1"},
+			expected:    true,
+		},
+		{
+			name:        "previous Replika with the same namespace and name",
+			labels:      map[string]string{resourceReplikaLabelPartOfKey: "app-config", resourceReplikaLabelPartOfNamespaceKey: "team-a"},
+			annotations: map[string]string{targetReplikaUIDAnnotation: "uid-old", targetReplikaGenerationAnnotation: "2"},
+			expected:    true,
+		},
+		{
+			name:        "Replika with the same name in another namespace",
+			labels:      map[string]string{resourceReplikaLabelPartOfKey: "app-config", resourceReplikaLabelPartOfNamespaceKey: "team-b"},
+			annotations: map[string]string{targetReplikaUIDAnnotation: "uid-b", targetReplikaGenerationAnnotation: "1"},
+		},
+		{
+			name:        "written before the namespace label by another Replika",
+			labels:      map[string]string{resourceReplikaLabelPartOfKey: "app-config"},
+			annotations: map[string]string{targetReplikaUIDAnnotation: "uid-b", targetReplikaGenerationAnnotation: "1"},
+		},
+		{
+			name:        "written before the namespace label by the Replika",
+			labels:      map[string]string{resourceReplikaLabelPartOfKey: "app-config"},
+			annotations: map[string]string{targetReplikaUIDAnnotation: "uid-a", targetReplikaGenerationAnnotation: "1"},
+			expected:    true,
+		},
+		{
+			name:   "adopted target without annotations",
+			labels: map[string]string{resourceReplikaLabelPartOfKey: "app-config", resourceReplikaLabelPartOfNamespaceKey: "team-a"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target := &unstructured.Unstructured{}
+			target.SetLabels(test.labels)
+			target.SetAnnotations(test.annotations)
+			if stale := IsStaleTarget(replika, target); stale != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, stale)
+			}
+		})
+	}
+}
+
+func TestPruneStaleTargetsSameNameReplikas(t *testing.T) {
+	// Two Replikas with the same name in different namespaces, writing to the same shared namespaces
+	first := newGenerationReplika("team-a", "uid-a", 1)
+	second := newGenerationReplika("team-b", "uid-b", 1)
+
+	var objects []client.Object
+	for _, replika := range []*replikav1beta1.Replika{first, second} {
+		for _, namespace := range []string{"shared-1", "shared-2"} {
+			objects = append(objects, newGenerationTarget(replika, namespace+"-"+replika.Namespace))
+		}
+	}
+
+	r := &ReplikaReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()}
+
+	for _, replika := range []*replikav1beta1.Replika{first, second} {
+		targets, _, err := r.ListTargets(context.Background(), replika)
+		if err != nil {
+			t.Fatalf("unexpected error listing the targets: %v", err)
+		}
+		if len(targets) != 2 {
+			t.Errorf("expected 2 targets listed for %s, got %d", replika.Namespace, len(targets))
+		}
+		if err = r.PruneStaleTargets(context.Background(), replika); err != nil {
+			t.Fatalf("unexpected error pruning the targets: %v", err)
+		}
+	}
+
+	list := &corev1.ConfigMapList{}
+	if err := r.List(context.Background(), list); err != nil || len(list.Items) != 4 {
+		t.Fatalf("expected the 4 targets kept, got %d: %v", len(list.Items), err)
+	}
+
+	// A new generation of the first Replika prunes only its own targets
+	first.Generation = 2
+	if err := r.PruneStaleTargets(context.Background(), first); err != nil {
+		t.Fatalf("unexpected error pruning the targets: %v", err)
+	}
+	if err := r.List(context.Background(), list); err != nil || len(list.Items) != 2 {
+		t.Fatalf("expected 2 targets kept, got %d: %v", len(list.Items), err)
+	}
+	for i := range list.Items {
+		if list.Items[i].Labels[resourceReplikaLabelPartOfNamespaceKey] != second.Namespace {
+			t.Errorf("expected the targets of %s kept, got %s", second.Namespace, list.Items[i].Namespace)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/auditlog"
+)
+
+const (
+	// Annotation keeping the name of the source on the targets named after the hash of their content
+	targetBaseNameAnnotation = "replika.prosimcorp.com/base-name"
+
+	// Length of the hash appended to the names of the targets
+	targetNameHashLength = 10
+)
+
+// HashTargetNames append a short hash of their content to the names of the ConfigMap and Secret targets,
+// like app-config-7f3a2c91d0. Each content is written as a new object, so the workloads only switch to it
+// when they reference the new name. The original name is kept in an annotation
+func HashTargetNames(targets []unstructured.Unstructured) (err error) {
+
+	for i := range targets {
+		if !IsReloadableTarget(&targets[i]) {
+			continue
+		}
+
+		var checksum string
+		checksum, err = GetTargetChecksum(&targets[i])
+		if err != nil {
+			return err
+		}
+
+		baseName := targets[i].GetName()
+		annotations := targets[i].GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[targetBaseNameAnnotation] = baseName
+		targets[i].SetAnnotations(annotations)
+		targets[i].SetName(baseName + "-" + checksum[:targetNameHashLength])
+	}
+
+	return err
+}
+
+// IsHashedName return true when the name is the base name followed by a content hash
+func IsHashedName(name, baseName string) bool {
+	suffix, found := strings.CutPrefix(name, baseName+"-")
+	if !found || len(suffix) != targetNameHashLength {
+		return false
+	}
+	_, err := hex.DecodeString(suffix)
+	return err == nil
+}
+
+// RenamePodSpecReferences point the references of the pod spec to older versions of a hashed target to its
+// current name, returning whether something changed. The references to the base name are only renamed
+// when renameBaseName is true, as they may point to an object the controller does not manage
+func RenamePodSpecReferences(podSpec *corev1.PodSpec, target *unstructured.Unstructured, renameBaseName bool) (changed bool) {
+
+	baseName := target.GetAnnotations()[targetBaseNameAnnotation]
+	isConfigMap := target.GetKind() == "ConfigMap"
+	rename := func(name *string) {
+		if *name != target.GetName() && ((renameBaseName && *name == baseName) || IsHashedName(*name, baseName)) {
+			*name = target.GetName()
+			changed = true
+		}
+	}
+
+	// Rename the volumes, including projected ones
+	for i := range podSpec.Volumes {
+		volume := &podSpec.Volumes[i]
+		if isConfigMap && volume.ConfigMap != nil {
+			rename(&volume.ConfigMap.Name)
+		}
+		if !isConfigMap && volume.Secret != nil {
+			rename(&volume.Secret.SecretName)
+		}
+		if volume.Projected == nil {
+			continue
+		}
+		for j := range volume.Projected.Sources {
+			projection := &volume.Projected.Sources[j]
+			if isConfigMap && projection.ConfigMap != nil {
+				rename(&projection.ConfigMap.Name)
+			}
+			if !isConfigMap && projection.Secret != nil {
+				rename(&projection.Secret.Name)
+			}
+		}
+	}
+
+	// Rename the environment of all the containers
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for i := range containers {
+			for j := range containers[i].EnvFrom {
+				envFrom := &containers[i].EnvFrom[j]
+				if isConfigMap && envFrom.ConfigMapRef != nil {
+					rename(&envFrom.ConfigMapRef.Name)
+				}
+				if !isConfigMap && envFrom.SecretRef != nil {
+					rename(&envFrom.SecretRef.Name)
+				}
+			}
+			for j := range containers[i].Env {
+				valueFrom := containers[i].Env[j].ValueFrom
+				if valueFrom == nil {
+					continue
+				}
+				if isConfigMap && valueFrom.ConfigMapKeyRef != nil {
+					rename(&valueFrom.ConfigMapKeyRef.Name)
+				}
+				if !isConfigMap && valueFrom.SecretKeyRef != nil {
+					rename(&valueFrom.SecretKeyRef.Name)
+				}
+			}
+		}
+	}
+
+	// Rename the image pull secrets
+	if !isConfigMap {
+		for i := range podSpec.ImagePullSecrets {
+			rename(&podSpec.ImagePullSecrets[i].Name)
+		}
+	}
+
+	return changed
+}
+
+// IsSwitchableBaseName return true when the workloads referencing the base name of a hashed target can be
+// switched to it: no object has the base name, or it is a target created by the controller for the Replika
+func (r *ReplikaReconciler) IsSwitchableBaseName(ctx context.Context, replika *replikav1beta1.Replika, target *unstructured.Unstructured) (switchable bool, err error) {
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(target.GroupVersionKind())
+	err = r.uncachedReader().Get(ctx, client.ObjectKey{
+		Namespace: target.GetNamespace(),
+		Name:      target.GetAnnotations()[targetBaseNameAnnotation],
+	}, existing)
+	if apierrors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return switchable, err
+	}
+
+	return IsCreatedByController(existing) && IsPartOfReplika(replika, existing), err
+}
+
+// SwitchWorkloads point the Deployments and StatefulSets referencing older versions of a hashed target,
+// or its base name when switchable, to its current name, rolling them to the new content
+func (r *ReplikaReconciler) SwitchWorkloads(ctx context.Context, replika *replikav1beta1.Replika, target *unstructured.Unstructured) (err error) {
+
+	var renameBaseName bool
+	renameBaseName, err = r.IsSwitchableBaseName(ctx, replika, target)
+	if err != nil {
+		return err
+	}
+
+	// Switch the Deployments
+	deployments := &appsv1.DeploymentList{}
+	err = r.List(ctx, deployments, client.InNamespace(target.GetNamespace()))
+	if err != nil {
+		return err
+	}
+
+	for i := range deployments.Items {
+		original := deployments.Items[i].DeepCopy()
+		if !RenamePodSpecReferences(&deployments.Items[i].Spec.Template.Spec, target, renameBaseName) {
+			continue
+		}
+
+		err = r.Patch(ctx, &deployments.Items[i], client.StrategicMergeFrom(original))
+		if err != nil {
+			return err
+		}
+		LogInfof(ctx, workloadReloaded, "Deployment", deployments.Items[i].Namespace, deployments.Items[i].Name)
+		r.RecordObjectAuditLog(ctx, replika, appsv1.SchemeGroupVersion.WithKind("Deployment"), &deployments.Items[i],
+			auditlog.ActionUpdate, auditReasonSwitch)
+	}
+
+	// Switch the StatefulSets
+	statefulSets := &appsv1.StatefulSetList{}
+	err = r.List(ctx, statefulSets, client.InNamespace(target.GetNamespace()))
+	if err != nil {
+		return err
+	}
+
+	for i := range statefulSets.Items {
+		original := statefulSets.Items[i].DeepCopy()
+		if !RenamePodSpecReferences(&statefulSets.Items[i].Spec.Template.Spec, target, renameBaseName) {
+			continue
+		}
+
+		err = r.Patch(ctx, &statefulSets.Items[i], client.StrategicMergeFrom(original))
+		if err != nil {
+			return err
+		}
+		LogInfof(ctx, workloadReloaded, "StatefulSet", statefulSets.Items[i].Namespace, statefulSets.Items[i].Name)
+		r.RecordObjectAuditLog(ctx, replika, appsv1.SchemeGroupVersion.WithKind("StatefulSet"), &statefulSets.Items[i],
+			auditlog.ActionUpdate, auditReasonSwitch)
+	}
+
+	return err
+}
+
+// IsReferencedVersion return true when the target is an older version of a hashed target still referenced
+// by a workload or a Pod, even an owned one of a previous revision, so it is not pruned until nobody uses it
+func (r *ReplikaReconciler) IsReferencedVersion(ctx context.Context, replika *replikav1beta1.Replika, target *unstructured.Unstructured) (referenced bool, err error) {
+
+	if !replika.Spec.Target.HashSuffix || target.GetAnnotations()[targetBaseNameAnnotation] == "" {
+		return referenced, err
+	}
+
+	var consumers []replikav1beta1.ReplikaConsumerStatus
+	consumers, err = r.GetTargetConsumers(ctx, target)
+	if err != nil || len(consumers) > 0 {
+		return len(consumers) > 0, err
+	}
+
+	pods := &corev1.PodList{}
+	err = r.List(ctx, pods, client.InNamespace(target.GetNamespace()))
+	if err != nil {
+		return referenced, err
+	}
+	for i := range pods.Items {
+		if PodSpecReferencesTarget(&pods.Items[i].Spec, target) || PodSpecPullsWithTarget(&pods.Items[i].Spec, target) {
+			referenced = true
+			break
+		}
+	}
+
+	return referenced, err
+}
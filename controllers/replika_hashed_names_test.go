@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+// newHashedTarget return a hashed target of the kind with its current name
+func newHashedTarget(kind, name, baseName string) *unstructured.Unstructured {
+	target := &unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "v1", "kind": kind}}
+	target.SetName(name)
+	target.SetAnnotations(map[string]string{targetBaseNameAnnotation: baseName})
+	return target
+}
+
+func TestIsHashedName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{name: "app-config-7f3a2c91d0", expected: true},
+		{name: "app-config"},
+		{name: "app-config-7f3a2c91"},
+		{name: "app-config-zzzzzzzzzz"},
+		{name: "app-config-extra-7f3a2c91d0"},
+		{name: "other-7f3a2c91d0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if hashed := IsHashedName(test.name, "app-config"); hashed != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, hashed)
+			}
+		})
+	}
+}
+
+func TestHashTargetNames(t *testing.T) {
+	newTarget := func(kind, value string) unstructured.Unstructured {
+		target := unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       kind,
+			"data":       map[string]interface{}{"a": value},
+		}}
+		target.SetName("app-config")
+		return target
+	}
+
+	targets := []unstructured.Unstructured{newTarget("ConfigMap", "1"), newTarget("ConfigMap", "2"), newTarget("Service", "1")}
+	if err := HashTargetNames(targets); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := range targets[:2] {
+		if !IsHashedName(targets[i].GetName(), "app-config") {
+			t.Errorf("expected a hashed name, got %s", targets[i].GetName())
+		}
+		if targets[i].GetAnnotations()[targetBaseNameAnnotation] != "app-config" {
+			t.Errorf("expected the base name kept, got %v", targets[i].GetAnnotations())
+		}
+	}
+	if targets[0].GetName() == targets[1].GetName() {
+		t.Errorf("different contents share the name %s", targets[0].GetName())
+	}
+	if targets[2].GetName() != "app-config" {
+		t.Errorf("expected other kinds not renamed, got %s", targets[2].GetName())
+	}
+}
+
+func TestRenamePodSpecReferences(t *testing.T) {
+	const current = "app-config-00000000ff"
+
+	tests := []struct {
+		name     string
+		kind     string
+		podSpec  corev1.PodSpec
+		baseName bool
+		changed  bool
+		renamed  func(podSpec *corev1.PodSpec) string
+		expected string
+	}{
+		{
+			name: "volume referencing an older version",
+			kind: "ConfigMap",
+			podSpec: corev1.PodSpec{Volumes: []corev1.Volume{{Name: "config", VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-config-7f3a2c91d0"}},
+			}}}},
+			changed:  true,
+			renamed:  func(podSpec *corev1.PodSpec) string { return podSpec.Volumes[0].ConfigMap.Name },
+			expected: current,
+		},
+		{
+			name: "environment referencing the base name",
+			kind: "ConfigMap",
+			podSpec: corev1.PodSpec{Containers: []corev1.Container{{Name: "web", EnvFrom: []corev1.EnvFromSource{{
+				ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"}},
+			}}}}},
+			baseName: true,
+			changed:  true,
+			renamed:  func(podSpec *corev1.PodSpec) string { return podSpec.Containers[0].EnvFrom[0].ConfigMapRef.Name },
+			expected: current,
+		},
+		{
+			name: "environment referencing a base name not switchable",
+			kind: "ConfigMap",
+			podSpec: corev1.PodSpec{Containers: []corev1.Container{{Name: "web", EnvFrom: []corev1.EnvFromSource{{
+				ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"}},
+			}}}}},
+			renamed:  func(podSpec *corev1.PodSpec) string { return podSpec.Containers[0].EnvFrom[0].ConfigMapRef.Name },
+			expected: "app-config",
+		},
+		{
+			name: "already referencing the current version",
+			kind: "ConfigMap",
+			podSpec: corev1.PodSpec{Volumes: []corev1.Volume{{Name: "config", VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: current}},
+			}}}},
+			renamed:  func(podSpec *corev1.PodSpec) string { return podSpec.Volumes[0].ConfigMap.Name },
+			expected: current,
+		},
+		{
+			name: "referencing other objects",
+			kind: "ConfigMap",
+			podSpec: corev1.PodSpec{Volumes: []corev1.Volume{{Name: "config", VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-config-extra"}},
+			}}}},
+			renamed:  func(podSpec *corev1.PodSpec) string { return podSpec.Volumes[0].ConfigMap.Name },
+			expected: "app-config-extra",
+		},
+		{
+			name: "Secret of the same name",
+			kind: "ConfigMap",
+			podSpec: corev1.PodSpec{Volumes: []corev1.Volume{{Name: "config", VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: "app-config"},
+			}}}},
+			renamed:  func(podSpec *corev1.PodSpec) string { return podSpec.Volumes[0].Secret.SecretName },
+			expected: "app-config",
+		},
+		{
+			name:     "image pull Secret",
+			kind:     "Secret",
+			podSpec:  corev1.PodSpec{ImagePullSecrets: []corev1.LocalObjectReference{{Name: "app-config-7f3a2c91d0"}}},
+			changed:  true,
+			renamed:  func(podSpec *corev1.PodSpec) string { return podSpec.ImagePullSecrets[0].Name },
+			expected: current,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			podSpec := test.podSpec.DeepCopy()
+			changed := RenamePodSpecReferences(podSpec, newHashedTarget(test.kind, current, "app-config"), test.baseName)
+			if changed != test.changed {
+				t.Errorf("expected changed %t, got %t", test.changed, changed)
+			}
+			if name := test.renamed(podSpec); name != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, name)
+			}
+		})
+	}
+}
+
+func TestSwitchWorkloadsBaseName(t *testing.T) {
+	replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-config"}}
+	ownLabels := map[string]string{
+		resourceReplikaLabelCreatedKey:         resourceReplikaLabelCreatedValue,
+		resourceReplikaLabelPartOfKey:          replika.Name,
+		resourceReplikaLabelPartOfNamespaceKey: replika.Namespace,
+	}
+	foreignLabels := map[string]string{
+		resourceReplikaLabelCreatedKey:         resourceReplikaLabelCreatedValue,
+		resourceReplikaLabelPartOfKey:          replika.Name,
+		resourceReplikaLabelPartOfNamespaceKey: "other",
+	}
+
+	tests := []struct {
+		name     string
+		existing *corev1.ConfigMap
+		expected string
+	}{
+		{name: "base name not found", expected: "app-config-00000000ff"},
+		{
+			name:     "base name created for the Replika",
+			existing: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "app-config", Labels: ownLabels}},
+			expected: "app-config-00000000ff",
+		},
+		{
+			name:     "base name not created by the controller",
+			existing: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "app-config"}},
+			expected: "app-config",
+		},
+		{
+			name:     "base name created for another Replika",
+			existing: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "app-config", Labels: foreignLabels}},
+			expected: "app-config",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "web"}}
+			deployment.Spec.Template.Spec.Volumes = []corev1.Volume{{Name: "config", VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"}},
+			}}}
+			objects := []client.Object{deployment}
+			if test.existing != nil {
+				objects = append(objects, test.existing)
+			}
+			r := &ReplikaReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()}
+
+			target := newHashedTarget("ConfigMap", "app-config-00000000ff", "app-config")
+			target.SetNamespace("team-a")
+			if err := r.SwitchWorkloads(context.Background(), replika, target); err != nil {
+				t.Fatalf("unexpected error switching the workloads: %v", err)
+			}
+
+			switched := &appsv1.Deployment{}
+			if err := r.Get(context.Background(), client.ObjectKeyFromObject(deployment), switched); err != nil {
+				t.Fatalf("unexpected error getting the Deployment: %v", err)
+			}
+			if name := switched.Spec.Template.Spec.Volumes[0].ConfigMap.Name; name != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, name)
+			}
+		})
+	}
+}
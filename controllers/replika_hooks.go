@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/auditlog"
+	"prosimcorp.com/replika/pkg/conditions"
+)
+
+const (
+	// Phases of the synchronization where the hooks run
+	hookPhasePreSync  = "presync"
+	hookPhasePostSync = "postsync"
+
+	// Annotation holding the revision of the source a hook Job was created for
+	hookRevisionAnnotation = "replika.prosimcorp.com/revision"
+
+	// Length of the revision included in the name of the hook Jobs
+	hookRevisionLength = 10
+
+	// Time the finished hook Jobs are kept, unless their template defines it
+	hookJobTTL = time.Hour
+)
+
+// errHookPending is returned while a hook Job is running
+var errHookPending = &PendingError{reason: "a hook Job of the synchronization is running"}
+
+// GetTargetsRevision return the revision of the source being synchronized, shared by all the targets
+func GetTargetsRevision(targets []unstructured.Unstructured) (revision string, err error) {
+	if len(targets) == 0 {
+		return revision, err
+	}
+	return GetTargetHash(&targets[0], &targets[0])
+}
+
+// GetHookJobName return the name of the Job of a hook for a revision of the source.
+// The Pods of the Job are labeled with its name, so long names are truncated and suffixed with their hash
+func GetHookJobName(replika *replikav1beta1.Replika, phase, revision string) string {
+	if len(revision) > hookRevisionLength {
+		revision = revision[:hookRevisionLength]
+	}
+
+	name := replika.Name + "-" + phase + "-" + revision
+	if len(name) <= validation.LabelValueMaxLength {
+		return name
+	}
+
+	hash := sha256.Sum256([]byte(name))
+	suffix := hex.EncodeToString(hash[:])[:hookRevisionLength]
+	return strings.TrimRight(name[:validation.LabelValueMaxLength-len(suffix)-1], "-.") + "-" + suffix
+}
+
+// RunHook create the Job of a hook for the revision of the source and return errHookPending until it finishes.
+// The Job is created in the namespace of the Replika, owned by it and run as the ServiceAccount of the hook.
+// The revision is recorded in the status once the Job completes, so the hook runs once per revision even
+// when the finished Job is removed
+func (r *ReplikaReconciler) RunHook(ctx context.Context, replika *replikav1beta1.Replika, hook *replikav1beta1.ReplikaHookSpec, phase, revision string) (err error) {
+
+	if replika.Status.HookRevisions[phase] == revision {
+		return err
+	}
+
+	jobName := GetHookJobName(replika, phase, revision)
+
+	job := &batchv1.Job{}
+	err = r.Get(ctx, client.ObjectKey{Namespace: replika.Namespace, Name: jobName}, job)
+	if apierrors.IsNotFound(err) {
+
+		// The Job only gets the permissions of an existing ServiceAccount of the namespace
+		err = r.Get(ctx, client.ObjectKey{Namespace: replika.Namespace, Name: hook.ServiceAccountName}, &corev1.ServiceAccount{})
+		if apierrors.IsNotFound(err) {
+			err = NewPermanentErrorf(hookServiceAccountError, replika.Namespace, hook.ServiceAccountName, phase)
+		}
+		if err != nil {
+			return err
+		}
+
+		job = &batchv1.Job{
+			ObjectMeta: *hook.Template.ObjectMeta.DeepCopy(),
+			Spec:       *hook.Template.Spec.DeepCopy(),
+		}
+		job.SetNamespace(replika.Namespace)
+		job.SetName(jobName)
+		job.Spec.Template.Spec.ServiceAccountName = hook.ServiceAccountName
+		job.Spec.Template.Spec.DeprecatedServiceAccount = ""
+		if job.Spec.TTLSecondsAfterFinished == nil {
+			ttl := int32(hookJobTTL.Seconds())
+			job.Spec.TTLSecondsAfterFinished = &ttl
+		}
+
+		labels := job.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[resourceReplikaLabelCreatedKey] = resourceReplikaLabelCreatedValue
+		labels[resourceReplikaLabelPartOfKey] = replika.Name
+		labels[resourceReplikaLabelPartOfNamespaceKey] = replika.Namespace
+		job.SetLabels(labels)
+
+		annotations := job.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[hookRevisionAnnotation] = revision
+		job.SetAnnotations(annotations)
+
+		err = controllerutil.SetControllerReference(replika, job, r.Scheme)
+		if err != nil {
+			return err
+		}
+
+		err = r.Create(ctx, job)
+		if err != nil {
+			return err
+		}
+		LogInfof(ctx, hookJobCreated, phase, replika.Namespace, jobName)
+		r.RecordObjectAuditLog(ctx, replika, batchv1.SchemeGroupVersion.WithKind("Job"), job, auditlog.ActionCreate, auditReasonHook)
+		return errHookPending
+	}
+	if err != nil {
+		return err
+	}
+
+	completed, failed := GetJobResult(job, job.CreationTimestamp.Time)
+	if failed {
+		err = NewErrorf(hookJobFailedError, phase, replika.Namespace, jobName)
+		return err
+	}
+	if !completed {
+		return errHookPending
+	}
+
+	if replika.Status.HookRevisions == nil {
+		replika.Status.HookRevisions = map[string]string{}
+	}
+	replika.Status.HookRevisions[phase] = revision
+
+	return err
+}
+
+// RunHooks run the hook of a phase of the synchronization, when defined,
+// reflecting its state in the status of the Replika
+func (r *ReplikaReconciler) RunHooks(ctx context.Context, replika *replikav1beta1.Replika, targets []unstructured.Unstructured, phase string) (err error) {
+
+	hook := replika.Spec.Hooks.PreSync
+	if phase == hookPhasePostSync {
+		hook = replika.Spec.Hooks.PostSync
+	}
+	if hook == nil {
+		return err
+	}
+
+	var revision string
+	revision, err = GetTargetsRevision(targets)
+	if err != nil || revision == "" {
+		return err
+	}
+
+	// The hooks run any Pod as a ServiceAccount of the namespace, so only the operators enabling them run them
+	if r.HookJobs {
+		err = r.RunHook(ctx, replika, hook, phase, revision)
+	} else {
+		err = NewPermanentErrorf(hookJobsDisabledError, phase, replika.Namespace, replika.Name)
+	}
+
+	switch {
+	case IsPendingError(err):
+		r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+			metav1.ConditionFalse,
+			ConditionReasonHookPending,
+			ConditionReasonHookPendingMessage,
+		))
+	case err != nil:
+		reason, message := GetFailureReason(err, ConditionReasonHookFailed, ConditionReasonHookFailedMessage)
+		r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+			metav1.ConditionFalse,
+			reason,
+			message,
+		))
+	}
+
+	return err
+}
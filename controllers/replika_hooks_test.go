@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/conditions"
+)
+
+func TestGetHookJobName(t *testing.T) {
+	const revision = "0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name     string
+		replika  string
+		expected string
+	}{
+		{
+			name:     "short name",
+			replika:  "app-config",
+			expected: "app-config-preSync-" + revision[:hookRevisionLength],
+		},
+		{
+			name:    "name over the limit",
+			replika: strings.Repeat("a", 60),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Name: test.replika}}
+
+			name := GetHookJobName(replika, "preSync", revision)
+			if test.expected != "" && name != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, name)
+			}
+			if len(name) > validation.LabelValueMaxLength {
+				t.Errorf("the name %s exceeds the length of a label value", name)
+			}
+		})
+	}
+
+	// Long names of different phases are never truncated to the same Job
+	replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 60)}}
+	if GetHookJobName(replika, "preSync", revision) == GetHookJobName(replika, "postSync", revision) {
+		t.Errorf("the phases share the Job %s", GetHookJobName(replika, "preSync", revision))
+	}
+}
+
+func TestRunHooksDisabled(t *testing.T) {
+	replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-config"}}
+	replika.Spec.Hooks.PreSync = &replikav1beta1.ReplikaHookSpec{ServiceAccountName: "hook-runner"}
+
+	target := unstructured.Unstructured{}
+	target.SetAPIVersion("v1")
+	target.SetKind("ConfigMap")
+	target.SetNamespace("team-a")
+	target.SetName("app-config")
+
+	// The Job is never created, so no client is needed
+	r := &ReplikaReconciler{}
+	err := r.RunHooks(context.Background(), replika, []unstructured.Unstructured{target}, hookPhasePreSync)
+	if !IsPermanentError(err) {
+		t.Fatalf("expected a permanent error, got %v", err)
+	}
+	condition := conditions.Get(replika.Status.Conditions, ConditionTypeSourceSynced)
+	if condition == nil || condition.Status != metav1.ConditionFalse {
+		t.Errorf("expected the synchronization failed, got %v", condition)
+	}
+}
@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/conditions"
+)
+
+const (
+	// Default time waiting for the response of an HTTP hook
+	defaultHTTPHookTimeout = 10 * time.Second
+
+	// Outcomes of the synchronization sent to the HTTP hooks
+	httpHookOutcomePending   = "Pending"
+	httpHookOutcomeSucceeded = "Succeeded"
+	httpHookOutcomeFailed    = "Failed"
+
+	// Failure policies of the HTTP hooks
+	httpHookFailurePolicyFail = "Fail"
+
+	// Key of the status recording the targets the HTTP hooks were notified of
+	httpHookRevisionKey = "http"
+)
+
+// httpHookClient calls the HTTP hooks. Redirects are never followed, so the calls stay on the allowed hosts
+var httpHookClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// HTTPHookReplika defines the identity of the Replika sent to the HTTP hooks
+type HTTPHookReplika struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// HTTPHookPayload defines the body sent to the HTTP hooks
+type HTTPHookPayload struct {
+	Replika HTTPHookReplika `json:"replika"`
+	Phase   string          `json:"phase"`
+	Source  string          `json:"source"`
+	Outcome string          `json:"outcome"`
+	Error   string          `json:"error,omitempty"`
+	Targets []string        `json:"targets"`
+	Time    metav1.Time     `json:"time"`
+}
+
+// BuildHTTPHookPayload return the body sent to the HTTP hooks of a phase of the synchronization
+func BuildHTTPHookPayload(replika *replikav1beta1.Replika, phase string, targets []unstructured.Unstructured, syncErr error) (payload HTTPHookPayload) {
+
+	payload = HTTPHookPayload{
+		Replika: HTTPHookReplika{Namespace: replika.Namespace, Name: replika.Name},
+		Phase:   phase,
+		Source:  GetSourceRef(replika),
+		Outcome: httpHookOutcomePending,
+		Targets: []string{},
+		Time:    metav1.Now(),
+	}
+
+	for i := range targets {
+		payload.Targets = append(payload.Targets, targets[i].GetNamespace())
+	}
+
+	if phase != hookPhasePostSync {
+		return payload
+	}
+
+	switch {
+	case IsPendingError(syncErr):
+		payload.Outcome = httpHookOutcomePending
+	case syncErr != nil:
+		payload.Outcome = httpHookOutcomeFailed
+		payload.Error = syncErr.Error()
+	default:
+		payload.Outcome = httpHookOutcomeSucceeded
+	}
+
+	return payload
+}
+
+// ParseHostList return the hosts of a list separated by commas or new lines
+func ParseHostList(value string) (hosts []string) {
+	return parseOperatorConfigList(value)
+}
+
+// IsHTTPHookAllowed return true when the URL is an http(s) one whose host is allowed, as 'host' or '*.domain'
+func IsHTTPHookAllowed(rawURL string, allowedHosts []string) bool {
+
+	hookURL, err := url.Parse(rawURL)
+	if err != nil || (hookURL.Scheme != "http" && hookURL.Scheme != "https") {
+		return false
+	}
+
+	host := strings.ToLower(hookURL.Hostname())
+	for _, v := range allowedHosts {
+		v = strings.ToLower(v)
+		if host == v || (strings.HasPrefix(v, "*.") && strings.HasSuffix(host, v[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetHTTPHookRevision return a hash of the targets, so the HTTP hooks are only notified when they change
+func GetHTTPHookRevision(targets []unstructured.Unstructured) (revision string, err error) {
+
+	hash := sha256.New()
+	for i := range targets {
+		var targetHash string
+		targetHash, err = GetTargetHash(&targets[i], &targets[i])
+		if err != nil {
+			return revision, err
+		}
+		hash.Write([]byte(targets[i].GetNamespace() + "/" + targets[i].GetName() + "=" + targetHash + "\n"))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), err
+}
+
+// GetHTTPHookTimeout return the time waiting for the response of an HTTP hook, never over the maximum timeout
+// of the HTTP hooks, so a slow endpoint can not hold the synchronizations
+func GetHTTPHookTimeout(hook replikav1beta1.ReplikaHTTPHookSpec) (timeout time.Duration, err error) {
+
+	timeout = defaultHTTPHookTimeout
+	if hook.Timeout != "" {
+		timeout, err = time.ParseDuration(hook.Timeout)
+		if err != nil {
+			return timeout, err
+		}
+	}
+	if timeout <= 0 || timeout > replikav1beta1.MaxHTTPHookTimeout {
+		timeout = replikav1beta1.MaxHTTPHookTimeout
+	}
+
+	return timeout, err
+}
+
+// CallHTTPHook send the payload to the endpoint of the hook, failing on any response other than 2xx.
+// Only the allowed hosts are called
+func CallHTTPHook(ctx context.Context, hook replikav1beta1.ReplikaHTTPHookSpec, payload HTTPHookPayload, allowedHosts []string) (err error) {
+
+	if !IsHTTPHookAllowed(hook.URL, allowedHosts) {
+		err = NewPermanentErrorf(httpHookHostError, hook.URL)
+		return err
+	}
+
+	var timeout time.Duration
+	timeout, err = GetHTTPHookTimeout(hook)
+	if err != nil {
+		err = NewPermanentErrorf(httpHookTimeoutError, hook.URL, err.Error())
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var body []byte
+	body, err = json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var request *http.Request
+	request, err = http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	var response *http.Response
+	response, err = httpHookClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		err = NewErrorf(httpHookStatusError, hook.URL, response.StatusCode)
+	}
+
+	return err
+}
+
+// CallHTTPHooks call the HTTP hooks defined for a phase of the synchronization, only when the targets changed
+// since the last synchronization notified to them. The failures of the hooks with the 'Fail' policy are returned,
+// the rest of them are only logged
+func (r *ReplikaReconciler) CallHTTPHooks(ctx context.Context, replika *replikav1beta1.Replika, phase string, targets []unstructured.Unstructured, syncErr error) (err error) {
+
+	if len(replika.Spec.Hooks.HTTP) == 0 {
+		return err
+	}
+
+	var revision string
+	revision, err = GetHTTPHookRevision(targets)
+	if err != nil || replika.Status.HookRevisions[httpHookRevisionKey] == revision {
+		return err
+	}
+
+	// The synchronizations are notified until one of them succeeds
+	defer func() {
+		if phase == hookPhasePostSync && syncErr == nil && err == nil {
+			if replika.Status.HookRevisions == nil {
+				replika.Status.HookRevisions = map[string]string{}
+			}
+			replika.Status.HookRevisions[httpHookRevisionKey] = revision
+		}
+	}()
+
+	for _, hook := range replika.Spec.Hooks.HTTP {
+		if !hook.HasPhase(phase) {
+			continue
+		}
+
+		hookErr := CallHTTPHook(ctx, hook, BuildHTTPHookPayload(replika, phase, targets, syncErr), r.HTTPHookAllowedHosts)
+		if hookErr == nil {
+			continue
+		}
+
+		LogErrorDedupf(ctx, httpHookError, phase, hook.URL, hookErr.Error())
+		if hook.FailurePolicy == httpHookFailurePolicyFail && err == nil {
+			err = hookErr
+			r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+				metav1.ConditionFalse,
+				ConditionReasonHookFailed,
+				ConditionReasonHookFailedMessage,
+			))
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+func TestIsHTTPHookAllowed(t *testing.T) {
+	allowedHosts := []string{"hooks.example.com", "*.internal.example.com"}
+
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{url: "https://hooks.example.com/replika", expected: true},
+		{url: "http://hooks.example.com:8080/replika", expected: true},
+		{url: "https://HOOKS.example.com/replika", expected: true},
+		{url: "https://ci.internal.example.com/notify", expected: true},
+		{url: "https://internal.example.com/notify"},
+		{url: "https://evilinternal.example.com/notify"},
+		{url: "https://hooks.example.com.evil.com/replika"},
+		{url: "https://169.254.169.254/latest/meta-data"},
+		{url: "ftp://hooks.example.com/replika"},
+		{url: "://hooks.example.com"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			if allowed := IsHTTPHookAllowed(test.url, allowedHosts); allowed != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, allowed)
+			}
+		})
+	}
+
+	if IsHTTPHookAllowed("https://hooks.example.com/replika", nil) {
+		t.Errorf("expected every host refused without allowed hosts")
+	}
+}
+
+func TestParseHostList(t *testing.T) {
+	hosts := ParseHostList("hooks.example.com, *.internal.example.com\nci.example.com")
+	expected := []string{"hooks.example.com", "*.internal.example.com", "ci.example.com"}
+	if !reflect.DeepEqual(hosts, expected) {
+		t.Errorf("expected %v, got %v", expected, hosts)
+	}
+}
+
+func TestGetHTTPHookTimeout(t *testing.T) {
+	tests := []struct {
+		timeout  string
+		expected time.Duration
+		invalid  bool
+	}{
+		{timeout: "", expected: defaultHTTPHookTimeout},
+		{timeout: "5s", expected: 5 * time.Second},
+		{timeout: "1h", expected: replikav1beta1.MaxHTTPHookTimeout},
+		{timeout: "0s", expected: replikav1beta1.MaxHTTPHookTimeout},
+		{timeout: "soon", invalid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.timeout, func(t *testing.T) {
+			timeout, err := GetHTTPHookTimeout(replikav1beta1.ReplikaHTTPHookSpec{Timeout: test.timeout})
+			if (err != nil) != test.invalid {
+				t.Fatalf("expected invalid %t, got %v", test.invalid, err)
+			}
+			if !test.invalid && timeout != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, timeout)
+			}
+		})
+	}
+}
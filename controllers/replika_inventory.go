@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/auditlog"
+)
+
+// NewInventoryEntry return the inventory entry of a target
+func NewInventoryEntry(target *unstructured.Unstructured) replikav1beta1.ReplikaInventoryEntry {
+	return replikav1beta1.ReplikaInventoryEntry{
+		APIVersion: target.GetAPIVersion(),
+		Kind:       target.GetKind(),
+		Namespace:  target.GetNamespace(),
+		Name:       target.GetName(),
+	}
+}
+
+// AddInventoryEntries record in the inventory of the Replika the targets written, once each
+func AddInventoryEntries(replika *replikav1beta1.Replika, entries ...replikav1beta1.ReplikaInventoryEntry) {
+
+	recorded := map[replikav1beta1.ReplikaInventoryEntry]bool{}
+	for _, entry := range replika.Status.Inventory {
+		recorded[entry] = true
+	}
+
+	for _, entry := range entries {
+		if recorded[entry] {
+			continue
+		}
+		recorded[entry] = true
+		replika.Status.Inventory = append(replika.Status.Inventory, entry)
+	}
+}
+
+// RemoveInventoryEntries remove from the inventory of the Replika the entries matching the filter
+func RemoveInventoryEntries(replika *replikav1beta1.Replika, remove func(entry replikav1beta1.ReplikaInventoryEntry) bool) {
+
+	inventory := replika.Status.Inventory[:0]
+	for _, entry := range replika.Status.Inventory {
+		if !remove(entry) {
+			inventory = append(inventory, entry)
+		}
+	}
+	replika.Status.Inventory = inventory
+	if len(replika.Status.Inventory) == 0 {
+		replika.Status.Inventory = nil
+	}
+}
+
+// GetInventoryTarget return the object of an inventory entry when it still exists and belongs to the Replika.
+// Objects relabeled or replaced by someone else are never returned, so they are never deleted
+func (r *ReplikaReconciler) GetInventoryTarget(ctx context.Context, replika *replikav1beta1.Replika,
+	entry replikav1beta1.ReplikaInventoryEntry) (target *unstructured.Unstructured, err error) {
+
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(schema.FromAPIVersionAndKind(entry.APIVersion, entry.Kind))
+	err = r.Get(ctx, types.NamespacedName{Namespace: entry.Namespace, Name: entry.Name}, object)
+	if err != nil {
+		return target, client.IgnoreNotFound(err)
+	}
+
+	if !IsCreatedByController(object) || !IsPartOfReplika(replika, object) {
+		return target, err
+	}
+	return object, err
+}
+
+// PruneInventory delete the targets of the inventory that are not computed anymore, like the ones of the namespaces
+// leaving the selection or the ones of a previous source. The namespaces rejected on this synchronization keep
+// their targets, as they are still desired
+func (r *ReplikaReconciler) PruneInventory(ctx context.Context, replika *replikav1beta1.Replika, targets []unstructured.Unstructured) (err error) {
+
+	desired := map[replikav1beta1.ReplikaInventoryEntry]bool{}
+	for i := range targets {
+		desired[NewInventoryEntry(&targets[i])] = true
+	}
+	rejected := map[string]bool{}
+	for _, v := range replika.Status.RejectedNamespaces {
+		rejected[v.Namespace] = true
+	}
+
+	pruned := map[replikav1beta1.ReplikaInventoryEntry]bool{}
+	defer func() {
+		RemoveInventoryEntries(replika, func(entry replikav1beta1.ReplikaInventoryEntry) bool {
+			return pruned[entry]
+		})
+	}()
+
+	for _, entry := range replika.Status.Inventory {
+		if desired[entry] || rejected[entry.Namespace] {
+			continue
+		}
+
+		var target *unstructured.Unstructured
+		target, err = r.GetInventoryTarget(ctx, replika, entry)
+		if err != nil {
+			return err
+		}
+		if target != nil {
+			// Keep the previous versions of the hashed targets while they are referenced
+			var referenced bool
+			referenced, err = r.IsReferencedVersion(ctx, replika, target)
+			if err != nil {
+				return err
+			}
+			if referenced {
+				continue
+			}
+
+			uid := target.GetUID()
+			err = client.IgnoreNotFound(r.Delete(ctx, target, client.Preconditions{UID: &uid}))
+			if err != nil {
+				return err
+			}
+			LogInfof(ctx, inventoryTargetPruned, entry.Kind, entry.Namespace, entry.Name)
+			r.RecordAuditLog(ctx, replika, target, auditlog.ActionDelete, auditReasonPruned)
+			if r.Recorder != nil {
+				r.Recorder.Eventf(replika, corev1.EventTypeNormal, targetPrunedEventReason, inventoryTargetPruned,
+					entry.Kind, entry.Namespace, entry.Name)
+			}
+		}
+		pruned[entry] = true
+	}
+
+	return err
+}
+
+// ListInventoryTargets return the targets of the inventory not returned by ListTargets, like the ones
+// of a previous source, so they are deleted along with the Replika. The entries of the objects
+// that are gone are removed from the inventory
+func (r *ReplikaReconciler) ListInventoryTargets(ctx context.Context, replika *replikav1beta1.Replika,
+	listed []unstructured.Unstructured) (targets []unstructured.Unstructured, err error) {
+
+	known := map[replikav1beta1.ReplikaInventoryEntry]bool{}
+	for i := range listed {
+		known[NewInventoryEntry(&listed[i])] = true
+	}
+
+	gone := map[replikav1beta1.ReplikaInventoryEntry]bool{}
+	for _, entry := range replika.Status.Inventory {
+		if known[entry] {
+			continue
+		}
+
+		var target *unstructured.Unstructured
+		target, err = r.GetInventoryTarget(ctx, replika, entry)
+		if err != nil {
+			return targets, err
+		}
+		if target == nil {
+			gone[entry] = true
+			continue
+		}
+		targets = append(targets, *target)
+	}
+
+	RemoveInventoryEntries(replika, func(entry replikav1beta1.ReplikaInventoryEntry) bool {
+		return gone[entry]
+	})
+	return targets, err
+}
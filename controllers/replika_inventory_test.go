@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+// newInventoryEntry return the inventory entry of a ConfigMap
+func newInventoryEntry(namespace, name string) replikav1beta1.ReplikaInventoryEntry {
+	return replikav1beta1.ReplikaInventoryEntry{APIVersion: "v1", Kind: "ConfigMap", Namespace: namespace, Name: name}
+}
+
+func TestInventoryEntries(t *testing.T) {
+	replika := &replikav1beta1.Replika{}
+	first := newInventoryEntry("team-a", "app-config")
+	second := newInventoryEntry("team-b", "app-config")
+
+	AddInventoryEntries(replika, first, second, first)
+	AddInventoryEntries(replika, second)
+	if len(replika.Status.Inventory) != 2 {
+		t.Fatalf("expected each target recorded once, got %v", replika.Status.Inventory)
+	}
+
+	RemoveInventoryEntries(replika, func(entry replikav1beta1.ReplikaInventoryEntry) bool {
+		return entry == first
+	})
+	if len(replika.Status.Inventory) != 1 || replika.Status.Inventory[0] != second {
+		t.Errorf("expected only the second entry kept, got %v", replika.Status.Inventory)
+	}
+
+	RemoveInventoryEntries(replika, func(replikav1beta1.ReplikaInventoryEntry) bool { return true })
+	if replika.Status.Inventory != nil {
+		t.Errorf("expected an empty inventory, got %v", replika.Status.Inventory)
+	}
+}
+
+func TestPruneInventory(t *testing.T) {
+	replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-config"}}
+	labels := map[string]string{
+		resourceReplikaLabelCreatedKey:         resourceReplikaLabelCreatedValue,
+		resourceReplikaLabelPartOfKey:          replika.Name,
+		resourceReplikaLabelPartOfNamespaceKey: replika.Namespace,
+	}
+
+	// team-a is still desired, team-b left the selection, team-c was rejected on this synchronization
+	// and team-d holds an object relabeled by someone else
+	var objects []client.Object
+	for _, namespace := range []string{"team-a", "team-b", "team-c"} {
+		objects = append(objects, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "app-config", Labels: labels}})
+		AddInventoryEntries(replika, newInventoryEntry(namespace, "app-config"))
+	}
+	objects = append(objects, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-d", Name: "app-config"}})
+	AddInventoryEntries(replika, newInventoryEntry("team-d", "app-config"), newInventoryEntry("team-e", "app-config"))
+	replika.Status.RejectedNamespaces = []replikav1beta1.ReplikaNamespaceStatus{{Namespace: "team-c"}}
+
+	r := &ReplikaReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()}
+
+	desired := &unstructured.Unstructured{}
+	desired.SetAPIVersion("v1")
+	desired.SetKind("ConfigMap")
+	desired.SetNamespace("team-a")
+	desired.SetName("app-config")
+	if err := r.PruneInventory(context.Background(), replika, []unstructured.Unstructured{*desired}); err != nil {
+		t.Fatalf("unexpected error pruning the inventory: %v", err)
+	}
+
+	list := &corev1.ConfigMapList{}
+	if err := r.List(context.Background(), list); err != nil {
+		t.Fatalf("unexpected error listing the targets: %v", err)
+	}
+	kept := map[string]bool{}
+	for i := range list.Items {
+		kept[list.Items[i].Namespace] = true
+	}
+	if len(kept) != 3 || !kept["team-a"] || !kept["team-c"] || !kept["team-d"] {
+		t.Errorf("expected the targets of team-a, team-c and the foreign object of team-d kept, got %v", kept)
+	}
+
+	inventory := map[string]bool{}
+	for _, entry := range replika.Status.Inventory {
+		inventory[entry.Namespace] = true
+	}
+	if len(inventory) != 2 || !inventory["team-a"] || !inventory["team-c"] {
+		t.Errorf("expected the entries of team-a and team-c kept, got %v", replika.Status.Inventory)
+	}
+}
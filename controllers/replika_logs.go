@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -13,6 +15,86 @@ const (
 	parseSyncTimeError                = "Can not parse the synchronization time from replika: %s"
 	sourceAndTargetSameNamespaceError = "The source and targets have the same namespace: %s"
 	namespaceFormatError              = "The namespaces is in a wrong format: %s"
+	targetDryRunRejectedError         = "The target was rejected on dry-run in namespace %s: %s"
+	targetsRejectedError              = "The target was rejected in %d namespaces"
+	workloadReloadError               = "Can not reload the workloads consuming the target in namespace %s: %s"
+	consumersDiscoveryError           = "Can not discover the consumers of the targets for the Replika %s: %s"
+	protectedNamespaceError           = "The namespace is protected by the operator configuration: %s"
+	sourceKindNotAllowedError         = "The kind of the source is not allowed by the operator configuration: %s"
+	sourceClusterScopedError          = "The kind of the source is cluster-scoped and can not be replicated across namespaces: %s"
+	tooManyTargetsError               = "The targets exceed the limit: %d namespaces, %d allowed"
+	targetTooLargeError               = "The target is too large for namespace %s: %s"
+	targetTooLargeMessage             = "The target has %d bytes, exceeding the maximum size of %d bytes"
+	auditTargetsError                 = "Can not audit the targets of the Replika %s: %s"
+	celExpressionError                = "Can not evaluate the expression of the target namespaces: %s"
+	readyWhenError                    = "Can not evaluate the readiness expression of the source: %s"
+	sourceNotReadyError               = "The source %s/%s is not ready yet"
+	mergedSourceKindError             = "The source %s/%s must have the same group and kind as spec.source to be merged"
+	mergeConflictError                = "The sources define %d keys with different values"
+	aggregationSelectorError          = "The selector of the aggregation is invalid: %s"
+	aggregationEmptyError             = "No source %s was found in the aggregated namespaces"
+	canaryNotTargetError              = "The canary namespace is not one of the targets: %s"
+	canaryMismatchError               = "The target written in the canary namespace %s does not match the source"
+	canaryJobFailedError              = "The verification Job %s failed in the canary namespace %s"
+	hookJobFailedError                = "The %s hook Job %s/%s failed"
+	hookServiceAccountError           = "The ServiceAccount %s/%s running the %s hook does not exist"
+	hookJobsDisabledError             = "The hook Jobs are disabled in the operator, the %s hook of %s/%s can not run"
+	httpHookError                     = "The %s HTTP hook %s failed: %s"
+	httpHookStatusError               = "The HTTP hook %s returned the status %d"
+	httpHookTimeoutError              = "Can not parse the timeout of the HTTP hook %s: %s"
+	httpHookHostError                 = "The host of the HTTP hook %s is not allowed by the operator"
+	deletionPreviewError              = "Can not preview the deletion of the targets of the Replika %s: %s"
+	targetNotCreatedError             = "The object %s/%s is labeled as a target but was not created by the controller, it is not deleted"
+	auditLogError                     = "Can not write the audit log: %s"
+	targetAdmissionDeniedError        = "The target was denied by an admission policy in namespace %s: %s"
+	targetAdmissionFailedError        = "An admission webhook failed to process the target in namespace %s: %s"
+	synchronizationWindowError        = "The synchronization window of the Replika %s is invalid: %s"
+	inlineSourceError                 = "Can not decode the inline source: %s"
+	targetConflictError               = "The target conflicts with an object not written by the controller in namespace %s: %s"
+	targetImmutableError              = "The target is immutable and differs from the source in namespace %s: %s"
+	requiredResourceSelectorError     = "The selector of the required resource is invalid: %s"
+	unconsumedTargetsPruneError       = "Can not delete the unconsumed targets of the Replika %s: %s"
+	inventoryPruneError               = "Can not prune the targets of the Replika %s: %s"
+	outputOverwritesSourceError       = "The output %s %s would overwrite the source, it needs another name"
+	outputKindNotAllowedError         = "The kind of the output is not allowed by the operator configuration: %s"
+	lookupError                       = "Can not look up the values of the target in namespace %s: %s"
+	lookupKeyNotFoundMessage          = "the key %s is not found in the ConfigMap %s/%s"
+	deletedNamespacesPruneError       = "Can not remove the deleted namespaces from the status of the Replika %s: %s"
+	targetNamespaceTerminating        = "The target namespace %s is being deleted, it is skipped: %s"
+	namespaceRefreshError             = "Can not check whether the refreshed namespace %s is targeted by the Replika %s: %s"
+	namespaceOrderError               = "The pattern %s of the order of the namespaces is invalid: %s"
+	sourceInvalidError                = "The source failed its sanity checks: %s"
+	certificateExpiryError            = "Can not check the expiry of the certificate of the Replika %s: %s"
+	targetQuotaExceededError          = "The target exceeds a resource quota of namespace %s: %s"
+	targetBackoffMessage              = "The namespace is not written until %s: %s"
+	sourceMissingError                = "The source of the Replika %s is missing, its targets are handled by the %s policy"
+	deletionGracePeriodError          = "Can not parse the deletion grace period of the source: %s"
+
+	// Info messages
+	workloadReloaded        = "Reloaded %s %s/%s consuming a replicated target"
+	auditDriftDetected      = "Audit of the Replika %s found %d drifted and %d missing targets"
+	hookJobCreated          = "Created the %s hook Job %s/%s"
+	syncDeferred            = "Synchronization deferred by the window until %s"
+	unconsumedTargetDeleted = "Deleted the target in namespace %s, it has no consumers"
+	deletedNamespacePruned  = "Removed the namespace %s from the status, it was deleted"
+	namespaceRefreshed      = "The namespace %s requested a refresh, synchronizing the Replika %s"
+	inventoryTargetPruned   = "Pruned the target %s %s/%s, it is not computed from the Replika anymore"
+	staleTargetPruned       = "Pruned the target in namespace %s, it was written by the generation %s of the Replika"
+	targetRecreated         = "Recreated the target %s %s/%s, its immutable fields differ from the source"
+	anchorPruned            = "Pruned the anchor %s/%s, its namespace is not a target anymore"
+
+	// Events
+	targetDriftEvent            = "The target in namespace %s was modified by %s (%s) at %s"
+	targetDriftUnknownEvent     = "The target in namespace %s was modified outside of the controller"
+	targetWriteEventReason      = "Target"
+	targetWriteEvent            = "Replika %s/%s %s the object from %s at revision %s"
+	targetDeletedEventReason    = "TargetDeleted"
+	targetDeletedEvent          = "The target in namespace %s was deleted, %d remaining"
+	targetNotCreatedEventReason = "TargetNotCreated"
+	targetPrunedEventReason     = "TargetPruned"
+
+	// Appended to a repeated message once the deduplication interval is over
+	logSuppressedSummary = "%s (still failing, %d occurrences suppressed)"
 )
 
 // NewErrorf return an error with the message already formatted from parameters
@@ -31,3 +113,80 @@ func LogErrorf(ctx context.Context, err error, message string, params ...interfa
 	message = fmt.Sprintf(message, params...)
 	log.FromContext(ctx).Error(err, message)
 }
+
+// logEntry defines the state of a message already logged
+type logEntry struct {
+	lastLogged time.Time
+	suppressed int
+}
+
+// LogDeduplicator suppresses identical messages logged within an interval, summarizing them on the next one
+type LogDeduplicator struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	entries  map[string]*logEntry
+}
+
+// errorLogs deduplicates the messages of the errors repeated on every synchronization
+var errorLogs = NewLogDeduplicator(5 * time.Minute)
+
+// NewLogDeduplicator return a LogDeduplicator for the interval. Zero disables the deduplication
+func NewLogDeduplicator(interval time.Duration) *LogDeduplicator {
+	return &LogDeduplicator{
+		interval: interval,
+		entries:  map[string]*logEntry{},
+	}
+}
+
+// SetLogDeduplicationInterval change the interval used to deduplicate the messages of the errors
+func SetLogDeduplicationInterval(interval time.Duration) {
+	errorLogs.mutex.Lock()
+	defer errorLogs.mutex.Unlock()
+	errorLogs.interval = interval
+}
+
+// Allow return true when the message must be logged, and the number of occurrences suppressed since the last time
+func (d *LogDeduplicator) Allow(message string) (allowed bool, suppressed int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.interval <= 0 {
+		return true, 0
+	}
+
+	now := time.Now()
+	entry, found := d.entries[message]
+	if found && now.Sub(entry.lastLogged) < d.interval {
+		entry.suppressed++
+		return false, 0
+	}
+
+	// Forget the messages not repeated for a while, so the entries do not grow forever
+	for k, v := range d.entries {
+		if now.Sub(v.lastLogged) >= 2*d.interval {
+			delete(d.entries, k)
+		}
+	}
+
+	if found {
+		suppressed = entry.suppressed
+	}
+	d.entries[message] = &logEntry{lastLogged: now}
+	return true, suppressed
+}
+
+// LogErrorDedupf log the message like LogInfof, suppressing the identical ones until the deduplication interval is over
+func LogErrorDedupf(ctx context.Context, message string, params ...interface{}) {
+	formatted := fmt.Sprintf(message, params...)
+
+	allowed, suppressed := errorLogs.Allow(formatted)
+	if !allowed {
+		suppressedLogs.WithLabelValues(message).Inc()
+		return
+	}
+
+	if suppressed > 0 {
+		formatted = fmt.Sprintf(logSuppressedSummary, formatted, suppressed)
+	}
+	log.FromContext(ctx).Info(formatted)
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLogDeduplicator(t *testing.T) {
+	deduplicator := NewLogDeduplicator(time.Minute)
+
+	if allowed, suppressed := deduplicator.Allow("failed"); !allowed || suppressed != 0 {
+		t.Fatalf("expected the first message logged, got %t with %d suppressed", allowed, suppressed)
+	}
+	for i := 0; i < 3; i++ {
+		if allowed, _ := deduplicator.Allow("failed"); allowed {
+			t.Fatalf("expected the repeated message suppressed")
+		}
+	}
+	if allowed, _ := deduplicator.Allow("other"); !allowed {
+		t.Errorf("expected another message logged")
+	}
+
+	// Once the interval is over, the message is logged with the occurrences suppressed meanwhile
+	deduplicator.entries["failed"].lastLogged = time.Now().Add(-time.Minute)
+	if allowed, suppressed := deduplicator.Allow("failed"); !allowed || suppressed != 3 {
+		t.Errorf("expected the message logged with 3 suppressed, got %t with %d", allowed, suppressed)
+	}
+}
+
+func TestLogDeduplicatorForget(t *testing.T) {
+	deduplicator := NewLogDeduplicator(time.Minute)
+	deduplicator.Allow("old")
+	deduplicator.entries["old"].lastLogged = time.Now().Add(-2 * time.Minute)
+
+	// The messages not repeated for a while are forgotten
+	deduplicator.Allow("new")
+	if _, found := deduplicator.entries["old"]; found {
+		t.Errorf("expected the old message forgotten")
+	}
+}
+
+func TestLogDeduplicatorDisabled(t *testing.T) {
+	deduplicator := NewLogDeduplicator(0)
+	for i := 0; i < 2; i++ {
+		if allowed, _ := deduplicator.Allow("failed"); !allowed {
+			t.Errorf("expected every message logged when disabled")
+		}
+	}
+}
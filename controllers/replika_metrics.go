@@ -0,0 +1,397 @@
+package controllers
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"prosimcorp.com/replika/pkg/replicator"
+)
+
+const (
+	// States of the targets reported by the integrity audit
+	integrityStateSynced  = "synced"
+	integrityStateDrifted = "drifted"
+	integrityStateMissing = "missing"
+
+	// Outcomes of the writes of the targets. The skipped writes found the target unchanged
+	writeOutcomeAttempted = "attempted"
+	writeOutcomeSkipped   = "skipped"
+	writeOutcomePerformed = "performed"
+)
+
+var (
+	// resyncDuration measures how long it takes to reconcile all the Replikas after acquiring the leadership
+	resyncDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "replika_leader_resync_duration_seconds",
+		Help:    "Time spent reconciling every Replika after acquiring the leadership",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+	})
+
+	// oversizedTargets counts the targets of each Replika discarded for exceeding the maximum object size
+	oversizedTargets = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "replika_oversized_targets",
+		Help: "Targets of a Replika discarded for exceeding the maximum object size",
+	}, []string{"namespace", "name"})
+
+	// noTargetNamespaces flags the Replikas whose target matched no namespace on the last synchronization
+	noTargetNamespaces = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "replika_no_target_namespaces",
+		Help: "Whether the target of a Replika matched no namespace on the last synchronization",
+	}, []string{"namespace", "name"})
+
+	// queueWaitSeconds measures how long each Replika waited for a free worker before its last reconciliation
+	queueWaitSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "replika_queue_wait_seconds",
+		Help: "Time a Replika waited in the queue for a free worker before its last reconciliation",
+	}, []string{"namespace", "name"})
+
+	// reconcileDurationSeconds measures how long the last reconciliation of each Replika took
+	reconcileDurationSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "replika_reconcile_duration_seconds",
+		Help: "Duration of the last reconciliation of a Replika",
+	}, []string{"namespace", "name"})
+
+	// integrityTargets counts the targets of each Replika by state on the last integrity audit
+	integrityTargets = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "replika_integrity_targets",
+		Help: "Targets of a Replika by state on the last integrity audit",
+	}, []string{"namespace", "name", "state"})
+
+	// targetWriteErrors counts the failed writes of the targets of each Replika
+	targetWriteErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "replika_target_write_errors_total",
+		Help: "Failed writes of the targets of a Replika",
+	}, []string{"namespace", "name", "target_namespace"})
+
+	// targetWrites counts the writes of the targets of each Replika by outcome
+	targetWrites = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "replika_target_writes_total",
+		Help: "Writes of the targets of a Replika attempted, skipped as unchanged and performed",
+	}, []string{"namespace", "name", "outcome"})
+
+	// targetWrittenBytes counts the bytes of the targets of each Replika written to the API server
+	targetWrittenBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "replika_target_written_bytes_total",
+		Help: "Bytes of the targets of a Replika written to the API server",
+	}, []string{"namespace", "name"})
+
+	// certificateExpiryDays exposes the days left until the certificate of the source of each Replika expires
+	certificateExpiryDays = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "replika_certificate_expiry_days",
+		Help: "Days left until the certificate of the TLS source of a Replika expires",
+	}, []string{"namespace", "name"})
+
+	// conditionTransitions counts the changes of status of the conditions of each Replika
+	conditionTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "replika_condition_transitions_total",
+		Help: "Changes of status of a condition of a Replika",
+	}, []string{"namespace", "name", "type", "status"})
+
+	// conditionStates exposes the conditions of each Replika the same way as kube-state-metrics, one series per status
+	// valued 1 for the current one, so the alerting rules can be written without a custom resource state configuration
+	conditionStates = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "replika_condition",
+		Help: "Conditions of a Replika, 1 for the current status of each type and 0 for the rest of them",
+	}, []string{"namespace", "name", "type", "status", "reason"})
+
+	// cacheObjects counts the objects held by each informer of the controller
+	cacheObjects = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "replika_cache_objects",
+		Help: "Objects held by an informer of the controller",
+	}, []string{"informer"})
+
+	// cacheEstimatedBytes estimates the memory used by the objects held by each informer of the controller
+	cacheEstimatedBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "replika_cache_estimated_bytes",
+		Help: "Estimated size of the objects held by an informer of the controller, as their serialized size",
+	}, []string{"informer"})
+
+	// scheduledReplikas counts the Replikas registered on the scheduler by synchronization interval
+	scheduledReplikas = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "replika_scheduled_replikas",
+		Help: "Replikas registered on the synchronization scheduler by interval",
+	}, []string{"interval"})
+
+	// activeSourceWatches counts the kinds of sources being watched
+	activeSourceWatches = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "replika_active_source_watches",
+		Help: "Kinds of sources watched for changes, one informer each",
+	})
+
+	// droppedAuditRecords counts the records of the audit log dropped as the sink could not keep up
+	droppedAuditRecords = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "replika_audit_log_dropped_records_total",
+		Help: "Records of the audit log dropped as its buffer was full",
+	})
+
+	// suppressedLogs counts the repeated error messages not logged, by message template
+	suppressedLogs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "replika_suppressed_log_messages_total",
+		Help: "Repeated error messages suppressed from the logs",
+	}, []string{"message"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		resyncDuration,
+		oversizedTargets,
+		noTargetNamespaces,
+		queueWaitSeconds,
+		reconcileDurationSeconds,
+		integrityTargets,
+		suppressedLogs,
+		targetWriteErrors,
+		targetWrites,
+		targetWrittenBytes,
+		certificateExpiryDays,
+		conditionTransitions,
+		conditionStates,
+		cacheObjects,
+		cacheEstimatedBytes,
+		scheduledReplikas,
+		activeSourceWatches,
+		droppedAuditRecords,
+	)
+}
+
+// MetricsOptions defines the granularity of the metrics, so large installations can control their cardinality
+type MetricsOptions struct {
+	// PerReplika labels the series with the namespace and name of each Replika.
+	// When disabled, the series of all the Replikas are aggregated
+	PerReplika bool
+
+	// TargetNamespaces labels the series with the namespace of each target
+	TargetNamespaces bool
+}
+
+var (
+	metricsOptions = MetricsOptions{PerReplika: true}
+
+	// aggregatedGauges keeps the value of each Replika, by gauge and the rest of the labels,
+	// to expose their sum when the metrics are not labeled per Replika
+	aggregatedGaugesMutex sync.Mutex
+	aggregatedGauges      = map[*prometheus.GaugeVec]map[string]map[types.NamespacedName]float64{}
+
+	// conditionStateReasons keeps the reason labeling the series of each condition of each Replika,
+	// to delete them when the reason changes
+	conditionStatesMutex  sync.Mutex
+	conditionStateReasons = map[types.NamespacedName]map[string]string{}
+
+	// targetWriteErrorsSeries keeps the target namespaces labeling the series of each Replika
+	targetWriteErrorsMutex  sync.Mutex
+	targetWriteErrorsSeries = map[types.NamespacedName]map[string]bool{}
+)
+
+// SetMetricsOptions change the granularity of the metrics. It must be called before any series is recorded
+func SetMetricsOptions(options MetricsOptions) {
+	metricsOptions = options
+}
+
+// replikaLabelValues return the values of the labels identifying a Replika, empty when aggregated
+func replikaLabelValues(namespace, name string) []string {
+	if !metricsOptions.PerReplika {
+		return []string{"", ""}
+	}
+	return []string{namespace, name}
+}
+
+// targetNamespaceLabelValue return the value of the label identifying the namespace of a target, empty when disabled
+func targetNamespaceLabelValue(namespace string) string {
+	if !metricsOptions.TargetNamespaces {
+		return ""
+	}
+	return namespace
+}
+
+// setReplikaGauge set the value of a gauge for a Replika. When aggregated, the sum of all of them is exposed
+func setReplikaGauge(gauge *prometheus.GaugeVec, namespace, name string, value float64, labelValues ...string) {
+	if metricsOptions.PerReplika {
+		gauge.WithLabelValues(append([]string{namespace, name}, labelValues...)...).Set(value)
+		return
+	}
+
+	aggregatedGaugesMutex.Lock()
+	defer aggregatedGaugesMutex.Unlock()
+
+	series := strings.Join(labelValues, "/")
+	if aggregatedGauges[gauge] == nil {
+		aggregatedGauges[gauge] = map[string]map[types.NamespacedName]float64{}
+	}
+	if aggregatedGauges[gauge][series] == nil {
+		aggregatedGauges[gauge][series] = map[types.NamespacedName]float64{}
+	}
+	aggregatedGauges[gauge][series][types.NamespacedName{Namespace: namespace, Name: name}] = value
+
+	sum := 0.0
+	for _, v := range aggregatedGauges[gauge][series] {
+		sum += v
+	}
+	gauge.WithLabelValues(append([]string{"", ""}, labelValues...)...).Set(sum)
+}
+
+// deleteReplikaGauge remove the value of a gauge for a Replika
+func deleteReplikaGauge(gauge *prometheus.GaugeVec, namespace, name string, labelValues ...string) {
+	if metricsOptions.PerReplika {
+		gauge.DeleteLabelValues(append([]string{namespace, name}, labelValues...)...)
+		return
+	}
+
+	aggregatedGaugesMutex.Lock()
+	series := strings.Join(labelValues, "/")
+	_, found := aggregatedGauges[gauge][series][types.NamespacedName{Namespace: namespace, Name: name}]
+	aggregatedGaugesMutex.Unlock()
+
+	// Setting it to zero removes its contribution to the sum
+	if found {
+		setReplikaGauge(gauge, namespace, name, 0, labelValues...)
+		aggregatedGaugesMutex.Lock()
+		delete(aggregatedGauges[gauge][series], types.NamespacedName{Namespace: namespace, Name: name})
+		aggregatedGaugesMutex.Unlock()
+	}
+}
+
+// DeleteReplikaMetrics remove the series of a Replika that no longer exists
+func DeleteReplikaMetrics(namespace, name string) {
+	deleteReplikaGauge(oversizedTargets, namespace, name)
+	deleteReplikaGauge(noTargetNamespaces, namespace, name)
+	deleteReplikaGauge(queueWaitSeconds, namespace, name)
+	deleteReplikaGauge(reconcileDurationSeconds, namespace, name)
+	deleteConditionStates(namespace, name)
+	for _, state := range []string{integrityStateSynced, integrityStateDrifted, integrityStateMissing} {
+		deleteReplikaGauge(integrityTargets, namespace, name, state)
+	}
+
+	if !metricsOptions.PerReplika {
+		return
+	}
+	for _, outcome := range []string{writeOutcomeAttempted, writeOutcomeSkipped, writeOutcomePerformed} {
+		targetWrites.DeleteLabelValues(namespace, name, outcome)
+	}
+	targetWrittenBytes.DeleteLabelValues(namespace, name)
+	certificateExpiryDays.DeleteLabelValues(namespace, name)
+	for _, condType := range []string{ConditionTypeSourceSynced, ConditionTypeReady, ConditionTypeCertificateExpiringSoon} {
+		for _, status := range conditionStatuses {
+			conditionTransitions.DeleteLabelValues(namespace, name, condType, string(status))
+		}
+	}
+
+	targetWriteErrorsMutex.Lock()
+	defer targetWriteErrorsMutex.Unlock()
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	for targetNamespace := range targetWriteErrorsSeries[key] {
+		targetWriteErrors.DeleteLabelValues(namespace, name, targetNamespace)
+	}
+	delete(targetWriteErrorsSeries, key)
+}
+
+// incTargetWriteErrors account a failed write of a target of a Replika
+func incTargetWriteErrors(namespace, name, targetNamespace string) {
+	labelValues := append(replikaLabelValues(namespace, name), targetNamespaceLabelValue(targetNamespace))
+	targetWriteErrors.WithLabelValues(labelValues...).Inc()
+
+	// Remember the series of each Replika to delete them with it
+	targetWriteErrorsMutex.Lock()
+	defer targetWriteErrorsMutex.Unlock()
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	if targetWriteErrorsSeries[key] == nil {
+		targetWriteErrorsSeries[key] = map[string]bool{}
+	}
+	targetWriteErrorsSeries[key][labelValues[2]] = true
+}
+
+// observeTargetWrite account the write of a target of a Replika by outcome. The bytes are the size of the
+// whole target, as the API server stores the complete object on each change
+func observeTargetWrite(namespace, name string, target *unstructured.Unstructured, result replicator.Result, err error) {
+	labelValues := replikaLabelValues(namespace, name)
+	targetWrites.WithLabelValues(append(labelValues, writeOutcomeAttempted)...).Inc()
+	if err != nil {
+		return
+	}
+
+	if result == replicator.ResultUnchanged {
+		targetWrites.WithLabelValues(append(labelValues, writeOutcomeSkipped)...).Inc()
+		return
+	}
+	targetWrites.WithLabelValues(append(labelValues, writeOutcomePerformed)...).Inc()
+
+	targetJSON, err := target.MarshalJSON()
+	if err == nil {
+		targetWrittenBytes.WithLabelValues(labelValues...).Add(float64(len(targetJSON)))
+	}
+}
+
+// setCertificateExpiryDays set the days left until the certificate of the source of a Replika expires.
+// It is only exported when the series are labeled per Replika, as the days of several certificates can not be added
+func setCertificateExpiryDays(namespace, name string, days float64) {
+	if !metricsOptions.PerReplika {
+		return
+	}
+	certificateExpiryDays.WithLabelValues(namespace, name).Set(days)
+}
+
+// observeConditionTransition account a change of status of a condition of a Replika
+func observeConditionTransition(namespace, name, condType string, status metav1.ConditionStatus) {
+	labelValues := append(replikaLabelValues(namespace, name), condType, string(status))
+	conditionTransitions.WithLabelValues(labelValues...).Inc()
+}
+
+// setNoTargetNamespaces flag whether the target of a Replika matched no namespace.
+// When aggregated, the number of Replikas matching no namespace is exposed
+func setNoTargetNamespaces(namespace, name string, noTargets bool) {
+	value := 0.0
+	if noTargets {
+		value = 1
+	}
+	setReplikaGauge(noTargetNamespaces, namespace, name, value)
+}
+
+// conditionStatuses are the statuses exposed for each condition
+var conditionStatuses = []metav1.ConditionStatus{metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionUnknown}
+
+// setConditionState expose the current status and reason of a condition of a Replika.
+// When aggregated, the number of Replikas on each status and reason is exposed
+func setConditionState(namespace, name, condType string, status metav1.ConditionStatus, reason string) {
+	conditionStatesMutex.Lock()
+	defer conditionStatesMutex.Unlock()
+
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	if conditionStateReasons[key] == nil {
+		conditionStateReasons[key] = map[string]string{}
+	}
+
+	// The series of the previous reason are replaced
+	if previous, found := conditionStateReasons[key][condType]; found && previous != reason {
+		for _, s := range conditionStatuses {
+			deleteReplikaGauge(conditionStates, namespace, name, condType, string(s), previous)
+		}
+	}
+	conditionStateReasons[key][condType] = reason
+
+	for _, s := range conditionStatuses {
+		value := 0.0
+		if s == status {
+			value = 1
+		}
+		setReplikaGauge(conditionStates, namespace, name, value, condType, string(s), reason)
+	}
+}
+
+// deleteConditionStates remove the series of the conditions of a Replika
+func deleteConditionStates(namespace, name string) {
+	conditionStatesMutex.Lock()
+	defer conditionStatesMutex.Unlock()
+
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	for condType, reason := range conditionStateReasons[key] {
+		for _, s := range conditionStatuses {
+			deleteReplikaGauge(conditionStates, namespace, name, condType, string(s), reason)
+		}
+	}
+	delete(conditionStateReasons, key)
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+const (
+	migrationListError     = "Can not list the Replikas from %s"
+	migrationConvertError  = "Can not convert the Replika %s/%s from %s"
+	migrationCreateError   = "Can not create the migrated Replika %s/%s"
+	migrationAdoptError    = "Can not adopt the targets of the Replika %s/%s"
+	migrationReleaseError  = "Can not remove the finalizer of the old Replika %s/%s"
+	migrationReplikaExists = "Replika %s/%s already exists on the current version, skipping its copy"
+	migrationReplikaDone   = "Replika %s/%s migrated from %s"
+)
+
+// MigrateReplikas copy the Replikas from an old group/version into the current one and adopt their targets.
+// The finalizer of the old resources is removed, so they can be deleted without deleting the targets
+func MigrateReplikas(ctx context.Context, c client.Client, from schema.GroupVersion) (err error) {
+
+	oldReplikas := &unstructured.UnstructuredList{}
+	oldReplikas.SetGroupVersionKind(from.WithKind("Replika"))
+	err = c.List(ctx, oldReplikas)
+	if err != nil {
+		LogInfof(ctx, migrationListError, from.String())
+		return err
+	}
+
+	for i := range oldReplikas.Items {
+		oldReplika := &oldReplikas.Items[i]
+
+		// The spec of the old versions is a subset of the current one
+		replika := &replikav1beta1.Replika{}
+		err = runtime.DefaultUnstructuredConverter.FromUnstructured(oldReplika.Object, replika)
+		if err != nil {
+			LogInfof(ctx, migrationConvertError, oldReplika.GetNamespace(), oldReplika.GetName(), from.String())
+			return err
+		}
+
+		replika.TypeMeta = metav1.TypeMeta{}
+		replika.ObjectMeta = metav1.ObjectMeta{
+			Name:        oldReplika.GetName(),
+			Namespace:   oldReplika.GetNamespace(),
+			Labels:      oldReplika.GetLabels(),
+			Annotations: oldReplika.GetAnnotations(),
+		}
+		replika.Status = replikav1beta1.ReplikaStatus{}
+
+		err = c.Create(ctx, replika)
+		if apierrors.IsAlreadyExists(err) {
+			LogInfof(ctx, migrationReplikaExists, replika.Namespace, replika.Name)
+			err = c.Get(ctx, client.ObjectKeyFromObject(replika), replika)
+		}
+		if err != nil {
+			LogInfof(ctx, migrationCreateError, replika.Namespace, replika.Name)
+			return err
+		}
+
+		err = AdoptTargets(ctx, c, replika, oldReplika.GetUID())
+		if err != nil {
+			LogInfof(ctx, migrationAdoptError, replika.Namespace, replika.Name)
+			return err
+		}
+
+		// Release the old resource. Versions of the same group share the storage, so nothing to release
+		if from.Group != replikav1beta1.GroupVersion.Group && controllerutil.ContainsFinalizer(oldReplika, replikaFinalizer) {
+			controllerutil.RemoveFinalizer(oldReplika, replikaFinalizer)
+			err = c.Update(ctx, oldReplika)
+			if err != nil {
+				LogInfof(ctx, migrationReleaseError, oldReplika.GetNamespace(), oldReplika.GetName())
+				return err
+			}
+		}
+
+		LogInfof(ctx, migrationReplikaDone, replika.Namespace, replika.Name, from.String())
+	}
+
+	return err
+}
+
+// AdoptTargets label the existing targets of a Replika, so they are managed by the current controller.
+// Only the objects annotated as written by the old Replika, or by the current one, are adopted, as the
+// part-of label does not tell apart the Replikas with the same name in different namespaces
+func AdoptTargets(ctx context.Context, c client.Client, replika *replikav1beta1.Replika, oldUID types.UID) (err error) {
+
+	targets := &unstructured.UnstructuredList{}
+	targets.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   replika.Spec.Source.Group,
+		Kind:    replika.Spec.Source.Kind,
+		Version: replika.Spec.Source.Version,
+	})
+
+	err = c.List(ctx, targets, client.MatchingLabels{resourceReplikaLabelPartOfKey: replika.Name})
+	if err != nil {
+		return err
+	}
+
+	for i := range targets.Items {
+		labels := targets.Items[i].GetLabels()
+		if labels[resourceReplikaLabelCreatedKey] == resourceReplikaLabelCreatedValue {
+			continue
+		}
+
+		writtenBy := types.UID(targets.Items[i].GetAnnotations()[targetReplikaUIDAnnotation])
+		if writtenBy == "" || (writtenBy != oldUID && writtenBy != replika.UID) {
+			continue
+		}
+
+		labels[resourceReplikaLabelCreatedKey] = resourceReplikaLabelCreatedValue
+		labels[resourceReplikaLabelPartOfNamespaceKey] = replika.Namespace
+		targets.Items[i].SetLabels(labels)
+		err = c.Update(ctx, &targets.Items[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+const (
+	// Annotation of the namespaces requesting the synchronization of every Replika targeting them when changed
+	namespaceRefreshAnnotation = "replika.prosimcorp.com/refresh"
+)
+
+// namespaceRefreshPredicate filter the changes of the refresh annotation of the namespaces
+var namespaceRefreshPredicate = predicate.Funcs{
+	CreateFunc: func(event.CreateEvent) bool { return false },
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return e.ObjectNew.GetAnnotations()[namespaceRefreshAnnotation] != "" &&
+			e.ObjectOld.GetAnnotations()[namespaceRefreshAnnotation] != e.ObjectNew.GetAnnotations()[namespaceRefreshAnnotation]
+	},
+	DeleteFunc:  func(event.DeleteEvent) bool { return false },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+}
+
+// namespaceDeletionPredicate filter the events of the namespaces being deleted or already deleted
+var namespaceDeletionPredicate = predicate.Funcs{
+	CreateFunc: func(event.CreateEvent) bool { return false },
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return e.ObjectOld.GetDeletionTimestamp() == nil && e.ObjectNew.GetDeletionTimestamp() != nil
+	},
+	DeleteFunc:  func(event.DeleteEvent) bool { return true },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+}
+
+// NamespaceEventHandler return the handler of the namespace events, enqueueing the Replikas referencing
+// the namespaces being deleted and the ones targeting the namespaces requesting a refresh
+func (r *ReplikaReconciler) NamespaceEventHandler() handler.EventHandler {
+
+	deleted := handler.EnqueueRequestsFromMapFunc(r.Queue.MapFunc(r.MapNamespaceToReplikas))
+	refreshed := handler.EnqueueRequestsFromMapFunc(r.Queue.MapFunc(r.MapRefreshedNamespaceToReplikas))
+
+	return handler.Funcs{
+		UpdateFunc: func(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+			if namespaceDeletionPredicate.Update(e) {
+				deleted.Update(e, q)
+			}
+			if namespaceRefreshPredicate.Update(e) {
+				refreshed.Update(e, q)
+			}
+		},
+		DeleteFunc: func(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+			deleted.Delete(e, q)
+		},
+	}
+}
+
+// MapNamespaceToReplikas return the Replikas whose status references the namespace, so a deleted
+// namespace is removed from their status without waiting for the next synchronization
+func (r *ReplikaReconciler) MapNamespaceToReplikas(object client.Object) (requests []reconcile.Request) {
+
+	replikaList := &replikav1beta1.ReplikaList{}
+	err := r.List(context.Background(), replikaList)
+	if err != nil {
+		return requests
+	}
+
+	for i := range replikaList.Items {
+		if GetStatusNamespaces(&replikaList.Items[i])[object.GetName()] {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: replikaList.Items[i].Namespace, Name: replikaList.Items[i].Name},
+			})
+		}
+	}
+
+	return requests
+}
+
+// MapRefreshedNamespaceToReplikas return the Replikas targeting the namespace, so a wiped
+// or rebuilt namespace gets all its targets back in one step
+func (r *ReplikaReconciler) MapRefreshedNamespaceToReplikas(object client.Object) (requests []reconcile.Request) {
+	ctx := context.Background()
+
+	replikaList := &replikav1beta1.ReplikaList{}
+	err := r.List(ctx, replikaList)
+	if err != nil {
+		return requests
+	}
+
+	for i := range replikaList.Items {
+		replika := &replikaList.Items[i]
+		replika.SetSourceDefaults()
+
+		var namespaces []string
+		namespaces, err = r.GetNamespaces(ctx, replika)
+		if err != nil {
+			LogErrorDedupf(ctx, namespaceRefreshError, object.GetName(), replika.Name, err.Error())
+			continue
+		}
+
+		for _, namespace := range namespaces {
+			if namespace == object.GetName() {
+				LogInfof(ctx, namespaceRefreshed, object.GetName(), replika.Name)
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Namespace: replika.Namespace, Name: replika.Name},
+				})
+				break
+			}
+		}
+	}
+
+	return requests
+}
+
+// PruneDeletedNamespaces remove from the status of the Replika the results of the target namespaces
+// deleted or being deleted, so they are not reported as failed forever
+func (r *ReplikaReconciler) PruneDeletedNamespaces(ctx context.Context, replika *replikav1beta1.Replika) (err error) {
+
+	for namespace := range GetStatusNamespaces(replika) {
+		namespaceObject := &corev1.Namespace{}
+		err = r.Get(ctx, types.NamespacedName{Name: namespace}, namespaceObject)
+		if client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		if err == nil && namespaceObject.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		err = nil
+		if RemoveNamespaceResults(replika, namespace) {
+			LogInfof(ctx, deletedNamespacePruned, namespace)
+		}
+	}
+
+	return err
+}
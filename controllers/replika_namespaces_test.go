@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+// newNamespacesReplika return a Replika whose status references team-a, team-b and team-c
+func newNamespacesReplika() *replikav1beta1.Replika {
+	replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-config"}}
+	replika.Status.SyncedNamespaces = []string{"team-a", "team-b"}
+	replika.Status.SyncedTargets = 2
+	replika.Status.FailedNamespaces = []string{"team-c"}
+	replika.Status.FailedTargets = 1
+	replika.Status.TotalTargets = 3
+	replika.Status.RejectedNamespaces = []replikav1beta1.ReplikaNamespaceStatus{{Namespace: "team-c"}}
+	replika.Status.Consumers = []replikav1beta1.ReplikaConsumerStatus{{Namespace: "team-b", Kind: "Deployment", Name: "web"}}
+	replika.Status.Integrity = &replikav1beta1.ReplikaIntegrityStatus{
+		MissingNamespaces: []string{"team-c"},
+		DriftedTargets:    []replikav1beta1.ReplikaDriftStatus{{Namespace: "team-b"}},
+	}
+	return replika
+}
+
+func TestRemoveNamespaceResults(t *testing.T) {
+	replika := newNamespacesReplika()
+
+	if !RemoveNamespaceResults(replika, "team-c") {
+		t.Fatalf("expected the status changed")
+	}
+	status := replika.Status
+	if len(status.FailedNamespaces) != 0 || status.FailedTargets != 0 || status.TotalTargets != 2 {
+		t.Errorf("expected the failure of team-c removed, got %+v", status)
+	}
+	if status.RejectedNamespaces != nil || len(status.Integrity.MissingNamespaces) != 0 {
+		t.Errorf("expected the rejection and the audit of team-c removed, got %+v", status)
+	}
+
+	RemoveNamespaceResults(replika, "team-b")
+	if len(replika.Status.SyncedNamespaces) != 1 || replika.Status.Consumers != nil || replika.Status.Integrity.DriftedTargets != nil {
+		t.Errorf("expected the results of team-b removed, got %+v", replika.Status)
+	}
+
+	if RemoveNamespaceResults(replika, "team-z") {
+		t.Errorf("expected the status unchanged for a namespace not referenced")
+	}
+}
+
+func TestGetStatusNamespaces(t *testing.T) {
+	namespaces := GetStatusNamespaces(newNamespacesReplika())
+	if len(namespaces) != 3 || !namespaces["team-a"] || !namespaces["team-b"] || !namespaces["team-c"] {
+		t.Errorf("expected team-a, team-b and team-c, got %v", namespaces)
+	}
+}
+
+func TestPruneDeletedNamespaces(t *testing.T) {
+	replika := newNamespacesReplika()
+
+	// team-a exists, team-b is being deleted and team-c is already deleted
+	deletionTime := metav1.Now()
+	r := &ReplikaReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b", DeletionTimestamp: &deletionTime, Finalizers: []string{"kubernetes"}}},
+	).Build()}
+
+	if err := r.PruneDeletedNamespaces(context.Background(), replika); err != nil {
+		t.Fatalf("unexpected error pruning the namespaces: %v", err)
+	}
+	namespaces := GetStatusNamespaces(replika)
+	if len(namespaces) != 1 || !namespaces["team-a"] {
+		t.Errorf("expected only team-a kept in the status, got %v", namespaces)
+	}
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/replicator"
+)
+
+// BuildOutputs return the outputs composed from the source for each namespace, with the labels of the rest of the targets.
+// An output with the kind and name of the source would overwrite it, so it is refused
+func (r *ReplikaReconciler) BuildOutputs(replika *replikav1beta1.Replika, source *unstructured.Unstructured,
+	namespaces []string, labels map[string]string) (targets []unstructured.Unstructured, err error) {
+
+	settings := r.settings()
+	for _, spec := range replika.Spec.Target.Outputs {
+		if !settings.IsKindAllowed("", spec.Kind) {
+			err = NewPermanentErrorf(outputKindNotAllowedError, spec.Kind)
+			return targets, err
+		}
+		if spec.Kind == source.GetKind() && spec.Name == source.GetName() {
+			err = NewPermanentErrorf(outputOverwritesSourceError, spec.Kind, spec.Name)
+			return targets, err
+		}
+
+		output := replicator.Output{Kind: spec.Kind, Name: spec.Name, Type: spec.Type}
+		for _, key := range spec.Keys {
+			output.Keys = append(output.Keys, replicator.OutputKey{From: key.From, To: key.To})
+		}
+
+		var composed *unstructured.Unstructured
+		composed, err = replicator.Compose(source, output)
+		if err != nil {
+			err = NewPermanentError(err)
+			return targets, err
+		}
+
+		targets = append(targets, r.replicator().BuildTargets(composed, namespaces, labels)...)
+	}
+
+	return targets, err
+}
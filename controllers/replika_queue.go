@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+// QueueTracker records when each Replika becomes ready to be reconciled, so the time it waits in the queue
+// for a free worker is known once its reconciliation starts. A nil QueueTracker records nothing
+type QueueTracker struct {
+	mutex   sync.Mutex
+	pending map[types.NamespacedName]time.Time
+}
+
+// NewQueueTracker return an empty QueueTracker
+func NewQueueTracker() *QueueTracker {
+	return &QueueTracker{
+		pending: map[types.NamespacedName]time.Time{},
+	}
+}
+
+// Enqueued record that a Replika is ready to be reconciled from the time on. The earliest time is kept
+// until it is reconciled, the same way as the queue merges the requests of a Replika into a single one
+func (q *QueueTracker) Enqueued(key types.NamespacedName, readyTime time.Time) {
+	if q == nil {
+		return
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if pendingTime, found := q.pending[key]; found && pendingTime.Before(readyTime) {
+		return
+	}
+	q.pending[key] = readyTime
+}
+
+// Dequeued return the time a Replika waited since it was ready to be reconciled, forgetting it.
+// False is returned when it is unknown, like for the retries after a failure
+func (q *QueueTracker) Dequeued(key types.NamespacedName, now time.Time) (wait time.Duration, found bool) {
+	if q == nil {
+		return wait, found
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	var readyTime time.Time
+	readyTime, found = q.pending[key]
+	if !found {
+		return wait, found
+	}
+	delete(q.pending, key)
+
+	// Requests reconciled before their requeue time, because of another event, did not wait
+	if now.After(readyTime) {
+		wait = now.Sub(readyTime)
+	}
+	return wait, found
+}
+
+// Forget remove a Replika from the tracker
+func (q *QueueTracker) Forget(key types.NamespacedName) {
+	if q == nil {
+		return
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	delete(q.pending, key)
+}
+
+// Predicate return a predicate marking the Replikas of the events as enqueued. It must be the last one
+// of its watch, so only the events passing the rest of the predicates are marked
+func (q *QueueTracker) Predicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(object client.Object) bool {
+		q.Enqueued(types.NamespacedName{Namespace: object.GetNamespace(), Name: object.GetName()}, time.Now())
+		return true
+	})
+}
+
+// MapFunc return the map function marking as enqueued the Replikas returned by another one
+func (q *QueueTracker) MapFunc(mapFunc handler.MapFunc) handler.MapFunc {
+	return func(object client.Object) (requests []reconcile.Request) {
+		requests = mapFunc(object)
+		for _, request := range requests {
+			q.Enqueued(request.NamespacedName, time.Now())
+		}
+		return requests
+	}
+}
+
+// SetReconcileStats record in the status and the metrics of the Replika the time it waited for a worker,
+// when known, and the duration of its reconciliation
+func SetReconcileStats(replika *replikav1beta1.Replika, queueWait time.Duration, queueWaitFound bool, duration time.Duration) {
+	if queueWaitFound {
+		replika.Status.QueueWait = &metav1.Duration{Duration: queueWait.Round(time.Millisecond)}
+		setReplikaGauge(queueWaitSeconds, replika.Namespace, replika.Name, queueWait.Seconds())
+	}
+	replika.Status.ReconcileDuration = &metav1.Duration{Duration: duration.Round(time.Millisecond)}
+	setReplikaGauge(reconcileDurationSeconds, replika.Namespace, replika.Name, duration.Seconds())
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+func TestQueueTracker(t *testing.T) {
+	key := types.NamespacedName{Namespace: "default", Name: "app-config"}
+	now := time.Now()
+	tracker := NewQueueTracker()
+
+	// The earliest time a Replika is ready is kept, as the queue merges its requests
+	tracker.Enqueued(key, now.Add(-3*time.Second))
+	tracker.Enqueued(key, now.Add(-time.Second))
+	wait, found := tracker.Dequeued(key, now)
+	if !found || wait != 3*time.Second {
+		t.Errorf("expected a wait of 3s, got %v (%t)", wait, found)
+	}
+
+	// Unknown once dequeued, like the retries after a failure
+	if _, found = tracker.Dequeued(key, now); found {
+		t.Errorf("expected the wait unknown once dequeued")
+	}
+
+	// A requeue reconciled earlier because of another event did not wait
+	tracker.Enqueued(key, now.Add(time.Minute))
+	if wait, found = tracker.Dequeued(key, now); !found || wait != 0 {
+		t.Errorf("expected no wait, got %v (%t)", wait, found)
+	}
+
+	tracker.Enqueued(key, now)
+	tracker.Forget(key)
+	if _, found = tracker.Dequeued(key, now); found {
+		t.Errorf("expected the Replika forgotten")
+	}
+
+	// A nil tracker records nothing
+	var disabled *QueueTracker
+	disabled.Enqueued(key, now)
+	if _, found = disabled.Dequeued(key, now); found {
+		t.Errorf("expected nothing recorded by a nil tracker")
+	}
+}
+
+func TestQueueTrackerMapFunc(t *testing.T) {
+	key := types.NamespacedName{Namespace: "default", Name: "app-config"}
+	tracker := NewQueueTracker()
+
+	mapFunc := tracker.MapFunc(func(client.Object) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: key}}
+	})
+	if requests := mapFunc(&replikav1beta1.Replika{}); len(requests) != 1 {
+		t.Fatalf("expected the requests of the map function returned, got %v", requests)
+	}
+	if _, found := tracker.Dequeued(key, time.Now()); !found {
+		t.Errorf("expected the mapped Replika marked as enqueued")
+	}
+}
+
+func TestSetReconcileStats(t *testing.T) {
+	replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-config"}}
+
+	SetReconcileStats(replika, 0, false, 1500*time.Microsecond)
+	if replika.Status.QueueWait != nil {
+		t.Errorf("expected the unknown wait not recorded, got %v", replika.Status.QueueWait)
+	}
+	if replika.Status.ReconcileDuration == nil || replika.Status.ReconcileDuration.Duration != 2*time.Millisecond {
+		t.Errorf("expected the duration rounded to 2ms, got %v", replika.Status.ReconcileDuration)
+	}
+
+	SetReconcileStats(replika, time.Second, true, time.Millisecond)
+	if replika.Status.QueueWait == nil || replika.Status.QueueWait.Duration != time.Second {
+		t.Errorf("expected a wait of 1s, got %v", replika.Status.QueueWait)
+	}
+}
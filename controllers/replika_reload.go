@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/auditlog"
+)
+
+const (
+	// Annotation set on the pod template of workloads consuming a replicated object.
+	// The name part is a hash of the consumed object to keep it inside the length limits
+	workloadChecksumAnnotationPrefix = "checksum.replika.prosimcorp.com/"
+
+	workloadChecksumPatch = `{"spec":{"template":{"metadata":{"annotations":{%q:%q}}}}}`
+)
+
+// IsReloadableTarget return true when the target is a ConfigMap or a Secret
+func IsReloadableTarget(target *unstructured.Unstructured) bool {
+	gvk := target.GroupVersionKind()
+	return gvk.Group == "" && (gvk.Kind == "ConfigMap" || gvk.Kind == "Secret")
+}
+
+// GetTargetChecksum return a hash of the content of a ConfigMap or a Secret
+func GetTargetChecksum(target *unstructured.Unstructured) (checksum string, err error) {
+
+	content := map[string]interface{}{}
+	for _, field := range []string{"data", "binaryData", "stringData"} {
+		if value, found := target.Object[field]; found {
+			content[field] = value
+		}
+	}
+
+	// Maps are marshalled with sorted keys, so the result is stable between loops
+	var contentJSON []byte
+	contentJSON, err = json.Marshal(content)
+	if err != nil {
+		return checksum, err
+	}
+
+	sum := sha256.Sum256(contentJSON)
+	checksum = hex.EncodeToString(sum[:])
+	return checksum, err
+}
+
+// GetWorkloadChecksumAnnotation return the annotation key used on workloads for a consumed target
+func GetWorkloadChecksumAnnotation(target *unstructured.Unstructured) string {
+	sum := sha256.Sum256([]byte(target.GetKind() + "/" + target.GetName()))
+	return workloadChecksumAnnotationPrefix + strings.ToLower(target.GetKind()) + "-" + hex.EncodeToString(sum[:])[:16]
+}
+
+// PodSpecReferencesTarget return true when the pod spec mounts or loads the environment from the target.
+// Only ConfigMaps and Secrets can be referenced, so it is always false for the rest of the kinds
+func PodSpecReferencesTarget(podSpec *corev1.PodSpec, target *unstructured.Unstructured) bool {
+
+	if !IsReloadableTarget(target) {
+		return false
+	}
+
+	name := target.GetName()
+	isConfigMap := target.GetKind() == "ConfigMap"
+
+	// Look into the volumes, including projected ones
+	for _, volume := range podSpec.Volumes {
+		if isConfigMap && volume.ConfigMap != nil && volume.ConfigMap.Name == name {
+			return true
+		}
+		if !isConfigMap && volume.Secret != nil && volume.Secret.SecretName == name {
+			return true
+		}
+		if volume.Projected == nil {
+			continue
+		}
+		for _, projection := range volume.Projected.Sources {
+			if isConfigMap && projection.ConfigMap != nil && projection.ConfigMap.Name == name {
+				return true
+			}
+			if !isConfigMap && projection.Secret != nil && projection.Secret.Name == name {
+				return true
+			}
+		}
+	}
+
+	// Look into the environment of all the containers
+	containers := append([]corev1.Container{}, podSpec.InitContainers...)
+	containers = append(containers, podSpec.Containers...)
+	for _, container := range containers {
+		for _, envFrom := range container.EnvFrom {
+			if isConfigMap && envFrom.ConfigMapRef != nil && envFrom.ConfigMapRef.Name == name {
+				return true
+			}
+			if !isConfigMap && envFrom.SecretRef != nil && envFrom.SecretRef.Name == name {
+				return true
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if isConfigMap && env.ValueFrom.ConfigMapKeyRef != nil && env.ValueFrom.ConfigMapKeyRef.Name == name {
+				return true
+			}
+			if !isConfigMap && env.ValueFrom.SecretKeyRef != nil && env.ValueFrom.SecretKeyRef.Name == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ReloadWorkloads patch the checksum of the target on the pod template of the Deployments and StatefulSets
+// consuming it. Workloads are only patched when the checksum changes, so the rollout happens once per change.
+// The targets named after their content are switched by name instead
+func (r *ReplikaReconciler) ReloadWorkloads(ctx context.Context, replika *replikav1beta1.Replika, target *unstructured.Unstructured) (err error) {
+
+	if !IsReloadableTarget(target) {
+		return err
+	}
+
+	if target.GetAnnotations()[targetBaseNameAnnotation] != "" {
+		return r.SwitchWorkloads(ctx, replika, target)
+	}
+
+	var checksum string
+	checksum, err = GetTargetChecksum(target)
+	if err != nil {
+		return err
+	}
+	annotation := GetWorkloadChecksumAnnotation(target)
+	patch := client.RawPatch(types.StrategicMergePatchType, []byte(fmt.Sprintf(workloadChecksumPatch, annotation, checksum)))
+
+	// Reload the Deployments
+	deployments := &appsv1.DeploymentList{}
+	err = r.List(ctx, deployments, client.InNamespace(target.GetNamespace()))
+	if err != nil {
+		return err
+	}
+
+	for i := range deployments.Items {
+		template := &deployments.Items[i].Spec.Template
+		if template.Annotations[annotation] == checksum || !PodSpecReferencesTarget(&template.Spec, target) {
+			continue
+		}
+
+		err = r.Patch(ctx, &deployments.Items[i], patch)
+		if err != nil {
+			return err
+		}
+		LogInfof(ctx, workloadReloaded, "Deployment", deployments.Items[i].Namespace, deployments.Items[i].Name)
+		r.RecordObjectAuditLog(ctx, replika, appsv1.SchemeGroupVersion.WithKind("Deployment"), &deployments.Items[i],
+			auditlog.ActionUpdate, auditReasonReload)
+	}
+
+	// Reload the StatefulSets
+	statefulSets := &appsv1.StatefulSetList{}
+	err = r.List(ctx, statefulSets, client.InNamespace(target.GetNamespace()))
+	if err != nil {
+		return err
+	}
+
+	for i := range statefulSets.Items {
+		template := &statefulSets.Items[i].Spec.Template
+		if template.Annotations[annotation] == checksum || !PodSpecReferencesTarget(&template.Spec, target) {
+			continue
+		}
+
+		err = r.Patch(ctx, &statefulSets.Items[i], patch)
+		if err != nil {
+			return err
+		}
+		LogInfof(ctx, workloadReloaded, "StatefulSet", statefulSets.Items[i].Namespace, statefulSets.Items[i].Name)
+		r.RecordObjectAuditLog(ctx, replika, appsv1.SchemeGroupVersion.WithKind("StatefulSet"), &statefulSets.Items[i],
+			auditlog.ActionUpdate, auditReasonReload)
+	}
+
+	return err
+}
@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+const (
+	resyncCacheSyncError = "Can not sync the cache before the full resync"
+	resyncListError      = "Can not list the Replikas for the full resync"
+	resyncStarted        = "Starting full resync of %d Replikas spread along %s"
+	resyncFinished       = "Full resync finished in %s"
+)
+
+// errResyncPending is reported by the readiness check while the full resync did not finish
+var errResyncPending = errors.New("the full resync of the Replikas did not finish yet")
+
+// LeaderResync reconciles every Replika once after acquiring the leadership.
+// The requests are smeared along a window at random times instead of being triggered at the same time,
+// and the time needed to reconcile all of them is exposed as a metric
+type LeaderResync struct {
+	Client client.Client
+	Cache  cache.Cache
+
+	// Spread is the window used to distribute the initial reconciliations
+	Spread time.Duration
+
+	// SpreadPerReplika grows the window proportionally to the number of Replikas, keeping Spread as the minimum
+	SpreadPerReplika time.Duration
+
+	events chan event.GenericEvent
+
+	mutex     sync.Mutex
+	listed    bool
+	finished  bool
+	createdAt time.Time
+	startTime time.Time
+	pending   map[types.NamespacedName]bool
+}
+
+// NewLeaderResync return a LeaderResync ready to be added to the manager
+func NewLeaderResync(c client.Client, informers cache.Cache, spread time.Duration) *LeaderResync {
+	return &LeaderResync{
+		Client:    c,
+		Cache:     informers,
+		Spread:    spread,
+		events:    make(chan event.GenericEvent),
+		createdAt: time.Now(),
+		pending:   map[types.NamespacedName]bool{},
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, so the resync only happens on the leader
+func (l *LeaderResync) NeedLeaderElection() bool {
+	return true
+}
+
+// Events return the channel where the resync requests are sent
+func (l *LeaderResync) Events() <-chan event.GenericEvent {
+	return l.events
+}
+
+// Start implements manager.Runnable. It lists all the Replikas and requests their reconciliation spread along the window
+func (l *LeaderResync) Start(ctx context.Context) (err error) {
+
+	if !l.Cache.WaitForCacheSync(ctx) {
+		LogInfof(ctx, resyncCacheSyncError)
+		return err
+	}
+
+	replikaList := &replikav1beta1.ReplikaList{}
+	err = l.Client.List(ctx, replikaList)
+	if err != nil {
+		LogInfof(ctx, resyncListError)
+		return err
+	}
+
+	l.mutex.Lock()
+	l.listed = true
+	l.startTime = time.Now()
+	for _, v := range replikaList.Items {
+		l.pending[types.NamespacedName{Namespace: v.Namespace, Name: v.Name}] = true
+	}
+	l.finished = len(replikaList.Items) == 0
+	l.mutex.Unlock()
+
+	if len(replikaList.Items) == 0 {
+		return err
+	}
+
+	// Replikas with higher priority are synchronized first
+	sort.SliceStable(replikaList.Items, func(i, j int) bool {
+		return replikaList.Items[i].Spec.Priority > replikaList.Items[j].Spec.Priority
+	})
+
+	window := l.GetWindow(len(replikaList.Items))
+	LogInfof(ctx, resyncStarted, len(replikaList.Items), window.String())
+
+	delays := GetResyncDelays(len(replikaList.Items), window)
+	for i := range replikaList.Items {
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Until(l.startTime.Add(delays[i]))):
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case l.events <- event.GenericEvent{Object: &replikaList.Items[i]}:
+		}
+	}
+
+	return err
+}
+
+// GetWindow return the window used to smear the reconciliations of the given number of Replikas
+func (l *LeaderResync) GetWindow(replikas int) (window time.Duration) {
+	window = l.SpreadPerReplika * time.Duration(replikas)
+	if window < l.Spread {
+		window = l.Spread
+	}
+	return window
+}
+
+// GetResyncDelays return a random delay inside the window for each Replika, sorted ascending
+// so the order of the requests is kept
+func GetResyncDelays(replikas int, window time.Duration) (delays []time.Duration) {
+	delays = make([]time.Duration, replikas)
+	if window <= 0 {
+		return delays
+	}
+
+	for i := range delays {
+		delays[i] = time.Duration(rand.Int63n(int64(window)))
+	}
+	sort.Slice(delays, func(i, j int) bool {
+		return delays[i] < delays[j]
+	})
+	return delays
+}
+
+// MarkSynced remove a Replika from the pending list of the resync, observing the duration when it is the last one
+func (l *LeaderResync) MarkSynced(ctx context.Context, key types.NamespacedName) {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if !l.pending[key] {
+		return
+	}
+
+	delete(l.pending, key)
+	if len(l.pending) == 0 {
+		l.finished = true
+		duration := time.Since(l.startTime)
+		resyncDuration.Observe(duration.Seconds())
+		LogInfof(ctx, resyncFinished, duration.String())
+	}
+}
+
+// Predicate filter the creation events of the Replikas already covered by the resync,
+// so they are not all reconciled at the same time when the informers are filled
+func (l *LeaderResync) Predicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			l.mutex.Lock()
+			defer l.mutex.Unlock()
+
+			if !l.listed {
+				return false
+			}
+			return !l.pending[types.NamespacedName{Namespace: e.Object.GetNamespace(), Name: e.Object.GetName()}]
+		},
+	}
+}
+
+// ReadyzCheck return a readiness check failing until the full resync finished or the timeout elapsed.
+// The timeout also makes ready the replicas not holding the leadership, as they never resync
+func (l *LeaderResync) ReadyzCheck(timeout time.Duration) healthz.Checker {
+	return func(_ *http.Request) error {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+
+		if l.finished || time.Since(l.createdAt) >= timeout {
+			return nil
+		}
+		return errResyncPending
+	}
+}
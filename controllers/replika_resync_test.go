@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestGetWindow(t *testing.T) {
+	resync := &LeaderResync{Spread: time.Minute, SpreadPerReplika: time.Second}
+
+	if window := resync.GetWindow(10); window != time.Minute {
+		t.Errorf("expected the minimum window for few Replikas, got %v", window)
+	}
+	if window := resync.GetWindow(600); window != 10*time.Minute {
+		t.Errorf("expected the window grown with the Replikas, got %v", window)
+	}
+}
+
+func TestGetResyncDelays(t *testing.T) {
+	window := time.Minute
+	delays := GetResyncDelays(100, window)
+	if len(delays) != 100 {
+		t.Fatalf("expected a delay per Replika, got %d", len(delays))
+	}
+
+	distinct := map[time.Duration]bool{}
+	for i, delay := range delays {
+		if delay < 0 || delay >= window {
+			t.Errorf("expected the delay inside the window, got %v", delay)
+		}
+		if i > 0 && delay < delays[i-1] {
+			t.Errorf("expected the delays sorted ascending")
+		}
+		distinct[delay] = true
+	}
+	if len(distinct) < 2 {
+		t.Errorf("expected the delays smeared along the window")
+	}
+
+	for _, delay := range GetResyncDelays(3, 0) {
+		if delay != 0 {
+			t.Errorf("expected no delay without window, got %v", delay)
+		}
+	}
+}
+
+func TestLeaderResyncReadyzCheck(t *testing.T) {
+	first := types.NamespacedName{Namespace: "default", Name: "first"}
+	second := types.NamespacedName{Namespace: "default", Name: "second"}
+
+	resync := NewLeaderResync(nil, nil, time.Minute)
+	resync.listed = true
+	resync.startTime = time.Now()
+	resync.pending[first] = true
+	resync.pending[second] = true
+	check := resync.ReadyzCheck(time.Hour)
+
+	// Not ready until every Replika listed is synchronized
+	resync.MarkSynced(context.Background(), first)
+	resync.MarkSynced(context.Background(), types.NamespacedName{Namespace: "default", Name: "created-later"})
+	if err := check(nil); err != errResyncPending {
+		t.Errorf("expected the resync pending, got %v", err)
+	}
+	resync.MarkSynced(context.Background(), second)
+	if err := check(nil); err != nil {
+		t.Errorf("expected ready once the resync finished, got %v", err)
+	}
+}
+
+func TestLeaderResyncReadyzCheckTimeout(t *testing.T) {
+	// The replicas not holding the leadership never resync, they are ready once the timeout elapses
+	resync := NewLeaderResync(nil, nil, time.Minute)
+	resync.createdAt = time.Now().Add(-time.Hour)
+
+	if err := resync.ReadyzCheck(time.Minute)(nil); err != nil {
+		t.Errorf("expected ready after the timeout, got %v", err)
+	}
+	if err := resync.ReadyzCheck(2 * time.Hour)(nil); err != errResyncPending {
+		t.Errorf("expected the resync pending before the timeout, got %v", err)
+	}
+}
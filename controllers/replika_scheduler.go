@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+const (
+	// Time between two checks of the due Replikas
+	schedulerTick = time.Second
+)
+
+// ScheduleEntry defines the next synchronization of a Replika
+type ScheduleEntry struct {
+	Key      types.NamespacedName
+	Interval time.Duration
+	Next     time.Time
+}
+
+// SyncScheduler enqueues the periodical synchronizations of all the Replikas from a single place,
+// instead of each reconciliation requeueing itself. Replikas sharing an interval are smeared along it
+// by a phase derived from their name, so they never fire at the same time
+type SyncScheduler struct {
+	mutex   sync.Mutex
+	entries map[types.NamespacedName]*ScheduleEntry
+	events  chan event.GenericEvent
+}
+
+// NewSyncScheduler return an empty SyncScheduler ready to be added to the manager
+func NewSyncScheduler() *SyncScheduler {
+	return &SyncScheduler{
+		entries: map[types.NamespacedName]*ScheduleEntry{},
+		events:  make(chan event.GenericEvent),
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, so only the leader enqueues the synchronizations
+func (s *SyncScheduler) NeedLeaderElection() bool {
+	return true
+}
+
+// Events return the channel where the due synchronizations are sent
+func (s *SyncScheduler) Events() <-chan event.GenericEvent {
+	return s.events
+}
+
+// GetPhase return the offset of a Replika inside its interval, stable across restarts
+func GetPhase(key types.NamespacedName, interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
+	}
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(key.String()))
+	return time.Duration(hash.Sum64() % uint64(interval))
+}
+
+// GetNextSynchronization return the next time a Replika is due, aligned to its phase inside the interval
+func GetNextSynchronization(key types.NamespacedName, interval time.Duration, now time.Time) time.Time {
+	if interval <= 0 {
+		return now
+	}
+	next := now.Truncate(interval).Add(GetPhase(key, interval))
+	for !next.After(now) {
+		next = next.Add(interval)
+	}
+	return next
+}
+
+// Schedule register the next synchronization of a Replika
+func (s *SyncScheduler) Schedule(key types.NamespacedName, interval time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.entries[key] = &ScheduleEntry{
+		Key:      key,
+		Interval: interval,
+		Next:     GetNextSynchronization(key, interval, time.Now()),
+	}
+	s.updateMetrics()
+}
+
+// Unschedule remove a Replika from the scheduler
+func (s *SyncScheduler) Unschedule(key types.NamespacedName) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.entries, key)
+	s.updateMetrics()
+}
+
+// Entries return the scheduled synchronizations sorted by time
+func (s *SyncScheduler) Entries() (entries []ScheduleEntry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, v := range s.entries {
+		entries = append(entries, *v)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Next.Before(entries[j].Next)
+	})
+	return entries
+}
+
+// updateMetrics expose the number of Replikas scheduled on each interval. The mutex must be held
+func (s *SyncScheduler) updateMetrics() {
+	buckets := map[string]float64{}
+	for _, v := range s.entries {
+		buckets[v.Interval.String()]++
+	}
+	scheduledReplikas.Reset()
+	for interval, count := range buckets {
+		scheduledReplikas.WithLabelValues(interval).Set(count)
+	}
+}
+
+// due return the Replikas whose synchronization time arrived, removing them until they are scheduled again
+func (s *SyncScheduler) due(now time.Time) (keys []types.NamespacedName) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for k, v := range s.entries {
+		if v.Next.After(now) {
+			continue
+		}
+		keys = append(keys, k)
+		delete(s.entries, k)
+	}
+	s.updateMetrics()
+	return keys
+}
+
+// Start implements manager.Runnable, enqueueing the Replikas as they become due
+func (s *SyncScheduler) Start(ctx context.Context) error {
+
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			for _, key := range s.due(now) {
+				replika := &replikav1beta1.Replika{
+					ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+				}
+				select {
+				case <-ctx.Done():
+					return nil
+				case s.events <- event.GenericEvent{Object: replika}:
+				}
+			}
+		}
+	}
+}
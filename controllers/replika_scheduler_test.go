@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestGetNextSynchronization(t *testing.T) {
+	key := types.NamespacedName{Namespace: "default", Name: "app-config"}
+	interval := time.Minute
+	now := time.Date(2022, 1, 1, 10, 0, 30, 0, time.UTC)
+
+	next := GetNextSynchronization(key, interval, now)
+	if !next.After(now) || next.Sub(now) > interval {
+		t.Errorf("expected the next synchronization within the interval, got %v", next.Sub(now))
+	}
+	if phase := next.Sub(next.Truncate(interval)); phase != GetPhase(key, interval) {
+		t.Errorf("expected the synchronization aligned to the phase %v, got %v", GetPhase(key, interval), phase)
+	}
+
+	// The phase is stable and differs between Replikas, smearing them along the interval
+	if GetPhase(key, interval) != GetPhase(key, interval) {
+		t.Errorf("expected a stable phase")
+	}
+	other := types.NamespacedName{Namespace: "default", Name: "other-config"}
+	if GetPhase(key, interval) == GetPhase(other, interval) {
+		t.Errorf("expected different phases for different Replikas")
+	}
+
+	if next := GetNextSynchronization(key, 0, now); !next.Equal(now) {
+		t.Errorf("expected the synchronization right now without interval, got %v", next)
+	}
+}
+
+func TestSyncSchedulerDue(t *testing.T) {
+	scheduler := NewSyncScheduler()
+	first := types.NamespacedName{Namespace: "default", Name: "first"}
+	second := types.NamespacedName{Namespace: "default", Name: "second"}
+	scheduler.Schedule(first, time.Minute)
+	scheduler.Schedule(second, time.Hour)
+
+	entries := scheduler.Entries()
+	if len(entries) != 2 || entries[0].Next.After(entries[1].Next) {
+		t.Fatalf("expected 2 entries sorted by time, got %v", entries)
+	}
+
+	// The due Replikas are removed until they are scheduled again
+	due := scheduler.due(time.Now().Add(time.Minute))
+	if len(due) != 1 || due[0] != first {
+		t.Fatalf("expected only the first Replika due, got %v", due)
+	}
+	if entries = scheduler.Entries(); len(entries) != 1 || entries[0].Key != second {
+		t.Errorf("expected only the second Replika scheduled, got %v", entries)
+	}
+
+	scheduler.Unschedule(second)
+	if entries = scheduler.Entries(); len(entries) != 0 {
+		t.Errorf("expected no Replika scheduled, got %v", entries)
+	}
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	shutdownDraining     = "Draining the in-flight synchronizations before shutting down"
+	shutdownDrained      = "All the in-flight synchronizations finished"
+	shutdownDrainTimeout = "The in-flight synchronizations did not finish in %s, cancelling them"
+)
+
+// ShutdownDrainer lets the in-flight reconciliations finish when the manager stops, instead of cancelling
+// them in the middle of the writes of the targets. New reconciliations are refused while draining,
+// and the remaining ones are cancelled once the timeout expires
+type ShutdownDrainer struct {
+	// Timeout is the maximum time waiting for the in-flight reconciliations.
+	// It must be shorter than the termination grace period of the Pod
+	Timeout time.Duration
+
+	mutex    sync.Mutex
+	draining bool
+	inFlight sync.WaitGroup
+
+	// expired is cancelled when the timeout expires after the shutdown
+	expired context.Context
+	expire  context.CancelFunc
+}
+
+// NewShutdownDrainer return a ShutdownDrainer ready to be added to the manager
+func NewShutdownDrainer(timeout time.Duration) *ShutdownDrainer {
+	expired, expire := context.WithCancel(context.Background())
+	return &ShutdownDrainer{
+		Timeout: timeout,
+		expired: expired,
+		expire:  expire,
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, so the in-flight reconciliations are drained
+// also when the leadership was never acquired
+func (d *ShutdownDrainer) NeedLeaderElection() bool {
+	return false
+}
+
+// Begin account a reconciliation starting. It return false while draining, so the reconciliation is skipped
+func (d *ShutdownDrainer) Begin() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.draining {
+		return false
+	}
+	d.inFlight.Add(1)
+	return true
+}
+
+// Done account a reconciliation finished
+func (d *ShutdownDrainer) Done() {
+	d.inFlight.Done()
+}
+
+// Context return a context keeping the values of ctx that is not cancelled by the shutdown of the manager,
+// only when the timeout of the drain expires
+func (d *ShutdownDrainer) Context(ctx context.Context) (context.Context, context.CancelFunc) {
+	drainCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(d.expired, cancel)
+	return drainCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// Start implements manager.Runnable. It waits for the shutdown of the manager to drain the in-flight reconciliations
+func (d *ShutdownDrainer) Start(ctx context.Context) (err error) {
+
+	<-ctx.Done()
+
+	d.mutex.Lock()
+	d.draining = true
+	d.mutex.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		d.inFlight.Wait()
+		close(drained)
+	}()
+
+	LogInfof(ctx, shutdownDraining)
+	select {
+	case <-drained:
+		LogInfof(ctx, shutdownDrained)
+	case <-time.After(d.Timeout):
+		LogInfof(ctx, shutdownDrainTimeout, d.Timeout.String())
+		d.expire()
+		<-drained
+	}
+
+	return err
+}
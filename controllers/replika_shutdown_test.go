@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestShutdownDrainerDrains(t *testing.T) {
+	drainer := NewShutdownDrainer(time.Minute)
+	if !drainer.Begin() {
+		t.Fatalf("expected the reconciliation accepted before the shutdown")
+	}
+
+	managerCtx, stopManager := context.WithCancel(context.Background())
+	drainCtx, cancel := drainer.Context(managerCtx)
+	defer cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		_ = drainer.Start(managerCtx)
+		close(stopped)
+	}()
+	stopManager()
+
+	// The in-flight reconciliation keeps running after the shutdown, and new ones are refused
+	time.Sleep(50 * time.Millisecond)
+	if drainCtx.Err() != nil {
+		t.Errorf("expected the in-flight reconciliation not cancelled by the shutdown")
+	}
+	if drainer.Begin() {
+		t.Errorf("expected new reconciliations refused while draining")
+	}
+	select {
+	case <-stopped:
+		t.Fatalf("expected the drainer waiting for the in-flight reconciliation")
+	default:
+	}
+
+	drainer.Done()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the drain")
+	}
+}
+
+func TestShutdownDrainerTimeout(t *testing.T) {
+	drainer := NewShutdownDrainer(50 * time.Millisecond)
+	drainer.Begin()
+
+	managerCtx, stopManager := context.WithCancel(context.Background())
+	drainCtx, cancel := drainer.Context(managerCtx)
+	defer cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		_ = drainer.Start(managerCtx)
+		close(stopped)
+	}()
+	stopManager()
+
+	// The in-flight reconciliation is cancelled once the timeout expires
+	select {
+	case <-drainCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the cancellation of the in-flight reconciliation")
+	}
+	drainer.Done()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the drain")
+	}
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// SourceCache keeps the last read of the sources with their own synchronization time,
+// so they are not read again on each synchronization of their Replika
+type SourceCache struct {
+	mutex   sync.Mutex
+	entries map[types.NamespacedName]map[string]sourceCacheEntry
+}
+
+// sourceCacheEntry is a source and the time it was read
+type sourceCacheEntry struct {
+	source   *unstructured.Unstructured
+	readTime time.Time
+}
+
+// NewSourceCache return an empty SourceCache
+func NewSourceCache() *SourceCache {
+	return &SourceCache{
+		entries: map[types.NamespacedName]map[string]sourceCacheEntry{},
+	}
+}
+
+// Get return a copy of the source of the Replika when it was read less than maxAge ago
+func (c *SourceCache) Get(key types.NamespacedName, sourceRef string, maxAge time.Duration) (source *unstructured.Unstructured, found bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, found := c.entries[key][sourceRef]
+	if !found || time.Since(entry.readTime) >= maxAge {
+		return source, false
+	}
+	return entry.source.DeepCopy(), true
+}
+
+// Set keep a copy of the source of the Replika read right now
+func (c *SourceCache) Set(key types.NamespacedName, sourceRef string, source *unstructured.Unstructured) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.entries[key] == nil {
+		c.entries[key] = map[string]sourceCacheEntry{}
+	}
+	c.entries[key][sourceRef] = sourceCacheEntry{source: source.DeepCopy(), readTime: time.Now()}
+}
+
+// Forget remove the sources of a Replika from the cache
+func (c *SourceCache) Forget(key types.NamespacedName) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.entries, key)
+}
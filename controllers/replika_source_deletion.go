@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/conditions"
+)
+
+const (
+	// Policies applied to the targets once the source is deleted
+	sourceOnDeleteRetain        = "Retain"
+	sourceOnDeleteDeleteTargets = "DeleteTargets"
+	sourceOnDeleteFreeze        = "Freeze"
+)
+
+// GetSourceOnDelete return the policy applied to the targets once the source is deleted, Retain when not set
+func GetSourceOnDelete(replika *replikav1beta1.Replika) string {
+	if replika.Spec.Source.OnDelete == "" {
+		return sourceOnDeleteRetain
+	}
+	return replika.Spec.Source.OnDelete
+}
+
+// HandleMissingSource apply the deletion policy of the source once it is not found. Retained targets fail
+// the synchronization as before, frozen ones keep the last content of the source until it is back, and the rest
+// are deleted when the grace period expires, unless the Replika only audits them, the operator is paused or the
+// deletion waits for its confirmation. The time the source was found missing is kept in the status
+func (r *ReplikaReconciler) HandleMissingSource(ctx context.Context, replika *replikav1beta1.Replika, sourceErr error) (err error) {
+
+	if replika.Status.SourceMissingTime == nil {
+		missingTime := metav1.Now()
+		replika.Status.SourceMissingTime = &missingTime
+	}
+	missingSince := replika.Status.SourceMissingTime.Format(time.RFC3339)
+
+	policy := GetSourceOnDelete(replika)
+	condition := conditions.New(ConditionTypeSourceSynced,
+		metav1.ConditionFalse,
+		ConditionReasonSourceMissing,
+		ConditionReasonSourceMissingFrozenMessage, missingSince,
+	)
+	pendingErr := &PendingError{reason: fmt.Sprintf(sourceMissingError, replika.Name, policy)}
+
+	switch policy {
+	case sourceOnDeleteFreeze:
+		r.SetReplikaCondition(replika, condition)
+		return pendingErr
+
+	case sourceOnDeleteDeleteTargets:
+		// A grace period is always required, so a source briefly missing never removes its targets at once
+		var gracePeriod time.Duration
+		gracePeriod, err = time.ParseDuration(replika.Spec.Source.DeletionGracePeriod)
+		if err != nil {
+			err = NewPermanentErrorf(deletionGracePeriodError, err.Error())
+			return err
+		}
+		if gracePeriod <= 0 {
+			err = NewPermanentErrorf(deletionGracePeriodError, "it must be greater than zero")
+			return err
+		}
+
+		// Keep the targets until the grace period expires, the source can be back in the meantime
+		deletionTime := replika.Status.SourceMissingTime.Add(gracePeriod)
+		if time.Now().Before(deletionTime) {
+			condition.Message = fmt.Sprintf(ConditionReasonSourceMissingDeletionMessage, missingSince, deletionTime.Format(time.RFC3339))
+			r.SetReplikaCondition(replika, condition)
+			return pendingErr
+		}
+
+		// The audit-only Replikas never delete their targets, and nothing is deleted while the operator is paused
+		// or the deletion waits for its confirmation
+		if replika.Spec.Synchronization.AuditOnly {
+			condition.Message = fmt.Sprintf(ConditionReasonSourceMissingAuditOnlyMessage, missingSince)
+			r.SetReplikaCondition(replika, condition)
+			return pendingErr
+		}
+		if r.settings().Paused {
+			r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+				metav1.ConditionFalse,
+				ConditionReasonPaused,
+				ConditionReasonPausedMessage,
+			))
+			return errPaused
+		}
+		err = r.CheckSourceDeletionConfirmation(ctx, replika)
+		if err != nil {
+			return err
+		}
+
+		// Delete the targets by batches, reporting the progress until none remains
+		var remaining int
+		remaining, err = r.DeleteTargets(ctx, replika)
+		if err != nil {
+			return err
+		}
+		condition.Message = fmt.Sprintf(ConditionReasonSourceMissingDeletedMessage, missingSince)
+		if remaining > 0 {
+			condition.Message = fmt.Sprintf(ConditionReasonSourceMissingDeletingMessage, missingSince, remaining)
+		}
+
+		// The confirmation only approves this deletion, the next one is confirmed again
+		if remaining == 0 {
+			err = r.ClearConfirmation(ctx, replika)
+			if err != nil {
+				return err
+			}
+		}
+		r.SetReplikaCondition(replika, condition)
+		return pendingErr
+	}
+
+	r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+		metav1.ConditionFalse,
+		ConditionReasonSourceNotFound,
+		ConditionReasonSourceNotFoundMessage,
+	))
+	return sourceErr
+}
@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/conditions"
+)
+
+func TestGetSourceOnDelete(t *testing.T) {
+	tests := []struct {
+		onDelete string
+		expected string
+	}{
+		{onDelete: "", expected: sourceOnDeleteRetain},
+		{onDelete: sourceOnDeleteFreeze, expected: sourceOnDeleteFreeze},
+		{onDelete: sourceOnDeleteDeleteTargets, expected: sourceOnDeleteDeleteTargets},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expected, func(t *testing.T) {
+			replika := &replikav1beta1.Replika{}
+			replika.Spec.Source.OnDelete = test.onDelete
+			if policy := GetSourceOnDelete(replika); policy != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, policy)
+			}
+		})
+	}
+}
+
+func TestHandleMissingSource(t *testing.T) {
+	sourceErr := fmt.Errorf("the source is not found")
+	expired := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+
+	tests := []struct {
+		name                  string
+		onDelete              string
+		gracePeriod           string
+		missingTime           *metav1.Time
+		auditOnly             bool
+		paused                bool
+		confirmationThreshold int
+		confirmed             string
+
+		expectedErr    error
+		pending        bool
+		permanent      bool
+		expectedReason string
+		deleted        bool
+	}{
+		{
+			name:           "retained targets",
+			onDelete:       sourceOnDeleteRetain,
+			expectedErr:    sourceErr,
+			expectedReason: ConditionReasonSourceNotFound,
+		},
+		{
+			name:           "frozen targets",
+			onDelete:       sourceOnDeleteFreeze,
+			pending:        true,
+			expectedReason: ConditionReasonSourceMissing,
+		},
+		{
+			name:           "targets kept during the grace period",
+			onDelete:       sourceOnDeleteDeleteTargets,
+			gracePeriod:    "24h",
+			pending:        true,
+			expectedReason: ConditionReasonSourceMissing,
+		},
+		{
+			name:        "zero grace period refused",
+			onDelete:    sourceOnDeleteDeleteTargets,
+			gracePeriod: "0s",
+			missingTime: &expired,
+			permanent:   true,
+		},
+		{
+			name:           "targets deleted once the grace period expires",
+			onDelete:       sourceOnDeleteDeleteTargets,
+			gracePeriod:    "1h",
+			missingTime:    &expired,
+			pending:        true,
+			expectedReason: ConditionReasonSourceMissing,
+			deleted:        true,
+		},
+		{
+			name:           "targets of an audit-only Replika",
+			onDelete:       sourceOnDeleteDeleteTargets,
+			gracePeriod:    "1h",
+			missingTime:    &expired,
+			auditOnly:      true,
+			pending:        true,
+			expectedReason: ConditionReasonSourceMissing,
+		},
+		{
+			name:           "targets while paused",
+			onDelete:       sourceOnDeleteDeleteTargets,
+			gracePeriod:    "1h",
+			missingTime:    &expired,
+			paused:         true,
+			expectedErr:    errPaused,
+			expectedReason: ConditionReasonPaused,
+		},
+		{
+			name:                  "targets waiting for the confirmation",
+			onDelete:              sourceOnDeleteDeleteTargets,
+			gracePeriod:           "1h",
+			missingTime:           &expired,
+			confirmationThreshold: 1,
+			expectedErr:           errConfirmationPending,
+			expectedReason:        ConditionReasonPendingConfirmation,
+		},
+		{
+			name:                  "targets waiting for the confirmation of the deletion",
+			onDelete:              sourceOnDeleteDeleteTargets,
+			gracePeriod:           "1h",
+			missingTime:           &expired,
+			confirmationThreshold: 1,
+			confirmed:             "delete/1",
+			expectedErr:           errConfirmationPending,
+			expectedReason:        ConditionReasonPendingConfirmation,
+		},
+		{
+			name:                  "targets deleted once confirmed",
+			onDelete:              sourceOnDeleteDeleteTargets,
+			gracePeriod:           "1h",
+			missingTime:           &expired,
+			confirmationThreshold: 1,
+			confirmed:             "delete-targets/1",
+			pending:               true,
+			expectedReason:        ConditionReasonSourceMissing,
+			deleted:               true,
+		},
+	}
+
+	// The Replika is stored to clear its confirmation
+	if err := replikav1beta1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatalf("unexpected error registering the Replika: %v", err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-config", Generation: 1}}
+			if test.confirmed != "" {
+				replika.Annotations = map[string]string{confirmationAnnotation: test.confirmed}
+			}
+			replika.Spec.Source = replikav1beta1.ReplikaSourceSpec{
+				Version:             "v1",
+				Kind:                "ConfigMap",
+				Name:                "app-config",
+				Namespace:           "default",
+				OnDelete:            test.onDelete,
+				DeletionGracePeriod: test.gracePeriod,
+			}
+			replika.Spec.Synchronization.AuditOnly = test.auditOnly
+			replika.Status.SourceMissingTime = test.missingTime
+
+			// Two targets written in other namespaces
+			targets := []client.Object{replika.DeepCopy()}
+			for _, ns := range []string{"team-a", "team-b"} {
+				targets = append(targets, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+					Namespace: ns,
+					Name:      "app-config",
+					Labels: map[string]string{
+						resourceReplikaLabelPartOfKey:          replika.Name,
+						resourceReplikaLabelPartOfNamespaceKey: replika.Namespace,
+						resourceReplikaLabelCreatedKey:         resourceReplikaLabelCreatedValue,
+					},
+				}})
+			}
+
+			config := NewOperatorConfig()
+			config.SetSettings(OperatorSettings{Concurrency: 1, Paused: test.paused})
+			r := &ReplikaReconciler{
+				Client:                fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(targets...).Build(),
+				Config:                config,
+				ConfirmationThreshold: test.confirmationThreshold,
+			}
+
+			err := r.HandleMissingSource(context.Background(), replika, sourceErr)
+			switch {
+			case test.expectedErr != nil && err != test.expectedErr:
+				t.Errorf("expected %v, got %v", test.expectedErr, err)
+			case test.pending && (!IsPendingError(err) || err == errPaused || err == errConfirmationPending):
+				t.Errorf("expected the source missing pending, got %v", err)
+			case test.permanent && !IsPermanentError(err):
+				t.Errorf("expected a permanent error, got %v", err)
+			}
+
+			if replika.Status.SourceMissingTime == nil {
+				t.Errorf("expected the time the source was found missing recorded")
+			}
+			if test.expectedReason != "" {
+				condition := conditions.Get(replika.Status.Conditions, ConditionTypeSourceSynced)
+				if condition == nil || condition.Reason != test.expectedReason {
+					t.Errorf("expected the reason %s, got %v", test.expectedReason, condition)
+				}
+			}
+
+			expectedTargets := 2
+			if test.deleted {
+				expectedTargets = 0
+			}
+			list := &corev1.ConfigMapList{}
+			if err := r.List(context.Background(), list); err != nil || len(list.Items) != expectedTargets {
+				t.Errorf("expected %d targets, got %d: %v", expectedTargets, len(list.Items), err)
+			}
+
+			// The confirmation is cleared once the deletion it approved is done
+			stored := &replikav1beta1.Replika{}
+			if err := r.Get(context.Background(), client.ObjectKeyFromObject(replika), stored); err != nil {
+				t.Fatalf("unexpected error getting the Replika: %v", err)
+			}
+			if _, found := stored.Annotations[confirmationAnnotation]; found != (test.confirmed != "" && !test.deleted) {
+				t.Errorf("unexpected confirmation annotation %v", stored.Annotations)
+			}
+		})
+	}
+}
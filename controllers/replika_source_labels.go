@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+const (
+	// Labels identifying the source of the targets, so all the copies of a source are selected with a single query.
+	// The name of the source can be longer than a label value, so the identity is a hash of the whole reference
+	sourceNamespaceLabel = "replika.prosimcorp.com/source-namespace"
+	sourceIDLabel        = "replika.prosimcorp.com/source-id"
+	sourceRevisionLabel  = "replika.prosimcorp.com/source-revision"
+
+	// Length of the hashes used as label values
+	sourceLabelHashLength = 10
+)
+
+// GetSourceID return the short hash identifying a source by its group, kind, namespace and name
+func GetSourceID(group, kind, namespace, name string) string {
+	sum := sha256.Sum256([]byte(group + "/" + kind + "/" + namespace + "/" + name))
+	return hex.EncodeToString(sum[:])[:sourceLabelHashLength]
+}
+
+// GetSourceIDSelector return the label selector matching all the targets copied from a source
+func GetSourceIDSelector(group, kind, namespace, name string) string {
+	return sourceIDLabel + "=" + GetSourceID(group, kind, namespace, name)
+}
+
+// GetTargetLabels return the labels added to the targets of a Replika: the ones marking them as written by
+// the controller and the ones identifying the source and its revision
+func GetTargetLabels(replika *replikav1beta1.Replika, source *unstructured.Unstructured) (labels map[string]string, err error) {
+
+	labels = map[string]string{
+		resourceReplikaLabelCreatedKey:         resourceReplikaLabelCreatedValue,
+		resourceReplikaLabelPartOfKey:          replika.Name,
+		resourceReplikaLabelPartOfNamespaceKey: replika.Namespace,
+		sourceIDLabel: GetSourceID(source.GroupVersionKind().Group, source.GetKind(),
+			source.GetNamespace(), source.GetName()),
+	}
+	if source.GetNamespace() != "" {
+		labels[sourceNamespaceLabel] = source.GetNamespace()
+	}
+
+	var revision string
+	revision, err = GetTargetHash(source, source)
+	if err != nil {
+		return labels, err
+	}
+	labels[sourceRevisionLabel] = revision[:sourceLabelHashLength]
+
+	return labels, err
+}
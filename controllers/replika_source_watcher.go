@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	clientcache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+const (
+	sourceWatchStarted = "Started the watch of the sources %s"
+	sourceWatchStopped = "Stopped the watch of the sources %s, no Replika references them"
+	sourceWatchError   = "Can not watch the sources %s: %s"
+)
+
+// SourceRef defines an object used as source by a Replika. An empty namespace matches any of them
+type SourceRef struct {
+	GVK       schema.GroupVersionKind
+	Namespace string
+	Name      string
+}
+
+// sourceWatch is the informer of a kind of sources, shared by all the Replikas referencing it
+type sourceWatch struct {
+	cancel    context.CancelFunc
+	store     clientcache.Store
+	hasSynced clientcache.InformerSynced
+}
+
+// SourceWatcher enqueues the Replikas when their sources change. An informer is started for each kind
+// on the first Replika referencing it, and stopped when the last one stops doing it,
+// so the watches do not grow monotonically in clusters trying many kinds.
+// The sources not found are remembered until their informer sees them created, so the Replikas
+// pointing at the same missing source do not read it on each synchronization
+type SourceWatcher struct {
+	client dynamic.Interface
+	mapper meta.RESTMapper
+
+	mutex   sync.Mutex
+	ctx     context.Context
+	refs    map[types.NamespacedName][]SourceRef
+	watches map[schema.GroupVersionKind]*sourceWatch
+	missing map[SourceRef]error
+	events  chan event.GenericEvent
+}
+
+// NewSourceWatcher return an empty SourceWatcher ready to be added to the manager
+func NewSourceWatcher(client dynamic.Interface, mapper meta.RESTMapper) *SourceWatcher {
+	return &SourceWatcher{
+		client:  client,
+		mapper:  mapper,
+		refs:    map[types.NamespacedName][]SourceRef{},
+		watches: map[schema.GroupVersionKind]*sourceWatch{},
+		missing: map[SourceRef]error{},
+		events:  make(chan event.GenericEvent),
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, so only the leader watches the sources
+func (s *SourceWatcher) NeedLeaderElection() bool {
+	return true
+}
+
+// Events return the channel where the Replikas whose sources changed are sent
+func (s *SourceWatcher) Events() <-chan event.GenericEvent {
+	return s.events
+}
+
+// GetSourceRefs return the objects used as source by a Replika. Inline sources are not watched
+func GetSourceRefs(replika *replikav1beta1.Replika) (refs []SourceRef) {
+	sources := append([]replikav1beta1.ReplikaSourceSpec{replika.Spec.Source}, replika.Spec.Sources...)
+	for _, source := range sources {
+		if source.Inline != nil {
+			continue
+		}
+		refs = append(refs, SourceRef{
+			GVK:       source.GetGroupVersionKind(),
+			Namespace: source.Namespace,
+			Name:      source.Name,
+		})
+	}
+	return refs
+}
+
+// Start implements manager.Runnable, starting the watches registered before the manager and stopping
+// all of them when the manager stops
+func (s *SourceWatcher) Start(ctx context.Context) error {
+
+	s.mutex.Lock()
+	s.ctx = ctx
+	for gvk := range s.watches {
+		s.startWatch(gvk)
+	}
+	s.mutex.Unlock()
+
+	<-ctx.Done()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for gvk := range s.watches {
+		s.stopWatch(gvk)
+	}
+	return nil
+}
+
+// Watch register the sources of a Replika, starting the watches of the kinds not referenced yet
+func (s *SourceWatcher) Watch(replika *replikav1beta1.Replika) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.refs[types.NamespacedName{Namespace: replika.Namespace, Name: replika.Name}] = GetSourceRefs(replika)
+	s.reconcileWatches()
+}
+
+// Unwatch remove the sources of a Replika, stopping the watches of the kinds no longer referenced
+func (s *SourceWatcher) Unwatch(key types.NamespacedName) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.refs, key)
+	s.reconcileWatches()
+}
+
+// reconcileWatches start and stop the watches according to the referenced kinds. The mutex must be held
+func (s *SourceWatcher) reconcileWatches() {
+
+	referenced := map[schema.GroupVersionKind]bool{}
+	for _, refs := range s.refs {
+		for _, ref := range refs {
+			referenced[ref.GVK] = true
+		}
+	}
+
+	for gvk := range referenced {
+		if _, found := s.watches[gvk]; found {
+			continue
+		}
+		s.watches[gvk] = &sourceWatch{}
+		s.startWatch(gvk)
+	}
+
+	for gvk := range s.watches {
+		if referenced[gvk] {
+			continue
+		}
+		s.stopWatch(gvk)
+		delete(s.watches, gvk)
+	}
+
+	activeSourceWatches.Set(float64(len(s.watches)))
+}
+
+// startWatch start the informer of a kind once the manager is started. The mutex must be held
+func (s *SourceWatcher) startWatch(gvk schema.GroupVersionKind) {
+	if s.ctx == nil || s.watches[gvk].cancel != nil {
+		return
+	}
+
+	// Kinds not served yet are retried on the next registration of a Replika
+	mapping, err := s.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		LogErrorDedupf(s.ctx, sourceWatchError, gvk.String(), err.Error())
+		delete(s.watches, gvk)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.watches[gvk].cancel = cancel
+
+	informer := dynamicinformer.NewFilteredDynamicInformer(s.client, mapping.Resource, metav1.NamespaceAll, 0,
+		clientcache.Indexers{}, nil).Informer()
+	s.watches[gvk].store = informer.GetStore()
+	s.watches[gvk].hasSynced = informer.HasSynced
+	informer.AddEventHandler(clientcache.ResourceEventHandlerFuncs{
+		// The objects listed when the informer starts are already covered by the reconciliation
+		// of the Replika registering the watch
+		AddFunc: func(obj interface{}) {
+			if !informer.HasSynced() {
+				return
+			}
+			s.notify(ctx, gvk, obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			s.notify(ctx, gvk, obj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(clientcache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			s.notify(ctx, gvk, obj)
+		},
+	})
+
+	go informer.Run(ctx.Done())
+	LogInfof(s.ctx, sourceWatchStarted, gvk.String())
+}
+
+// stopWatch stop the informer of a kind. The mutex must be held
+func (s *SourceWatcher) stopWatch(gvk schema.GroupVersionKind) {
+	if s.watches[gvk].cancel == nil {
+		return
+	}
+
+	s.watches[gvk].cancel()
+	s.watches[gvk].cancel = nil
+	s.watches[gvk].store = nil
+	s.watches[gvk].hasSynced = nil
+
+	// Without the informer, nothing would tell when the missing sources are created
+	for ref := range s.missing {
+		if ref.GVK == gvk {
+			delete(s.missing, ref)
+		}
+	}
+	LogInfof(s.ctx, sourceWatchStopped, gvk.String())
+}
+
+// GetCachedObjects return the objects held by the informer of each watched kind
+func (s *SourceWatcher) GetCachedObjects() (objects map[schema.GroupVersionKind][]interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	objects = map[schema.GroupVersionKind][]interface{}{}
+	for gvk, watch := range s.watches {
+		if watch.store != nil {
+			objects[gvk] = watch.store.List()
+		}
+	}
+	return objects
+}
+
+// GetMissing return the NotFound error of a source remembered as missing, or nil when it must be read
+func (s *SourceWatcher) GetMissing(ref SourceRef) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.missing[ref]
+}
+
+// SetMissing remember a source as missing with the NotFound error returned when reading it.
+// It is only remembered while the informer of its kind is synced and does not hold it,
+// as its creation could not be seen otherwise
+func (s *SourceWatcher) SetMissing(ref SourceRef, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	watch, found := s.watches[ref.GVK]
+	if !found || watch.store == nil || !watch.hasSynced() {
+		return
+	}
+
+	storeKey := ref.Name
+	if ref.Namespace != "" {
+		storeKey = ref.Namespace + "/" + ref.Name
+	}
+	if _, exists, _ := watch.store.GetByKey(storeKey); exists {
+		return
+	}
+	s.missing[ref] = err
+}
+
+// notify enqueue the Replikas using the changed object as source
+func (s *SourceWatcher) notify(ctx context.Context, gvk schema.GroupVersionKind, obj interface{}) {
+
+	object, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+
+	var keys []types.NamespacedName
+	s.mutex.Lock()
+	delete(s.missing, SourceRef{GVK: gvk, Namespace: object.GetNamespace(), Name: object.GetName()})
+	for key, refs := range s.refs {
+		for _, ref := range refs {
+			if ref.GVK != gvk || ref.Name != object.GetName() {
+				continue
+			}
+			if ref.Namespace != "" && ref.Namespace != object.GetNamespace() {
+				continue
+			}
+			keys = append(keys, key)
+			break
+		}
+	}
+	s.mutex.Unlock()
+
+	for _, key := range keys {
+		replika := &replikav1beta1.Replika{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case s.events <- event.GenericEvent{Object: replika}:
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+)
+
+var (
+	configMapGVK = schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	secretGVK    = schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+)
+
+// newWatchedReplika return a Replika using the sources of the kinds, named app-config in the default namespace
+func newWatchedReplika(name string, kinds ...string) *replikav1beta1.Replika {
+	replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+	for i, kind := range kinds {
+		source := replikav1beta1.ReplikaSourceSpec{Version: "v1", Kind: kind, Name: "app-config", Namespace: "default"}
+		if i == 0 {
+			replika.Spec.Source = source
+			continue
+		}
+		replika.Spec.Sources = append(replika.Spec.Sources, source)
+	}
+	return replika
+}
+
+func TestGetSourceRefs(t *testing.T) {
+	replika := newWatchedReplika("app", "ConfigMap", "Secret")
+	replika.Spec.Sources = append(replika.Spec.Sources, replikav1beta1.ReplikaSourceSpec{
+		Inline: &runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap"}`)},
+	})
+
+	refs := GetSourceRefs(replika)
+	if len(refs) != 2 || refs[0].GVK != configMapGVK || refs[1].GVK != secretGVK {
+		t.Errorf("expected the ConfigMap and Secret sources without the inline one, got %v", refs)
+	}
+}
+
+func TestSourceWatcherWatches(t *testing.T) {
+	watcher := NewSourceWatcher(nil, nil)
+	watcher.Watch(newWatchedReplika("first", "ConfigMap"))
+	watcher.Watch(newWatchedReplika("second", "ConfigMap", "Secret"))
+
+	if len(watcher.watches) != 2 {
+		t.Fatalf("expected a watch per kind, got %d", len(watcher.watches))
+	}
+
+	// The watch of a kind is kept while a Replika references it
+	watcher.Unwatch(types.NamespacedName{Namespace: "default", Name: "second"})
+	if _, found := watcher.watches[secretGVK]; found {
+		t.Errorf("expected the watch of the Secrets stopped")
+	}
+	if _, found := watcher.watches[configMapGVK]; !found {
+		t.Errorf("expected the watch of the ConfigMaps kept")
+	}
+
+	watcher.Unwatch(types.NamespacedName{Namespace: "default", Name: "first"})
+	if len(watcher.watches) != 0 {
+		t.Errorf("expected no watch left, got %d", len(watcher.watches))
+	}
+}
+
+func TestSourceWatcherNotify(t *testing.T) {
+	watcher := NewSourceWatcher(nil, nil)
+	watcher.Watch(newWatchedReplika("configmap", "ConfigMap"))
+	watcher.Watch(newWatchedReplika("secret", "Secret"))
+
+	ref := SourceRef{GVK: configMapGVK, Namespace: "default", Name: "app-config"}
+	watcher.missing[ref] = errors.New("the source is not found")
+
+	source := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-config"}}
+	go watcher.notify(context.Background(), configMapGVK, source)
+
+	select {
+	case e := <-watcher.Events():
+		if e.Object.GetName() != "configmap" {
+			t.Errorf("expected the Replika using the ConfigMap enqueued, got %s", e.Object.GetName())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the Replika to be enqueued")
+	}
+
+	select {
+	case e := <-watcher.Events():
+		t.Errorf("unexpected Replika enqueued %s", e.Object.GetName())
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// A source seen by its informer is not missing anymore
+	if err := watcher.GetMissing(ref); err != nil {
+		t.Errorf("expected the source forgotten as missing, got %v", err)
+	}
+}
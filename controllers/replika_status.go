@@ -1,68 +1,449 @@
 package controllers
 
 import (
+	"context"
+	"errors"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/conditions"
+	"prosimcorp.com/replika/pkg/replicator"
 )
 
+// Maximum number of namespaces listed in status.syncedNamespaces and status.failedNamespaces
+const maxStatusNamespaces = 50
+
 // https://github.com/external-secrets/external-secrets/blob/80545f4f183795ef193747fc959558c761b51c99/apis/externalsecrets/v1alpha1/externalsecret_types.go#L168
 const (
 	// ConditionTypeSourceSynced indicates that the source was synchronizated or not
 	ConditionTypeSourceSynced = "SourceSynced"
 
+	// ConditionTypeReady indicates that all the targets are synchronized, so 'kubectl wait --for=condition=Ready' can be used
+	ConditionTypeReady = "Ready"
+
+	// ConditionTypeCertificateExpiringSoon indicates that the certificate of the source expires within the warning
+	ConditionTypeCertificateExpiringSoon = "CertificateExpiringSoon"
+
 	// Source not found
 	ConditionReasonSourceNotFound        = "SourceNotFound"
 	ConditionReasonSourceNotFoundMessage = "Source resource was not found"
 
+	// Source was deleted, its targets are handled according to spec.source.onDelete
+	ConditionReasonSourceMissing                 = "SourceMissing"
+	ConditionReasonSourceMissingFrozenMessage    = "The source is missing since %s, the targets keep its last content until it is back"
+	ConditionReasonSourceMissingDeletionMessage  = "The source is missing since %s, the targets are deleted at %s unless it is back"
+	ConditionReasonSourceMissingDeletingMessage  = "The source is missing since %s, %d targets remaining to be deleted"
+	ConditionReasonSourceMissingDeletedMessage   = "The source is missing since %s, its targets were deleted"
+	ConditionReasonSourceMissingAuditOnlyMessage = "The source is missing since %s, the targets are kept as the Replika only audits them"
+
+	// Source is not ready according to its readiness expression
+	ConditionReasonSourceNotReady        = "SourceNotReady"
+	ConditionReasonSourceNotReadyMessage = "Waiting for the source %s/%s to match its readiness expression"
+
+	// Source failed its sanity checks
+	ConditionReasonSourceInvalid        = "SourceInvalid"
+	ConditionReasonSourceInvalidMessage = "The source failed its sanity checks and was not replicated: %s"
+
+	// The certificate of the source expires within the warning, or not
+	ConditionReasonCertificateExpiring        = "CertificateExpiring"
+	ConditionReasonCertificateExpiringMessage = "The certificate of the source expires at %s"
+	ConditionReasonCertificateValid           = "CertificateValid"
+	ConditionReasonCertificateValidMessage    = "The certificate of the source is valid until %s"
+
+	// Source kind not allowed by the operator settings
+	ConditionReasonSourceKindNotAllowed        = "SourceKindNotAllowed"
+	ConditionReasonSourceKindNotAllowedMessage = "The kind of the source is not allowed by the operator configuration"
+
+	// Source kind is cluster-scoped
+	ConditionReasonSourceClusterScoped        = "SourceClusterScoped"
+	ConditionReasonSourceClusterScopedMessage = "The kind %s of the source is cluster-scoped, only namespaced kinds can be replicated across namespaces"
+
 	// Target namespace not found
 	ConditionReasonTargetNamespaceNotFound        = "TargetNamespaceNotFound"
 	ConditionReasonTargetNamespaceNotFoundMessage = "A target namespace was not found"
 
+	// Too many target namespaces
+	ConditionReasonTooManyTargets        = "TooManyTargets"
+	ConditionReasonTooManyTargetsMessage = "The source would be replicated in %d namespaces, exceeding the limit of %d"
+
 	// Replication failed
 	ConditionReasonSourceReplicationFailed        = "SourceReplicationFailed"
 	ConditionReasonSourceReplicationFailedMessage = "Error replicating the source on targets"
 
+	// Targets rejected by the API server during the dry-run validation
+	ConditionReasonTargetValidationFailed        = "TargetValidationFailed"
+	ConditionReasonTargetValidationFailedMessage = "Some target namespaces rejected the source, check status.rejectedNamespaces"
+
+	// Targets too large to be stored by the API server
+	ConditionReasonTargetTooLarge        = "TargetTooLarge"
+	ConditionReasonTargetTooLargeMessage = "Some targets exceed the maximum object size, check status.rejectedNamespaces"
+
+	// Workloads consuming the targets could not be reloaded
+	ConditionReasonWorkloadReloadFailed        = "WorkloadReloadFailed"
+	ConditionReasonWorkloadReloadFailedMessage = "Error reloading the workloads consuming the targets"
+
+	// Audit found drifted or missing targets
+	ConditionReasonTargetsDrifted        = "TargetsDrifted"
+	ConditionReasonTargetsDriftedMessage = "Some targets drifted from the source or are missing, check status.integrity"
+
+	// Audit found all the targets in sync
+	ConditionReasonAuditPassed        = "AuditPassed"
+	ConditionReasonAuditPassedMessage = "All the targets match the source"
+
+	// The spec of the Replika can not be synchronized, so it is not retried until the next synchronization
+	ConditionReasonInvalidSpec        = "InvalidSpec"
+	ConditionReasonInvalidSpecMessage = "The spec of the Replika is invalid, retrying on the next synchronization: %s"
+
+	// The service account of the operator is not allowed to perform the request
+	ConditionReasonRBACDenied        = "RBACDenied"
+	ConditionReasonRBACDeniedMessage = "The operator is not allowed to manage the resources, check its RBAC"
+
+	// A request to the API server exceeded the request timeout
+	ConditionReasonRequestTimeout        = "RequestTimeout"
+	ConditionReasonRequestTimeoutMessage = "A request to the API server timed out, check spec.synchronization.requestTimeout"
+
+	// The targets kept being modified by others while they were written with the optimistic lock
+	ConditionReasonTargetModified        = "TargetModified"
+	ConditionReasonTargetModifiedMessage = "The targets were modified by others while being written, retrying on the next synchronization"
+
+	// An admission webhook or policy denied the request
+	ConditionReasonAdmissionDenied        = "AdmissionDenied"
+	ConditionReasonAdmissionDeniedMessage = "An admission policy denied the targets in some namespaces, check status.rejectedNamespaces"
+
+	// An admission webhook failed to process the request, like when it is unavailable
+	ConditionReasonAdmissionFailed        = "AdmissionFailed"
+	ConditionReasonAdmissionFailedMessage = "An admission webhook failed to process the targets in some namespaces, check status.rejectedNamespaces"
+
+	// The target has an immutable field whose value changed on the source
+	ConditionReasonImmutableField        = "ImmutableField"
+	ConditionReasonImmutableFieldMessage = "A field of the targets is immutable and differs from the source, check status.rejectedNamespaces or enable spec.target.recreateImmutable"
+
+	// The namespace has an object with the name of the target not written by the controller
+	ConditionReasonTargetConflict        = "TargetConflict"
+	ConditionReasonTargetConflictMessage = "Some namespaces have an object with the name of the target not written by the controller, check status.rejectedNamespaces"
+
+	// The values looked up in the target namespace are missing
+	ConditionReasonLookupFailed        = "LookupFailed"
+	ConditionReasonLookupFailedMessage = "Some namespaces are missing the values looked up by the targets, check status.rejectedNamespaces"
+
+	// A resource quota of the target namespace is exceeded
+	ConditionReasonQuotaExceeded        = "QuotaExceeded"
+	ConditionReasonQuotaExceededMessage = "A resource quota of a target namespace is exceeded"
+
+	// The target namespace is being deleted
+	ConditionReasonNamespaceTerminating        = "NamespaceTerminating"
+	ConditionReasonNamespaceTerminatingMessage = "A target namespace is being deleted"
+
+	// The canary namespace is waiting for its verification
+	ConditionReasonCanaryPending        = "CanaryPending"
+	ConditionReasonCanaryPendingMessage = "The canary namespace was synchronized, waiting for its verification"
+
+	// The canary namespace failed its verification
+	ConditionReasonCanaryFailed        = "CanaryFailed"
+	ConditionReasonCanaryFailedMessage = "The canary namespace failed its verification, the rest of the targets were not synchronized"
+
+	// A hook Job of the synchronization is running
+	ConditionReasonHookPending        = "HookPending"
+	ConditionReasonHookPendingMessage = "Waiting for a hook Job of the synchronization to complete"
+
+	// A hook Job of the synchronization failed
+	ConditionReasonHookFailed        = "HookFailed"
+	ConditionReasonHookFailedMessage = "A hook Job of the synchronization failed"
+
+	// The target matched no namespace, so nothing was written
+	ConditionReasonNoTargetNamespaces        = "NoTargetNamespaces"
+	ConditionReasonNoTargetNamespacesMessage = "The target matches no namespace, nothing was replicated. Check spec.target.namespaces"
+
 	// Success
 	ConditionReasonSourceSynced        = "SourceSynced"
 	ConditionReasonSourceSyncedMessage = "Source was successfully synchronized"
+
+	// The targets are being deleted along with the Replika
+	ConditionReasonTargetsDeleting        = "TargetsDeleting"
+	ConditionReasonTargetsDeletingMessage = "The Replika is being deleted, %d targets remaining"
+
+	// An operation with a high blast radius waits for the confirmation annotation
+	ConditionReasonPendingConfirmation                      = "PendingConfirmation"
+	ConditionReasonPendingSyncConfirmationMessage           = "The source is a Secret replicated in all the namespaces, confirm it with the annotation %s=%s"
+	ConditionReasonPendingDeletionConfirmationMessage       = "Deleting the Replika removes %d targets, confirm it with the annotation %s=%s"
+	ConditionReasonPendingSourceDeletionConfirmationMessage = "The source is missing, deleting its %d targets waits for the annotation %s=%s"
+
+	// The operator is paused by its configuration
+	ConditionReasonPaused        = "Paused"
+	ConditionReasonPausedMessage = "The operator is paused, the targets are not written until it is resumed"
+
+	// The synchronization is deferred by the window of the Replika
+	ConditionReasonSyncDeferred        = "SyncDeferred"
+	ConditionReasonSyncDeferredMessage = "The synchronization is deferred by the window until %s"
+
+	// The synchronization did not finish yet
+	ConditionReasonSyncPending        = "SyncPending"
+	ConditionReasonSyncPendingMessage = "The source was not synchronized yet"
 )
 
-// NewReplikaCondition a set of default options for creating a Replika Condition.
-func (r *ReplikaReconciler) NewReplikaCondition(condType string, status metav1.ConditionStatus, reason, message string) *metav1.Condition {
-	return &metav1.Condition{
-		Type:               condType,
-		Status:             status,
-		LastTransitionTime: metav1.Now(),
-		Reason:             reason,
-		Message:            message,
+// IsQuotaExceeded return true when the request was refused by a resource quota of the namespace
+func IsQuotaExceeded(err error) bool {
+	return apierrors.IsForbidden(err) && strings.Contains(err.Error(), "exceeded quota")
+}
+
+// IsAdmissionDenial return true when the error comes from an admission policy (validating webhooks like OPA
+// or Kyverno, Pod Security Admission...). Webhooks can deny with any status code, so the message is inspected
+func IsAdmissionDenial(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := err.Error()
+	switch {
+	case strings.Contains(message, "admission webhook") && strings.Contains(message, "denied the request"):
+		return true
+	case apierrors.IsForbidden(err) && (strings.Contains(message, "admission webhook") || strings.Contains(message, "violates PodSecurity")):
+		return true
+	}
+	return false
+}
+
+// IsAdmissionFailure return true when an admission webhook could not be called or failed to answer,
+// which only affects the namespaces it applies to
+func IsAdmissionFailure(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "failed calling webhook")
+}
+
+// IsImmutableTarget return true when the target can not be updated in place, being refused by the replicator
+// or by the API server because some of its immutable fields differ from the source
+func IsImmutableTarget(err error) bool {
+	return errors.Is(err, replicator.ErrImmutableTarget) || replicator.IsImmutableError(err)
+}
+
+// GetFailureReason return the condition reason and message matching an error returned by the API server.
+// The fallback ones are returned when the error is not specific enough
+func GetFailureReason(err error, fallbackReason, fallbackMessage string) (reason, message string) {
+	switch {
+	case apierrors.HasStatusCause(err, corev1.NamespaceTerminatingCause):
+		return ConditionReasonNamespaceTerminating, ConditionReasonNamespaceTerminatingMessage
+
+	case IsQuotaExceeded(err):
+		return ConditionReasonQuotaExceeded, ConditionReasonQuotaExceededMessage
+
+	case IsAdmissionDenial(err):
+		return ConditionReasonAdmissionDenied, ConditionReasonAdmissionDeniedMessage
+
+	case IsAdmissionFailure(err):
+		return ConditionReasonAdmissionFailed, ConditionReasonAdmissionFailedMessage
+
+	case errors.Is(err, context.DeadlineExceeded):
+		return ConditionReasonRequestTimeout, ConditionReasonRequestTimeoutMessage
+
+	case apierrors.IsConflict(err):
+		return ConditionReasonTargetModified, ConditionReasonTargetModifiedMessage
+
+	case apierrors.IsForbidden(err):
+		return ConditionReasonRBACDenied, ConditionReasonRBACDeniedMessage
+
+	case IsImmutableTarget(err):
+		return ConditionReasonImmutableField, ConditionReasonImmutableFieldMessage
+
+	case errors.Is(err, replicator.ErrTargetConflict):
+		return ConditionReasonTargetConflict, ConditionReasonTargetConflictMessage
+	}
+
+	return fallbackReason, fallbackMessage
+}
+
+// UpdateReadyCondition set the Ready condition from the SourceSynced one. It is only true when
+// the last synchronization finished without errors, taking the reason of the failure otherwise
+func (r *ReplikaReconciler) UpdateReadyCondition(replika *replikav1beta1.Replika, syncErr error) {
+
+	condition := conditions.New(ConditionTypeReady,
+		metav1.ConditionFalse,
+		ConditionReasonSyncPending,
+		ConditionReasonSyncPendingMessage,
+	)
+
+	syncedCondition := conditions.Get(replika.Status.Conditions, ConditionTypeSourceSynced)
+	if syncedCondition != nil {
+		condition.Reason = syncedCondition.Reason
+		condition.Message = syncedCondition.Message
+		if syncedCondition.Status == metav1.ConditionTrue && syncErr == nil {
+			condition.Status = metav1.ConditionTrue
+		}
 	}
+
+	r.SetReplikaCondition(replika, condition)
 }
 
-// GetReplikaCondition returns the condition with the provided type.
-func (r *ReplikaReconciler) GetReplikaCondition(replika *replikav1beta1.Replika, condType string) *metav1.Condition {
+// SetSyncedCondition set the SourceSynced condition after a synchronization without errors. A target matching
+// no namespace is not reported as a success, as nothing was written. On-demand Replikas are not included,
+// as they have no targets while nobody consumes the source
+func (r *ReplikaReconciler) SetSyncedCondition(replika *replikav1beta1.Replika) {
+
+	noTargets := replika.Status.TotalTargets == 0 && !replika.Spec.Target.OnDemand
+	setNoTargetNamespaces(replika.Namespace, replika.Name, noTargets)
+	if noTargets {
+		r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+			metav1.ConditionFalse,
+			ConditionReasonNoTargetNamespaces,
+			ConditionReasonNoTargetNamespacesMessage,
+		))
+		return
+	}
+
+	r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+		metav1.ConditionTrue,
+		ConditionReasonSourceSynced,
+		ConditionReasonSourceSyncedMessage,
+	))
+}
+
+// SetReplikaCondition create or update a condition inside the status of the CR, counting its transitions
+// and exposing its current state as a metric
+func (r *ReplikaReconciler) SetReplikaCondition(replika *replikav1beta1.Replika, condition metav1.Condition) {
+	condition.ObservedGeneration = replika.Generation
+	if conditions.Set(&replika.Status.Conditions, condition) {
+		observeConditionTransition(replika.Namespace, replika.Name, condition.Type, condition.Status)
+	}
+	setConditionState(replika.Namespace, replika.Name, condition.Type, condition.Status, condition.Reason)
+}
+
+// ResetNamespaceResults clear the results of the targets before a new synchronization
+func ResetNamespaceResults(replika *replikav1beta1.Replika) {
+	replika.Status.SyncedTargets = 0
+	replika.Status.FailedTargets = 0
+	replika.Status.SyncedNamespaces = nil
+	replika.Status.FailedNamespaces = nil
+}
+
+// AddSyncedNamespace record a target written in the status of the Replika
+func AddSyncedNamespace(replika *replikav1beta1.Replika, namespace string) {
+	replika.Status.SyncedTargets++
+	if len(replika.Status.SyncedNamespaces) < maxStatusNamespaces {
+		replika.Status.SyncedNamespaces = append(replika.Status.SyncedNamespaces, namespace)
+	}
+}
+
+// AddFailedNamespace record a target that failed in the status of the Replika
+func AddFailedNamespace(replika *replikav1beta1.Replika, namespace string) {
+	replika.Status.FailedTargets++
+	if len(replika.Status.FailedNamespaces) < maxStatusNamespaces {
+		replika.Status.FailedNamespaces = append(replika.Status.FailedNamespaces, namespace)
+	}
+}
+
+// RemoveNamespaceResults remove from the status of the Replika every result of a target namespace,
+// so a deleted namespace is not reported as failed. It return whether the status changed
+func RemoveNamespaceResults(replika *replikav1beta1.Replika, namespace string) (changed bool) {
+	status := &replika.Status
+
+	var removed bool
+	if status.SyncedNamespaces, removed = removeString(status.SyncedNamespaces, namespace); removed {
+		status.SyncedTargets--
+		changed = true
+	}
+	if status.FailedNamespaces, removed = removeString(status.FailedNamespaces, namespace); removed {
+		status.FailedTargets--
+		changed = true
+	}
+	if status.TotalTargets > 0 && changed {
+		status.TotalTargets--
+	}
+
+	rejected := status.RejectedNamespaces[:0]
+	for _, v := range status.RejectedNamespaces {
+		if v.Namespace != namespace {
+			rejected = append(rejected, v)
+		}
+	}
+	changed = changed || len(rejected) != len(status.RejectedNamespaces)
+	status.RejectedNamespaces = rejected
+	if len(status.RejectedNamespaces) == 0 {
+		status.RejectedNamespaces = nil
+	}
 
-	for i, v := range replika.Status.Conditions {
-		if v.Type == condType {
-			return &replika.Status.Conditions[i]
+	// The targets of a deleted namespace are gone with it
+	inventoried := len(status.Inventory)
+	RemoveInventoryEntries(replika, func(entry replikav1beta1.ReplikaInventoryEntry) bool {
+		return entry.Namespace == namespace
+	})
+	changed = changed || len(status.Inventory) != inventoried
+
+	consumers := status.Consumers[:0]
+	for _, v := range status.Consumers {
+		if v.Namespace != namespace {
+			consumers = append(consumers, v)
 		}
 	}
-	return nil
+	changed = changed || len(consumers) != len(status.Consumers)
+	status.ConsumersCount -= len(status.Consumers) - len(consumers)
+	status.Consumers = consumers
+	if len(status.Consumers) == 0 {
+		status.Consumers = nil
+	}
+
+	if status.Integrity != nil {
+		if status.Integrity.DriftedNamespaces, removed = removeString(status.Integrity.DriftedNamespaces, namespace); removed {
+			changed = true
+		}
+		if status.Integrity.MissingNamespaces, removed = removeString(status.Integrity.MissingNamespaces, namespace); removed {
+			changed = true
+		}
+		driftedTargets := status.Integrity.DriftedTargets[:0]
+		for _, v := range status.Integrity.DriftedTargets {
+			if v.Namespace != namespace {
+				driftedTargets = append(driftedTargets, v)
+			}
+		}
+		status.Integrity.DriftedTargets = driftedTargets
+		if len(status.Integrity.DriftedTargets) == 0 {
+			status.Integrity.DriftedTargets = nil
+		}
+	}
+
+	return changed
 }
 
-// UpdateReplikaCondition update or create a new condition inside the status of the CR
-func (r *ReplikaReconciler) UpdateReplikaCondition(replika *replikav1beta1.Replika, condition *metav1.Condition) {
+// removeString return the list without the value, and whether it was found
+func removeString(list []string, value string) (result []string, removed bool) {
+	for _, v := range list {
+		if v == value {
+			removed = true
+			continue
+		}
+		result = append(result, v)
+	}
+	return result, removed
+}
 
-	// Get the condition
-	currentCondition := r.GetReplikaCondition(replika, condition.Type)
+// GetStatusNamespaces return the target namespaces referenced by the status of the Replika
+func GetStatusNamespaces(replika *replikav1beta1.Replika) (namespaces map[string]bool) {
+	status := &replika.Status
 
-	if currentCondition == nil {
-		// Create the condition when not existent
-		replika.Status.Conditions = append(replika.Status.Conditions, *condition)
-	} else {
-		// Update the condition when existent.
-		currentCondition.Status = condition.Status
-		currentCondition.Reason = condition.Reason
-		currentCondition.Message = condition.Message
-		currentCondition.LastTransitionTime = metav1.Now()
+	namespaces = map[string]bool{}
+	for _, v := range status.SyncedNamespaces {
+		namespaces[v] = true
+	}
+	for _, v := range status.FailedNamespaces {
+		namespaces[v] = true
+	}
+	for _, v := range status.RejectedNamespaces {
+		namespaces[v.Namespace] = true
 	}
+	for _, v := range status.Consumers {
+		namespaces[v.Namespace] = true
+	}
+	for _, v := range status.Inventory {
+		namespaces[v.Namespace] = true
+	}
+	if status.Integrity != nil {
+		for _, v := range status.Integrity.DriftedNamespaces {
+			namespaces[v] = true
+		}
+		for _, v := range status.Integrity.MissingNamespaces {
+			namespaces[v] = true
+		}
+	}
+
+	return namespaces
 }
@@ -2,15 +2,27 @@ package controllers
 
 import (
 	"context"
-	"k8s.io/apimachinery/pkg/types"
+	"errors"
+	"fmt"
+	"path"
 	"regexp"
+	"sort"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/auditlog"
+	"prosimcorp.com/replika/pkg/celselector"
+	"prosimcorp.com/replika/pkg/conditions"
+	"prosimcorp.com/replika/pkg/replicator"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -19,10 +31,25 @@ const (
 	defaultTargetNamespace     = "default"
 	namespaceRegularExpression = "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
 
+	// Maximum size of a target, leaving room under the etcd request limit for the metadata set by the API server
+	maxTargetSize = 1024 * 1024
+
+	// Targets deleted on each reconciliation while the Replika is being deleted, reporting the progress between batches
+	targetsDeletionBatch = 100
+
+	// Targets of a batch deleted at the same time
+	targetsDeletionWorkers = 10
+
+	// Annotation requesting the list of the objects deleted along with the Replika in its status
+	deletionPreviewAnnotation = "replika.prosimcorp.com/deletion-preview"
+
 	// The Replika CR which created the resource
 	resourceReplikaLabelPartOfKey   = "replika.prosimcorp.com/part-of"
 	resourceReplikaLabelPartOfValue = ""
 
+	// The namespace of the Replika CR which created the resource, telling apart the Replikas with the same name
+	resourceReplikaLabelPartOfNamespaceKey = "replika.prosimcorp.com/part-of-namespace"
+
 	// Who is managing the child resources
 	resourceReplikaLabelCreatedKey   = "replika.prosimcorp.com/created-by"
 	resourceReplikaLabelCreatedValue = "replika-controller"
@@ -34,6 +61,104 @@ const (
 // GetNamespaces Returns the target namespaces of a Replika as a golang list
 // The namespace of the replicated source is NEVER listed to avoid overwrites
 func (r *ReplikaReconciler) GetNamespaces(ctx context.Context, replika *replikav1beta1.Replika) (namespaces []string, err error) {
+	return r.SelectNamespaces(ctx, replika.Spec.Target.Namespaces, replika.Spec.Source.Namespace)
+}
+
+// SelectNamespaces Returns the namespaces selected by the spec as a golang list
+// The excluded namespace is NEVER listed to avoid overwrites
+func (r *ReplikaReconciler) SelectNamespaces(ctx context.Context, namespacesSpec replikav1beta1.ReplikaTargetNamespacesSpec, excludedNamespace string) (namespaces []string, err error) {
+
+	namespaces, err = r.selectNamespaces(ctx, namespacesSpec, excludedNamespace)
+	if err != nil {
+		return namespaces, err
+	}
+
+	if namespacesSpec.RequireResource != nil {
+		namespaces, err = r.FilterNamespacesByResource(ctx, namespaces, namespacesSpec.RequireResource)
+		if err != nil {
+			return namespaces, err
+		}
+	}
+
+	// Keep the same order across synchronizations, so the logs, Events and status are stable
+	namespaces, err = SortNamespaces(namespaces, namespacesSpec.Order)
+	return namespaces, err
+}
+
+// SortNamespaces return the namespaces matching each entry of the order first, in the order of the entries,
+// followed by the rest. The namespaces matched by the same entry, and the rest, are sorted alphabetically
+func SortNamespaces(namespaces []string, order []string) (sorted []string, err error) {
+
+	rank := make(map[string]int, len(namespaces))
+	for _, ns := range namespaces {
+		rank[ns] = len(order)
+		for i, pattern := range order {
+			var matched bool
+			matched, err = path.Match(pattern, ns)
+			if err != nil {
+				err = NewPermanentErrorf(namespaceOrderError, pattern, err.Error())
+				return sorted, err
+			}
+			if matched {
+				rank[ns] = i
+				break
+			}
+		}
+	}
+
+	sorted = append(sorted, namespaces...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if rank[sorted[i]] != rank[sorted[j]] {
+			return rank[sorted[i]] < rank[sorted[j]]
+		}
+		return sorted[i] < sorted[j]
+	})
+
+	return sorted, err
+}
+
+// FilterNamespacesByResource return the namespaces containing at least one object of the required kind matching its selector.
+// Only the metadata of the objects is listed
+func (r *ReplikaReconciler) FilterNamespacesByResource(ctx context.Context, namespaces []string, required *replikav1beta1.ReplikaRequiredResourceSpec) (filtered []string, err error) {
+
+	listOptions := []client.ListOption{}
+	if required.Selector != nil {
+		var selector labels.Selector
+		selector, err = metav1.LabelSelectorAsSelector(required.Selector)
+		if err != nil {
+			err = NewPermanentErrorf(requiredResourceSelectorError, err.Error())
+			return filtered, err
+		}
+		listOptions = append(listOptions, client.MatchingLabelsSelector{Selector: selector})
+	}
+
+	objects := &metav1.PartialObjectMetadataList{}
+	objects.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   required.Group,
+		Version: required.Version,
+		Kind:    required.Kind + "List",
+	})
+	err = r.List(ctx, objects, listOptions...)
+	if err != nil {
+		return filtered, err
+	}
+
+	present := map[string]bool{}
+	for _, object := range objects.Items {
+		present[object.GetNamespace()] = true
+	}
+
+	for _, ns := range namespaces {
+		if present[ns] {
+			filtered = append(filtered, ns)
+		}
+	}
+
+	return filtered, err
+}
+
+// selectNamespaces return the namespaces selected by the spec, before checking the resources they contain
+func (r *ReplikaReconciler) selectNamespaces(ctx context.Context, namespacesSpec replikav1beta1.ReplikaTargetNamespacesSpec, excludedNamespace string) (namespaces []string, err error) {
 
 	// Loop and check the targets given by the user
 	var expression *regexp.Regexp
@@ -42,8 +167,20 @@ func (r *ReplikaReconciler) GetNamespaces(ctx context.Context, replika *replikav
 		return namespaces, err
 	}
 
-	// List ALL namespaces without blacklisted ones
-	if replika.Spec.Target.Namespaces.MatchAll {
+	settings := r.settings()
+
+	// Compile the expression selecting the namespaces, when defined
+	var selector *celselector.Selector
+	if namespacesSpec.CELExpression != "" {
+		selector, err = celselector.Compile(namespacesSpec.CELExpression)
+		if err != nil {
+			err = NewPermanentErrorf(celExpressionError, err.Error())
+			return namespaces, err
+		}
+	}
+
+	// List ALL namespaces without blacklisted ones. Those not matching the expression are excluded too
+	if namespacesSpec.MatchAll || selector != nil {
 
 		namespaceList := &corev1.NamespaceList{}
 		err = r.List(ctx, namespaceList)
@@ -53,20 +190,30 @@ func (r *ReplikaReconciler) GetNamespaces(ctx context.Context, replika *replikav
 
 		// Convert Namespace Objects into Strings
 	namespaceLoop:
-		for _, v := range namespaceList.Items {
+		for i, v := range namespaceList.Items {
 			ns := v.GetName()
 
-			// Do NOT include the namespace of the replicated source to avoid possible overwrites
-			if ns == replika.Spec.Source.Namespace {
+			// Do NOT include the excluded namespace to avoid possible overwrites
+			if ns == excludedNamespace {
+				continue
+			}
+
+			// Do NOT include the namespaces being deleted, their writes would be refused
+			if !v.DeletionTimestamp.IsZero() {
+				continue
+			}
+
+			// Do NOT include the namespaces protected by the operator settings
+			if settings.IsNamespaceProtected(ns) {
 				continue
 			}
 
 			// Exclude blacklisted namespaces
-			for _, excludedNs := range replika.Spec.Target.Namespaces.ExcludeFrom {
+			for _, excludedNs := range namespacesSpec.ExcludeFrom {
 
 				// Namespaces must be well formatted
 				if !expression.Match([]byte(excludedNs)) {
-					err = NewErrorf(namespaceFormatError, excludedNs)
+					err = NewPermanentErrorf(namespaceFormatError, excludedNs)
 					return namespaces, err
 				}
 
@@ -74,6 +221,20 @@ func (r *ReplikaReconciler) GetNamespaces(ctx context.Context, replika *replikav
 					continue namespaceLoop
 				}
 			}
+
+			// Exclude the namespaces not matching the expression
+			if selector != nil {
+				var matches bool
+				matches, err = selector.Matches(&namespaceList.Items[i])
+				if err != nil {
+					err = NewPermanentErrorf(celExpressionError, err.Error())
+					return namespaces, err
+				}
+				if !matches {
+					continue
+				}
+			}
+
 			namespaces = append(namespaces, ns)
 		}
 
@@ -81,23 +242,33 @@ func (r *ReplikaReconciler) GetNamespaces(ctx context.Context, replika *replikav
 	}
 
 	// Empty list of targets, only 'default' included
-	if len(replika.Spec.Target.Namespaces.ReplicateIn) == 0 {
-		if replika.Spec.Source.Namespace != defaultTargetNamespace {
+	if len(namespacesSpec.ReplicateIn) == 0 {
+		if settings.IsNamespaceProtected(defaultTargetNamespace) {
+			err = NewPermanentErrorf(protectedNamespaceError, defaultTargetNamespace)
+			return namespaces, err
+		}
+
+		if excludedNamespace != defaultTargetNamespace {
 			namespaces = append(namespaces, defaultTargetNamespace)
 			return namespaces, err
 		}
 
-		err = NewErrorf(sourceAndTargetSameNamespaceError, defaultTargetNamespace)
+		err = NewPermanentErrorf(sourceAndTargetSameNamespaceError, defaultTargetNamespace)
 		return namespaces, err
 	}
 
-	for _, v := range replika.Spec.Target.Namespaces.ReplicateIn {
-		if v == replika.Spec.Source.Namespace {
-			err = NewErrorf(sourceAndTargetSameNamespaceError, v)
+	for _, v := range namespacesSpec.ReplicateIn {
+		if v == excludedNamespace {
+			err = NewPermanentErrorf(sourceAndTargetSameNamespaceError, v)
 		}
 
 		if !expression.Match([]byte(v)) {
-			err = NewErrorf(namespaceFormatError, v)
+			err = NewPermanentErrorf(namespaceFormatError, v)
+			return namespaces, err
+		}
+
+		if settings.IsNamespaceProtected(v) {
+			err = NewPermanentErrorf(protectedNamespaceError, v)
 			return namespaces, err
 		}
 
@@ -107,33 +278,260 @@ func (r *ReplikaReconciler) GetNamespaces(ctx context.Context, replika *replikav
 	return namespaces, err
 }
 
-// GetSynchronizationTime return the spec.synchronization.time as duration, or default time on failures
+// GetSynchronizationTime return the spec.synchronization.time as duration, or default time on failures.
+// The default time comes from the operator settings
 func (r *ReplikaReconciler) GetSynchronizationTime(replika *replikav1beta1.Replika) (synchronizationTime time.Duration, err error) {
+	defaultTime := r.settings().DefaultSynchronizationTime
+	if replika.Spec.Synchronization.Time == "" {
+		synchronizationTime = defaultTime
+		return synchronizationTime, err
+	}
+
 	synchronizationTime, err = time.ParseDuration(replika.Spec.Synchronization.Time)
 	if err != nil {
-		synchronizationTime = defaultSynchronizationTime
-		err = NewErrorf(parseSyncTimeError, replika.Name)
+		synchronizationTime = defaultTime
+		err = NewPermanentErrorf(parseSyncTimeError, replika.Name)
 		return synchronizationTime, err
 	}
 
+	// The sources read more often than the Replika shorten its synchronization time
+	for _, sourceSpec := range append([]replikav1beta1.ReplikaSourceSpec{replika.Spec.Source}, replika.Spec.Sources...) {
+		if sourceSpec.SynchronizationTime == "" {
+			continue
+		}
+
+		var sourceTime time.Duration
+		sourceTime, err = time.ParseDuration(sourceSpec.SynchronizationTime)
+		if err != nil {
+			err = NewPermanentErrorf(parseSyncTimeError, replika.Name)
+			return synchronizationTime, err
+		}
+		synchronizationTime = min(synchronizationTime, sourceTime)
+	}
+
 	return synchronizationTime, err
 }
 
-// GetSource return the source resource that will be replicated
+// GetRequestTimeout return the timeout of each request of the Replika to the API server, taking the operator one
+// when not set. Zero means no timeout
+func (r *ReplikaReconciler) GetRequestTimeout(replika *replikav1beta1.Replika) (timeout time.Duration) {
+	timeout = r.RequestTimeout
+	if replika.Spec.Synchronization.RequestTimeout == "" {
+		return timeout
+	}
+
+	// Invalid durations are refused by the webhook, the operator timeout is kept for them
+	requestTimeout, err := time.ParseDuration(replika.Spec.Synchronization.RequestTimeout)
+	if err == nil {
+		timeout = requestTimeout
+	}
+	return timeout
+}
+
+// requestContext return the context of a single request of the Replika reading a source or writing a target,
+// so a hung API server, like a slow aggregated one, does not stall the worker
+func (r *ReplikaReconciler) requestContext(ctx context.Context, replika *replikav1beta1.Replika) (context.Context, context.CancelFunc) {
+	timeout := r.GetRequestTimeout(replika)
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// GetSourceRef return a string identifying the source of a Replika as group/version/Kind/namespace/name
+func GetSourceRef(replika *replikav1beta1.Replika) string {
+	groupVersion := schema.GroupVersion{Group: replika.Spec.Source.Group, Version: replika.Spec.Source.Version}
+	return groupVersion.String() + "/" + replika.Spec.Source.Kind + "/" + replika.Spec.Source.Namespace + "/" + replika.Spec.Source.Name
+}
+
+// GetMaxTargets return the maximum number of targets for a Replika.
+// The lowest limit between the Replika and the operator is used
+func (r *ReplikaReconciler) GetMaxTargets(replika *replikav1beta1.Replika) (maxTargets int) {
+	maxTargets = replika.Spec.Target.MaxTargets
+	if r.MaxTargets > 0 && (maxTargets <= 0 || r.MaxTargets < maxTargets) {
+		maxTargets = r.MaxTargets
+	}
+	return maxTargets
+}
+
+// CheckMaxTargets return a permanent error when the number of target namespaces exceeds the limit of the Replika
+func (r *ReplikaReconciler) CheckMaxTargets(replika *replikav1beta1.Replika, namespaces int) (err error) {
+	maxTargets := r.GetMaxTargets(replika)
+	if maxTargets > 0 && namespaces > maxTargets {
+		r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+			metav1.ConditionFalse,
+			ConditionReasonTooManyTargets,
+			ConditionReasonTooManyTargetsMessage, namespaces, maxTargets,
+		))
+		err = NewPermanentErrorf(tooManyTargetsError, namespaces, maxTargets)
+	}
+	return err
+}
+
+// GetSource return the source resource that will be replicated.
+// When several sources are defined, they are merged according to the merge policy of the Replika
 func (r *ReplikaReconciler) GetSource(ctx context.Context, replika *replikav1beta1.Replika) (source *unstructured.Unstructured, err error) {
 
 	// Get the source manifest
+	replika.Status.MergeConflicts = nil
+	source, err = r.GetCachedSourceObject(ctx, replika, replika.Spec.Source)
+	if err == nil {
+		err = r.CheckSourceReady(replika, replika.Spec.Source, source)
+	}
+	if err != nil || len(replika.Spec.Sources) == 0 {
+		return source, err
+	}
+
+	// Get the rest of the sources, which must be of the same kind to be merged
+	sources := []*unstructured.Unstructured{source}
+	for _, sourceSpec := range replika.Spec.Sources {
+		if sourceSpec.Group != replika.Spec.Source.Group || sourceSpec.Kind != replika.Spec.Source.Kind {
+			err = NewPermanentErrorf(mergedSourceKindError, sourceSpec.Namespace, sourceSpec.Name)
+			return source, err
+		}
+
+		var mergedSource *unstructured.Unstructured
+		mergedSource, err = r.GetCachedSourceObject(ctx, replika, sourceSpec)
+		if err == nil {
+			err = r.CheckSourceReady(replika, sourceSpec, mergedSource)
+		}
+		if err != nil {
+			return source, err
+		}
+		sources = append(sources, mergedSource)
+	}
+
+	source, err = r.MergeSources(replika, sources)
+	return source, err
+}
+
+// CheckSourceReady return a PendingError while the readiness expression of the source is not true,
+// so the replication waits for the controller of the source to complete it
+func (r *ReplikaReconciler) CheckSourceReady(replika *replikav1beta1.Replika, sourceSpec replikav1beta1.ReplikaSourceSpec,
+	source *unstructured.Unstructured) (err error) {
+
+	if sourceSpec.ReadyWhen == "" {
+		return err
+	}
+
+	var selector *celselector.Selector
+	selector, err = celselector.CompileFor(celselector.ObjectVariable, sourceSpec.ReadyWhen)
+	if err != nil {
+		err = NewPermanentErrorf(readyWhenError, err.Error())
+		return err
+	}
+
+	var ready bool
+	ready, err = selector.Matches(source)
+	if err != nil {
+		err = NewPermanentErrorf(readyWhenError, err.Error())
+		return err
+	}
+	if ready {
+		return err
+	}
+
+	r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+		metav1.ConditionFalse,
+		ConditionReasonSourceNotReady,
+		ConditionReasonSourceNotReadyMessage, source.GetNamespace(), source.GetName(),
+	))
+	err = &PendingError{reason: fmt.Sprintf(sourceNotReadyError, source.GetNamespace(), source.GetName())}
+	return err
+}
+
+// MergeSources return the sources merged according to the merge policy of the Replika,
+// recording the conflicting keys in its status
+func (r *ReplikaReconciler) MergeSources(replika *replikav1beta1.Replika, sources []*unstructured.Unstructured) (source *unstructured.Unstructured, err error) {
+
+	var conflicts []replicator.Conflict
+	source, conflicts = replicator.Merge(sources, replika.Spec.MergePolicy.Precedence)
+
+	for _, conflict := range conflicts {
+		replika.Status.MergeConflicts = append(replika.Status.MergeConflicts, replikav1beta1.ReplikaMergeConflictStatus{
+			Field:   conflict.Field,
+			Key:     conflict.Key,
+			Sources: conflict.Sources,
+		})
+	}
+
+	if len(conflicts) > 0 && replika.Spec.MergePolicy.FailOnConflict {
+		err = NewPermanentErrorf(mergeConflictError, len(conflicts))
+	}
+
+	return source, err
+}
+
+// GetCachedSourceObject return the object defined by a source spec. The sources with their own synchronization time
+// are taken from the last read until it expires
+func (r *ReplikaReconciler) GetCachedSourceObject(ctx context.Context, replika *replikav1beta1.Replika, sourceSpec replikav1beta1.ReplikaSourceSpec) (source *unstructured.Unstructured, err error) {
+
+	ctx, cancel := r.requestContext(ctx, replika)
+	defer cancel()
+
+	if r.SourceCache == nil || sourceSpec.SynchronizationTime == "" || sourceSpec.Inline != nil {
+		return r.GetSourceObject(ctx, sourceSpec)
+	}
+
+	var maxAge time.Duration
+	maxAge, err = time.ParseDuration(sourceSpec.SynchronizationTime)
+	if err != nil {
+		err = NewPermanentErrorf(parseSyncTimeError, replika.Name)
+		return source, err
+	}
+
+	key := types.NamespacedName{Namespace: replika.Namespace, Name: replika.Name}
+	sourceRef := sourceSpec.GetGroupVersionKind().String() + "/" + sourceSpec.Namespace + "/" + sourceSpec.Name
+	source, found := r.SourceCache.Get(key, sourceRef, maxAge)
+	if found {
+		return source, err
+	}
+
+	source, err = r.GetSourceObject(ctx, sourceSpec)
+	if err != nil {
+		return source, err
+	}
+	r.SourceCache.Set(key, sourceRef, source)
+
+	return source, err
+}
+
+// GetSourceObject return the object defined by a source spec. Inline sources are taken from the Replika
+func (r *ReplikaReconciler) GetSourceObject(ctx context.Context, sourceSpec replikav1beta1.ReplikaSourceSpec) (source *unstructured.Unstructured, err error) {
+
+	source, err = sourceSpec.GetInlineObject()
+	if err != nil {
+		err = NewPermanentErrorf(inlineSourceError, err.Error())
+		return source, err
+	}
+	if source != nil {
+		source.SetNamespace(sourceSpec.Namespace)
+		return source, err
+	}
+
 	source = &unstructured.Unstructured{}
 	source.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   replika.Spec.Source.Group,
-		Kind:    replika.Spec.Source.Kind,
-		Version: replika.Spec.Source.Version,
+		Group:   sourceSpec.Group,
+		Kind:    sourceSpec.Kind,
+		Version: sourceSpec.Version,
 	})
 
+	// Sources known to be missing are not read again until their informer sees them created
+	sourceRef := SourceRef{GVK: sourceSpec.GetGroupVersionKind(), Namespace: sourceSpec.Namespace, Name: sourceSpec.Name}
+	if r.SourceWatcher != nil {
+		err = r.SourceWatcher.GetMissing(sourceRef)
+		if err != nil {
+			return source, err
+		}
+	}
+
 	err = r.Get(ctx, client.ObjectKey{
-		Namespace: replika.Spec.Source.Namespace,
-		Name:      replika.Spec.Source.Name,
+		Namespace: sourceSpec.Namespace,
+		Name:      sourceSpec.Name,
 	}, source)
+	if apierrors.IsNotFound(err) && r.SourceWatcher != nil {
+		r.SourceWatcher.SetMissing(sourceRef, err)
+	}
 
 	return source, err
 }
@@ -141,23 +539,105 @@ func (r *ReplikaReconciler) GetSource(ctx context.Context, replika *replikav1bet
 // BuildTargets return a list with all the targets that will be created using the source
 func (r *ReplikaReconciler) BuildTargets(ctx context.Context, replika *replikav1beta1.Replika) (targets []unstructured.Unstructured, err error) {
 
+	// Check the kind of the source is allowed by the operator settings
+	settings := r.settings()
+	if !settings.IsKindAllowed(replika.Spec.Source.Group, replika.Spec.Source.Kind) {
+		r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+			metav1.ConditionFalse,
+			ConditionReasonSourceKindNotAllowed,
+			ConditionReasonSourceKindNotAllowedMessage,
+		))
+		err = NewPermanentErrorf(sourceKindNotAllowedError, replika.Spec.Source.Kind)
+		return targets, err
+	}
+
+	// Cluster-scoped kinds have no namespace to be copied into, so they are refused with their own reason
+	if replikav1beta1.IsClusterScoped(r.RESTMapper(), replika.Spec.Source.GetGroupVersionKind()) {
+		r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+			metav1.ConditionFalse,
+			ConditionReasonSourceClusterScoped,
+			ConditionReasonSourceClusterScopedMessage, replika.Spec.Source.Kind,
+		))
+		err = NewPermanentErrorf(sourceClusterScopedError, replika.Spec.Source.Kind)
+		return targets, err
+	}
+
+	// Collect the sources from several namespaces into a single target
+	if replika.Spec.Aggregation != nil {
+		targets, err = r.BuildAggregatedTargets(ctx, replika)
+		return targets, err
+	}
+
 	// Get the source from a replika
 	var source *unstructured.Unstructured
 	source, err = r.GetSource(ctx, replika)
+	if IsPendingError(err) {
+		return targets, err
+	}
+
+	// The targets of a deleted source are handled by its deletion policy
+	if apierrors.IsNotFound(err) {
+		err = r.HandleMissingSource(ctx, replika, err)
+		return targets, err
+	}
 	if err != nil {
-		r.UpdateReplikaCondition(replika, r.NewReplikaCondition(ConditionTypeSourceSynced,
+		reason, message := GetFailureReason(err, ConditionReasonSourceNotFound, ConditionReasonSourceNotFoundMessage)
+		r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
 			metav1.ConditionFalse,
-			ConditionReasonSourceNotFound,
-			ConditionReasonSourceNotFoundMessage,
+			reason,
+			message,
 		))
 		return targets, err
 	}
+	replika.Status.SourceMissingTime = nil
+
+	// Refuse to replicate a corrupt or expired certificate into every namespace
+	if replika.Spec.Source.ValidateTLS && replicator.IsTLSSecret(source) {
+		err = replicator.ValidateTLSSecret(source, time.Now())
+		if err != nil {
+			r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+				metav1.ConditionFalse,
+				ConditionReasonSourceInvalid,
+				ConditionReasonSourceInvalidMessage, err.Error(),
+			))
+			err = NewErrorf(sourceInvalidError, err.Error())
+			return targets, err
+		}
+	}
+
+	// Warn about the certificates close to their expiry. This is informative, so it never breaks the synchronization
+	if replika.Spec.Source.ExpiryWarning != "" && replicator.IsTLSSecret(source) {
+		err = r.CheckCertificateExpiry(replika, source, time.Now())
+		if err != nil {
+			LogErrorDedupf(ctx, certificateExpiryError, replika.Name, err.Error())
+			err = nil
+		}
+	}
+
+	// Keep only the selected subtrees of the source
+	if len(replika.Spec.Source.Fields) > 0 {
+		source, err = replicator.ProjectFields(source, replika.Spec.Source.Fields)
+		if err != nil {
+			err = NewPermanentError(err)
+			return targets, err
+		}
+	}
+
+	// Drop the labels of the tooling managing the source
+	replicator.StripLabels(source, replika.Spec.Target.StripLabels)
+
+	// Expose what is distributed, so the edits needing the targets to be recreated are explained
+	replika.Status.SourceImmutable = replicator.IsImmutable(source)
+	replika.Status.SourceHash, err = GetTargetHash(source, source)
+	if err != nil {
+		return targets, err
+	}
 
 	// Get the namespaces to generate targets
 	var namespaces []string
 	namespaces, err = r.GetNamespaces(ctx, replika)
 	if err != nil {
-		r.UpdateReplikaCondition(replika, r.NewReplikaCondition(ConditionTypeSourceSynced,
+		r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
 			metav1.ConditionFalse,
 			ConditionReasonTargetNamespaceNotFound,
 			ConditionReasonTargetNamespaceNotFoundMessage,
@@ -165,106 +645,794 @@ func (r *ReplikaReconciler) BuildTargets(ctx context.Context, replika *replikav1
 		return targets, err
 	}
 
-	// Copy source object and generate a clean target object
-	target := source.DeepCopy()
-	unstructured.RemoveNestedField(target.Object, "metadata")
-	unstructured.RemoveNestedField(target.Object, "status")
-	target.SetName(source.GetName())
-	target.SetAnnotations(source.GetAnnotations())
+	// Keep only the namespaces consuming the source
+	if replika.Spec.Target.OnDemand {
+		namespaces, err = r.FilterNamespacesByConsumers(ctx, namespaces, source)
+		if err != nil {
+			return targets, err
+		}
+	}
+
+	// Refuse to replicate into more namespaces than allowed
+	err = r.CheckMaxTargets(replika, len(namespaces))
+	if err != nil {
+		return targets, err
+	}
+
+	// Generate a clean copy of the source for each namespace, labeled with the identity of the source
+	var labels map[string]string
+	labels, err = GetTargetLabels(replika, source)
+	if err != nil {
+		return targets, err
+	}
+	targets = r.replicator().BuildTargets(source, namespaces, labels)
 
-	labels := make(map[string]string)
-	for k, v := range source.GetLabels() {
-		labels[k] = v
+	// Point the bindings to the ServiceAccounts of each target namespace
+	if replika.Spec.Target.RewriteSubjectNamespaces {
+		err = replicator.RewriteSubjectNamespaces(targets, source.GetNamespace())
+		if err != nil {
+			err = NewPermanentError(err)
+			return targets, err
+		}
 	}
-	labels[resourceReplikaLabelCreatedKey] = resourceReplikaLabelCreatedValue
-	labels[resourceReplikaLabelPartOfKey] = replika.Name
 
-	target.SetLabels(labels)
+	// Point the selectors of the NetworkPolicies to each target namespace
+	if replika.Spec.Target.TemplateSelectors {
+		replicator.TemplateSelectors(targets, source.GetNamespace())
+	}
 
-	// Add a new target to the list changing the namespace
-	targets = []unstructured.Unstructured{}
-	for _, ns := range namespaces {
-		target.SetNamespace(ns)
-		targets = append(targets, *target.DeepCopy())
+	// Point the credentials of the docker config Secrets to the mirrors of the registries
+	if len(replika.Spec.Target.RegistryRewrites) > 0 {
+		err = replicator.RewriteRegistries(targets, replika.Spec.Target.RegistryRewrites)
+		if err != nil {
+			err = NewPermanentError(err)
+			return targets, err
+		}
+	}
+
+	// Compose the rest of the outputs from the data of the source
+	if len(replika.Spec.Target.Outputs) > 0 {
+		var outputs []unstructured.Unstructured
+		outputs, err = r.BuildOutputs(replika, source, namespaces, labels)
+		if err != nil {
+			return targets, err
+		}
+		targets = append(targets, outputs...)
+	}
+
+	// Specialize the targets with the values owned by their namespaces
+	if replika.Spec.Target.LookupValues {
+		targets = r.ResolveLookups(ctx, replika, targets)
+	}
+
+	// Name the targets after their final content
+	if replika.Spec.Target.HashSuffix {
+		err = HashTargetNames(targets)
 	}
 
 	return targets, err
 }
 
-// UpdateTarget Update a target, or create when not existent
-func (r *ReplikaReconciler) UpdateTarget(ctx context.Context, target *unstructured.Unstructured) (err error) {
+// ResolveLookups replace the lookup placeholders of each target with the values of the ConfigMaps of its namespace.
+// The namespaces missing the ConfigMaps or their keys are recorded in the status of the Replika
+func (r *ReplikaReconciler) ResolveLookups(ctx context.Context, replika *replikav1beta1.Replika, targets []unstructured.Unstructured) (resolved []unstructured.Unstructured) {
 
-	// Look for the target in the target namespace
-	tmpTarget := target.DeepCopy()
-	err = r.Get(ctx, client.ObjectKey{
-		Namespace: target.GetNamespace(),
-		Name:      tmpTarget.GetName(),
-	}, tmpTarget)
+	for i := range targets {
+		namespace := targets[i].GetNamespace()
+		configMaps := map[string]*corev1.ConfigMap{}
+
+		err := replicator.ResolveLookups(&targets[i], func(name, key string) (value string, err error) {
+			configMap, found := configMaps[name]
+			if !found {
+				configMap = &corev1.ConfigMap{}
+				err = r.uncachedReader().Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, configMap)
+				if err != nil {
+					return value, err
+				}
+				configMaps[name] = configMap
+			}
+
+			value, found = configMap.Data[key]
+			if !found {
+				err = fmt.Errorf(lookupKeyNotFoundMessage, key, namespace, name)
+			}
+			return value, err
+		})
+		if err != nil {
+			LogErrorDedupf(ctx, lookupError, namespace, err.Error())
+			replika.Status.RejectedNamespaces = append(replika.Status.RejectedNamespaces, replikav1beta1.ReplikaNamespaceStatus{
+				Namespace: namespace,
+				Reason:    ConditionReasonLookupFailed,
+				Message:   err.Error(),
+			})
+			AddFailedNamespace(replika, namespace)
+			continue
+		}
+		resolved = append(resolved, targets[i])
+	}
+
+	return resolved
+}
 
-	// Create the resource when it is not found
+// UpdateTarget Update a target, or create when not existent.
+// When dryRun is set, the request is only validated by the API server and nothing is persisted
+func (r *ReplikaReconciler) UpdateTarget(ctx context.Context, replika *replikav1beta1.Replika, target *unstructured.Unstructured, dryRun bool) (result replicator.Result, err error) {
+	return r.targetReplicator(replika).UpdateTarget(ctx, target, dryRun)
+}
+
+// RecreateTarget delete the existing target and create it again, replacing the immutable fields that can not be updated.
+// The object is only deleted when it was created by the controller, so foreign objects are never replaced
+func (r *ReplikaReconciler) RecreateTarget(ctx context.Context, replika *replikav1beta1.Replika, target *unstructured.Unstructured) (result replicator.Result, err error) {
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(target.GroupVersionKind())
+	err = r.Get(ctx, client.ObjectKeyFromObject(target), existing)
+	if client.IgnoreNotFound(err) != nil {
+		return result, err
+	}
+	if err == nil {
+		if !IsCreatedByController(existing) {
+			err = fmt.Errorf("%w: %s %s/%s", replicator.ErrTargetConflict, target.GetKind(), target.GetNamespace(), target.GetName())
+			return result, err
+		}
+		uid := existing.GetUID()
+		err = client.IgnoreNotFound(r.Delete(ctx, existing, client.Preconditions{UID: &uid}))
+		if err != nil {
+			return result, err
+		}
+	}
+
+	result, err = r.UpdateTarget(ctx, replika, target, false)
 	if err != nil {
-		err = r.Create(ctx, target.DeepCopy())
-		return err
+		return result, err
 	}
 
-	// Update the object
-	patch, err := target.MarshalJSON()
-	err = r.Patch(ctx, target, client.RawPatch(types.MergePatchType, patch))
+	LogInfof(ctx, targetRecreated, target.GetKind(), target.GetNamespace(), target.GetName())
+	result = replicator.ResultRecreated
+	return result, err
+}
 
-	return err
+// GetParallelism return the number of targets of the Replika written at the same time
+func GetParallelism(replika *replikav1beta1.Replika) int {
+	if replika.Spec.Synchronization.Parallelism < 1 {
+		return 1
+	}
+	return replika.Spec.Synchronization.Parallelism
+}
+
+// WriteTargets write the targets at the same time, returning the result and error of each one in the same order.
+// Each write is bounded by the request timeout of the Replika
+func (r *ReplikaReconciler) WriteTargets(ctx context.Context, replika *replikav1beta1.Replika, targets []unstructured.Unstructured) (results []replicator.Result, errs []error) {
+
+	results = make([]replicator.Result, len(targets))
+	errs = make([]error, len(targets))
+
+	var waitGroup sync.WaitGroup
+	for i := range targets {
+		waitGroup.Add(1)
+		go func(i int) {
+			defer waitGroup.Done()
+			requestCtx, cancel := r.requestContext(ctx, replika)
+			defer cancel()
+			results[i], errs[i] = r.UpdateTarget(requestCtx, replika, &targets[i], false)
+		}(i)
+	}
+	waitGroup.Wait()
+
+	return results, errs
+}
+
+// ValidateTargets run a server-side dry-run for each target and return those accepted by the API server.
+// Rejections from admission controllers or quotas are recorded per namespace in the status of the Replika
+func (r *ReplikaReconciler) ValidateTargets(ctx context.Context, replika *replikav1beta1.Replika, targets []unstructured.Unstructured) (accepted []unstructured.Unstructured) {
+
+	for i := range targets {
+		requestCtx, cancel := r.requestContext(ctx, replika)
+		_, err := r.UpdateTarget(requestCtx, replika, &targets[i], true)
+		cancel()
+		if err != nil {
+			LogErrorDedupf(ctx, targetDryRunRejectedError, targets[i].GetNamespace(), err.Error())
+			reason, _ := GetFailureReason(err, ConditionReasonTargetValidationFailed, "")
+			replika.Status.RejectedNamespaces = append(replika.Status.RejectedNamespaces, replikav1beta1.ReplikaNamespaceStatus{
+				Namespace: targets[i].GetNamespace(),
+				Reason:    reason,
+				Message:   err.Error(),
+			})
+			AddFailedNamespace(replika, targets[i].GetNamespace())
+			continue
+		}
+		accepted = append(accepted, targets[i])
+	}
+
+	return accepted
+}
+
+// CheckTargetsSize return the targets whose size can be stored by the API server.
+// Oversized targets are recorded per namespace in the status of the Replika instead of letting each write fail
+func (r *ReplikaReconciler) CheckTargetsSize(ctx context.Context, replika *replikav1beta1.Replika, targets []unstructured.Unstructured) (accepted []unstructured.Unstructured) {
+
+	oversized := 0
+	for i := range targets {
+		targetJSON, err := targets[i].MarshalJSON()
+		if err == nil && len(targetJSON) <= maxTargetSize {
+			accepted = append(accepted, targets[i])
+			continue
+		}
+
+		message := fmt.Sprintf(targetTooLargeMessage, len(targetJSON), maxTargetSize)
+		if err != nil {
+			message = err.Error()
+		}
+
+		LogErrorDedupf(ctx, targetTooLargeError, targets[i].GetNamespace(), message)
+		replika.Status.RejectedNamespaces = append(replika.Status.RejectedNamespaces, replikav1beta1.ReplikaNamespaceStatus{
+			Namespace: targets[i].GetNamespace(),
+			Reason:    ConditionReasonTargetTooLarge,
+			Message:   message,
+		})
+		AddFailedNamespace(replika, targets[i].GetNamespace())
+		oversized++
+	}
+
+	setReplikaGauge(oversizedTargets, replika.Namespace, replika.Name, float64(oversized))
+
+	return accepted
+}
+
+// SkipBackoffTargets return the targets whose namespace is not backing off after failing on its own,
+// like an exceeded quota or a broken admission webhook. The namespaces held are recorded in the status of the Replika
+func (r *ReplikaReconciler) SkipBackoffTargets(ctx context.Context, replika *replikav1beta1.Replika, targets []unstructured.Unstructured) (pending []unstructured.Unstructured) {
+
+	key := types.NamespacedName{Namespace: replika.Namespace, Name: replika.Name}
+	for i := range targets {
+		retry, backingOff := r.Failures.GetNamespaceRetry(key, targets[i].GetNamespace())
+		if !backingOff {
+			pending = append(pending, targets[i])
+			continue
+		}
+
+		replika.Status.RejectedNamespaces = append(replika.Status.RejectedNamespaces, NewBackoffNamespaceStatus(targets[i].GetNamespace(), retry))
+		AddFailedNamespace(replika, targets[i].GetNamespace())
+	}
+
+	return pending
+}
+
+// RejectNamespace record the target namespace as rejected for the reason. The namespace is backed off when
+// its failures are tracked, so it is not written on each synchronization while the rest of them are
+func (r *ReplikaReconciler) RejectNamespace(replika *replikav1beta1.Replika, namespace, reason, message string) {
+
+	status := replikav1beta1.ReplikaNamespaceStatus{
+		Namespace: namespace,
+		Reason:    reason,
+		Message:   message,
+	}
+	if r.Failures != nil {
+		retry := r.Failures.RecordNamespaceFailure(types.NamespacedName{Namespace: replika.Namespace, Name: replika.Name}, namespace, reason, message)
+		status = NewBackoffNamespaceStatus(namespace, retry)
+	}
+
+	replika.Status.RejectedNamespaces = append(replika.Status.RejectedNamespaces, status)
+}
+
+// NewBackoffNamespaceStatus return the status of a target namespace backing off, with its retry state
+func NewBackoffNamespaceStatus(namespace string, retry NamespaceRetry) replikav1beta1.ReplikaNamespaceStatus {
+	return replikav1beta1.ReplikaNamespaceStatus{
+		Namespace: namespace,
+		Reason:    retry.Reason,
+		Message:   fmt.Sprintf(targetBackoffMessage, retry.RetryTime.Format(time.RFC3339), retry.Message),
+		Failures:  retry.Failures,
+		RetryTime: &metav1.Time{Time: retry.RetryTime},
+	}
 }
 
 // UpdateTargets Synchronizes all the targets from a source declared on a Replika
 func (r *ReplikaReconciler) UpdateTargets(ctx context.Context, replika *replikav1beta1.Replika) (err error) {
 
-	// Get a list of manifests for all the targets
+	// Get a list of manifests for all the targets. The namespaces rejected while building them are recorded
+	replika.Status.RejectedNamespaces = nil
+	ResetNamespaceResults(replika)
 	var targets []unstructured.Unstructured
 	targets, err = r.BuildTargets(ctx, replika)
 	if err != nil {
 		return err
 	}
 
-	// Create the resource inside target namespaces
-	// Needed to create a copy and change the namespace between loops
-	for i := range targets {
-		err = r.UpdateTarget(ctx, &targets[i])
+	// Hold the operations with a high blast radius until they are confirmed
+	err = r.CheckSyncConfirmation(ctx, replika, targets)
+	if err != nil {
+		return err
+	}
+
+	// Stamp the targets with the Replika writing them, so the copies of older specs are told apart
+	StampTargets(replika, targets)
+
+	replika.Status.TotalTargets = len(targets) + len(replika.Status.RejectedNamespaces)
+
+	// Nothing is written while the operator is paused
+	if r.settings().Paused {
+		r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+			metav1.ConditionFalse,
+			ConditionReasonPaused,
+			ConditionReasonPausedMessage,
+		))
+		return errPaused
+	}
+
+	// Notify the HTTP hooks before and after the synchronization of the targets
+	err = r.CallHTTPHooks(ctx, replika, hookPhasePreSync, targets, nil)
+	if err != nil {
+		return err
+	}
+
+	err = r.SyncTargets(ctx, replika, targets)
+
+	// Remove the targets written before that are not computed anymore, or were written by an older spec
+	if err == nil && !replika.Spec.Synchronization.AuditOnly {
+		err = r.PruneInventory(ctx, replika, targets)
+		if err == nil {
+			err = r.PruneStaleTargets(ctx, replika)
+		}
+		if err == nil && replika.Spec.Target.Anchor {
+			err = r.PruneAnchors(ctx, replika, targets)
+		}
+		if err != nil {
+			LogErrorDedupf(ctx, inventoryPruneError, replika.Name, err.Error())
+		}
+	}
+
+	// Remove the targets whose last consumer disappeared
+	if err == nil && replika.Spec.Target.OnDemand && !replika.Spec.Synchronization.AuditOnly {
+		err = r.PruneUnconsumedTargets(ctx, replika, targets)
+		if err != nil {
+			LogErrorDedupf(ctx, unconsumedTargetsPruneError, replika.Name, err.Error())
+		}
+	}
+
+	hookErr := r.CallHTTPHooks(ctx, replika, hookPhasePostSync, targets, err)
+	if err == nil {
+		err = hookErr
+	}
+
+	return err
+}
+
+// SyncTargets write the targets, or only audit them for the audit-only Replikas
+func (r *ReplikaReconciler) SyncTargets(ctx context.Context, replika *replikav1beta1.Replika, targets []unstructured.Unstructured) (err error) {
+
+	// Audit the targets against the source when scheduled
+	if r.IsAuditDue(replika) {
+		err = r.AuditTargets(ctx, replika, targets)
+		if err != nil {
+			LogErrorDedupf(ctx, auditTargetsError, replika.Name, err.Error())
+			if replika.Spec.Synchronization.AuditOnly {
+				return err
+			}
+			err = nil
+		}
+	}
+
+	// Audit-only Replikas never write the targets
+	if replika.Spec.Synchronization.AuditOnly {
+		condition := conditions.New(ConditionTypeSourceSynced,
+			metav1.ConditionTrue,
+			ConditionReasonAuditPassed,
+			ConditionReasonAuditPassedMessage,
+		)
+		if len(replika.Status.Integrity.DriftedNamespaces) > 0 || len(replika.Status.Integrity.MissingNamespaces) > 0 {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = ConditionReasonTargetsDrifted
+			condition.Message = ConditionReasonTargetsDriftedMessage
+		}
+		r.SetReplikaCondition(replika, condition)
+		return err
+	}
+
+	// Discard the targets too large to be stored
+	targets = r.CheckTargetsSize(ctx, replika, targets)
+
+	// Hold the namespaces failing on their own until their backoff expires
+	if r.Failures != nil {
+		targets = r.SkipBackoffTargets(ctx, replika, targets)
+	}
+
+	// Validate the targets against the API server before writing them
+	if replika.Spec.Synchronization.DryRunValidation {
+		targets = r.ValidateTargets(ctx, replika, targets)
+	}
+
+	// Run the pre-sync hook before writing anything
+	err = r.RunHooks(ctx, replika, targets, hookPhasePreSync)
+	if err != nil {
+		return err
+	}
+
+	// Own the targets by the anchors of their namespaces
+	if replika.Spec.Target.Anchor {
+		err = r.SetAnchors(ctx, replika, targets)
 		if err != nil {
-			r.UpdateReplikaCondition(replika, r.NewReplikaCondition(ConditionTypeSourceSynced,
+			reason, message := GetFailureReason(err, ConditionReasonSourceReplicationFailed, ConditionReasonSourceReplicationFailedMessage)
+			r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
 				metav1.ConditionFalse,
-				ConditionReasonSourceReplicationFailed,
-				ConditionReasonSourceReplicationFailedMessage,
+				reason,
+				message,
 			))
 			return err
 		}
 	}
 
+	// Write and verify the canary namespace before the rest of the targets
+	if replika.Spec.Target.Rollout.Canary != nil {
+		targets, err = r.RolloutCanary(ctx, replika, targets)
+		if err != nil {
+			return err
+		}
+	} else {
+		replika.Status.Canary = nil
+	}
+
+	// Create the resource inside target namespaces by waves of the parallelism of the Replika.
+	// The results of each wave are processed in order once all its targets are written
+	parallelism := GetParallelism(replika)
+	for start := 0; start < len(targets); start += parallelism {
+		results, errs := r.WriteTargets(ctx, replika, targets[start:min(start+parallelism, len(targets))])
+		for j := range results {
+			i := start + j
+			result := results[j]
+			err = errs[j]
+
+			// Replace the targets that can not be updated in place when allowed
+			if IsImmutableTarget(err) && replika.Spec.Target.RecreateImmutable {
+				requestCtx, cancel := r.requestContext(ctx, replika)
+				result, err = r.RecreateTarget(requestCtx, replika, &targets[i])
+				cancel()
+			}
+			observeTargetWrite(replika.Namespace, replika.Name, &targets[i], result, err)
+
+			// The namespaces being deleted are not failures, they will be gone on the next synchronization
+			if apierrors.HasStatusCause(err, corev1.NamespaceTerminatingCause) {
+				LogInfof(ctx, targetNamespaceTerminating, targets[i].GetNamespace(), err.Error())
+				err = nil
+				continue
+			}
+
+			if err != nil {
+				incTargetWriteErrors(replika.Namespace, replika.Name, targets[i].GetNamespace())
+				AddFailedNamespace(replika, targets[i].GetNamespace())
+
+				// Exceeded quotas only refuse their namespace, which is backed off to avoid failing on each retry
+				if IsQuotaExceeded(err) {
+					LogErrorDedupf(ctx, targetQuotaExceededError, targets[i].GetNamespace(), err.Error())
+					r.RejectNamespace(replika, targets[i].GetNamespace(), ConditionReasonQuotaExceeded, err.Error())
+					err = nil
+					continue
+				}
+
+				// Admission policies only deny their namespace, the denial is reported and the rest of the targets written.
+				// The namespace is backed off too, so a broken webhook does not fail on each synchronization
+				if IsAdmissionDenial(err) {
+					LogErrorDedupf(ctx, targetAdmissionDeniedError, targets[i].GetNamespace(), err.Error())
+					r.RejectNamespace(replika, targets[i].GetNamespace(), ConditionReasonAdmissionDenied, err.Error())
+					err = nil
+					continue
+				}
+				if IsAdmissionFailure(err) {
+					LogErrorDedupf(ctx, targetAdmissionFailedError, targets[i].GetNamespace(), err.Error())
+					r.RejectNamespace(replika, targets[i].GetNamespace(), ConditionReasonAdmissionFailed, err.Error())
+					err = nil
+					continue
+				}
+
+				// The targets that can not be updated in place are refused, as they are only replaced when allowed
+				if IsImmutableTarget(err) {
+					LogErrorDedupf(ctx, targetImmutableError, targets[i].GetNamespace(), err.Error())
+					replika.Status.RejectedNamespaces = append(replika.Status.RejectedNamespaces, replikav1beta1.ReplikaNamespaceStatus{
+						Namespace: targets[i].GetNamespace(),
+						Reason:    ConditionReasonImmutableField,
+						Message:   err.Error(),
+					})
+					err = nil
+					continue
+				}
+				// The baseline objects of the namespace owners are never overwritten, the rest of the targets are written
+				if errors.Is(err, replicator.ErrTargetConflict) {
+					LogErrorDedupf(ctx, targetConflictError, targets[i].GetNamespace(), err.Error())
+					if r.Recorder != nil {
+						r.Recorder.Eventf(replika, corev1.EventTypeWarning, ConditionReasonTargetConflict, targetConflictError,
+							targets[i].GetNamespace(), err.Error())
+					}
+					replika.Status.RejectedNamespaces = append(replika.Status.RejectedNamespaces, replikav1beta1.ReplikaNamespaceStatus{
+						Namespace: targets[i].GetNamespace(),
+						Reason:    ConditionReasonTargetConflict,
+						Message:   err.Error(),
+					})
+					err = nil
+					continue
+				}
+
+				reason, message := GetFailureReason(err, ConditionReasonSourceReplicationFailed, ConditionReasonSourceReplicationFailedMessage)
+				r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+					metav1.ConditionFalse,
+					reason,
+					message,
+				))
+				return err
+			}
+			AddSyncedNamespace(replika, targets[i].GetNamespace())
+			AddInventoryEntries(replika, NewInventoryEntry(&targets[i]))
+			if r.Failures != nil {
+				r.Failures.ForgetNamespaceFailures(types.NamespacedName{Namespace: replika.Namespace, Name: replika.Name}, targets[i].GetNamespace())
+			}
+			r.RecordAuditLog(ctx, replika, &targets[i], auditActions[result], auditReasonSynchronization)
+
+			// Leave a trail of the change in the namespace of the target when requested
+			if replika.Spec.Target.RecordEvents && result != replicator.ResultUnchanged {
+				r.RecordTargetWrite(replika, &targets[i], result)
+			}
+
+			// Roll the workloads consuming the target when requested, only when its content was written.
+			// The targets named after their content are always switched, the new object is unused otherwise
+			hashedName := targets[i].GetAnnotations()[targetBaseNameAnnotation] != ""
+			if (replika.Spec.Target.ReloadWorkloads || hashedName) && result != replicator.ResultUnchanged {
+				err = r.ReloadWorkloads(ctx, replika, &targets[i])
+				if err != nil {
+					LogErrorDedupf(ctx, workloadReloadError, targets[i].GetNamespace(), err.Error())
+					r.SetReplikaCondition(replika, conditions.New(ConditionTypeSourceSynced,
+						metav1.ConditionFalse,
+						ConditionReasonWorkloadReloadFailed,
+						ConditionReasonWorkloadReloadFailedMessage,
+					))
+					return err
+				}
+			}
+		}
+	}
+
+	// Discover the workloads consuming the targets. This is informative, so it never breaks the synchronization
+	err = r.UpdateConsumers(ctx, replika, targets)
+	if err != nil {
+		LogErrorDedupf(ctx, consumersDiscoveryError, replika.Name, err.Error())
+		err = nil
+	}
+
+	// Some namespaces rejected the target
+	if len(replika.Status.RejectedNamespaces) > 0 {
+		condition := conditions.New(ConditionTypeSourceSynced,
+			metav1.ConditionFalse,
+			ConditionReasonTargetValidationFailed,
+			ConditionReasonTargetValidationFailedMessage,
+		)
+		switch replika.Status.RejectedNamespaces[0].Reason {
+		case ConditionReasonTargetTooLarge:
+			condition.Reason = ConditionReasonTargetTooLarge
+			condition.Message = ConditionReasonTargetTooLargeMessage
+		case ConditionReasonAdmissionDenied:
+			condition.Reason = ConditionReasonAdmissionDenied
+			condition.Message = ConditionReasonAdmissionDeniedMessage
+		case ConditionReasonTargetConflict:
+			condition.Reason = ConditionReasonTargetConflict
+			condition.Message = ConditionReasonTargetConflictMessage
+		case ConditionReasonImmutableField:
+			condition.Reason = ConditionReasonImmutableField
+			condition.Message = ConditionReasonImmutableFieldMessage
+		case ConditionReasonLookupFailed:
+			condition.Reason = ConditionReasonLookupFailed
+			condition.Message = ConditionReasonLookupFailedMessage
+		case ConditionReasonQuotaExceeded:
+			condition.Reason = ConditionReasonQuotaExceeded
+			condition.Message = ConditionReasonQuotaExceededMessage
+		}
+		r.SetReplikaCondition(replika, condition)
+		err = NewErrorf(targetsRejectedError, len(replika.Status.RejectedNamespaces))
+		return err
+	}
+
+	// Run the post-sync hook once all the targets are written
+	err = r.RunHooks(ctx, replika, targets, hookPhasePostSync)
+
 	return err
 }
 
-// DeleteTargets Delete all the targets previously created from a source declared on a Replika
-func (r *ReplikaReconciler) DeleteTargets(ctx context.Context, replika *replikav1beta1.Replika) (err error) {
+// IsCreatedByController return true for the objects carrying the created-by label of the controller
+func IsCreatedByController(object metav1.Object) bool {
+	return object.GetLabels()[resourceReplikaLabelCreatedKey] == resourceReplikaLabelCreatedValue
+}
+
+// IsPartOfReplika return true for the objects labeled as part of the Replika, by its namespace and name.
+// The objects labeled before the namespace label existed belong to the Replika only when they were written by it
+func IsPartOfReplika(replika *replikav1beta1.Replika, object metav1.Object) bool {
+
+	labels := object.GetLabels()
+	if labels[resourceReplikaLabelPartOfKey] != replika.Name {
+		return false
+	}
+
+	namespace, found := labels[resourceReplikaLabelPartOfNamespaceKey]
+	if !found {
+		return object.GetAnnotations()[targetReplikaUIDAnnotation] == string(replika.UID)
+	}
+	return namespace == replika.Namespace
+}
+
+// ListTargets return the targets created from the source declared on a Replika, across all the namespaces.
+// Objects labeled as part of the Replika but not created by the controller are returned apart, as foreign,
+// so they are never deleted. Objects part of a Replika with the same name in another namespace are never returned
+func (r *ReplikaReconciler) ListTargets(ctx context.Context, replika *replikav1beta1.Replika) (targets, foreign []unstructured.Unstructured, err error) {
 
-	// Construct a target list object
-	targets := &unstructured.UnstructuredList{}
-	targets.SetGroupVersionKind(schema.GroupVersionKind{
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
 		Group:   replika.Spec.Source.Group,
 		Kind:    replika.Spec.Source.Kind,
 		Version: replika.Spec.Source.Version,
 	})
+	err = r.List(ctx, list, client.MatchingLabels{resourceReplikaLabelPartOfKey: replika.Name})
+	if err != nil {
+		return targets, foreign, err
+	}
 
-	// Look for the targets inside the cluster
-	err = r.List(ctx, targets, client.MatchingLabels{resourceReplikaLabelPartOfKey: replika.Name})
+	for i := range list.Items {
+		if !IsPartOfReplika(replika, &list.Items[i]) {
+			continue
+		}
+		if !IsCreatedByController(&list.Items[i]) {
+			foreign = append(foreign, list.Items[i])
+			continue
+		}
+		targets = append(targets, list.Items[i])
+	}
+
+	return targets, foreign, err
+}
+
+// PreviewDeletion record in the status the objects DeleteTargets would remove when the Replika is deleted,
+// while the deletion preview annotation is 'true'
+func (r *ReplikaReconciler) PreviewDeletion(ctx context.Context, replika *replikav1beta1.Replika) (err error) {
+
+	if replika.GetAnnotations()[deletionPreviewAnnotation] != "true" {
+		replika.Status.DeletionPreview = nil
+		return err
+	}
+
+	var targets []unstructured.Unstructured
+	targets, _, err = r.ListTargets(ctx, replika)
 	if err != nil {
 		return err
 	}
 
-	// Delete the targets
-	for i := range targets.Items {
-		err = r.Delete(ctx, &targets.Items[i])
-		if err != nil {
-			return err
-		}
+	anchors := &corev1.ConfigMapList{}
+	err = r.uncachedReader().List(ctx, anchors, client.MatchingLabels(GetAnchorLabels(replika)))
+	if err != nil {
+		return err
+	}
+
+	var objects []string
+	for _, target := range targets {
+		objects = append(objects, target.GetNamespace()+"/"+target.GetName())
+	}
+	for _, anchor := range anchors.Items {
+		objects = append(objects, anchor.Namespace+"/"+anchor.Name)
+	}
+
+	replika.Status.DeletionPreview = &replikav1beta1.ReplikaDeletionPreviewStatus{
+		Time:    metav1.Now(),
+		Targets: len(objects),
+	}
+	if len(objects) > maxStatusNamespaces {
+		objects = objects[:maxStatusNamespaces]
 	}
+	replika.Status.DeletionPreview.Objects = objects
 
 	return err
 }
+
+// DeleteTargets Delete a batch of the targets previously created from a source declared on a Replika in parallel,
+// returning how many of them remain. The failures of some namespaces do not stop the rest of the batch,
+// being returned together. The anchors are deleted once no target remains.
+// An Event is emitted for each namespace, so long deletions can be followed.
+// Objects not created by the controller are never deleted, being reported instead
+func (r *ReplikaReconciler) DeleteTargets(ctx context.Context, replika *replikav1beta1.Replika) (remaining int, err error) {
+
+	var targets, foreign []unstructured.Unstructured
+	targets, foreign, err = r.ListTargets(ctx, replika)
+	if err != nil {
+		return remaining, err
+	}
+
+	// The targets written before and not listed anymore, like the ones of a previous source, are deleted too
+	var inventoried []unstructured.Unstructured
+	inventoried, err = r.ListInventoryTargets(ctx, replika, targets)
+	if err != nil {
+		return remaining, err
+	}
+	targets = append(targets, inventoried...)
+
+	for i := range foreign {
+		LogErrorDedupf(ctx, targetNotCreatedError, foreign[i].GetNamespace(), foreign[i].GetName())
+		if r.Recorder != nil {
+			r.Recorder.Eventf(replika, corev1.EventTypeWarning, targetNotCreatedEventReason, targetNotCreatedError,
+				foreign[i].GetNamespace(), foreign[i].GetName())
+		}
+	}
+
+	var deleteOptions []client.DeleteOption
+	if replika.Spec.Target.DeletionPropagation != "" {
+		deleteOptions = append(deleteOptions, client.PropagationPolicy(metav1.DeletionPropagation(replika.Spec.Target.DeletionPropagation)))
+	}
+
+	batch := targets
+	if len(batch) > targetsDeletionBatch {
+		batch = batch[:targetsDeletionBatch]
+	}
+
+	var mutex sync.Mutex
+	var waitGroup sync.WaitGroup
+	var errs []error
+	workers := make(chan struct{}, targetsDeletionWorkers)
+
+	remaining = len(targets)
+	for i := range batch {
+		waitGroup.Add(1)
+		workers <- struct{}{}
+		go func(target *unstructured.Unstructured) {
+			defer func() {
+				<-workers
+				waitGroup.Done()
+			}()
+
+			deleteErr := client.IgnoreNotFound(r.Delete(ctx, target, deleteOptions...))
+			if deleteErr == nil {
+				r.RecordAuditLog(ctx, replika, target, auditlog.ActionDelete, auditReasonDeletion)
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if deleteErr != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", target.GetNamespace(), deleteErr))
+				return
+			}
+			remaining--
+			deleted := NewInventoryEntry(target)
+			RemoveInventoryEntries(replika, func(entry replikav1beta1.ReplikaInventoryEntry) bool {
+				return entry == deleted
+			})
+
+			if r.Recorder != nil {
+				r.Recorder.Eventf(replika, corev1.EventTypeNormal, targetDeletedEventReason, targetDeletedEvent,
+					target.GetNamespace(), remaining)
+			}
+		}(&batch[i])
+	}
+	waitGroup.Wait()
+
+	err = utilerrors.NewAggregate(errs)
+	if err != nil || remaining > 0 {
+		return remaining, err
+	}
+
+	err = r.DeleteAnchors(ctx, replika)
+	return remaining, err
+}
+
+// replicator return the Replicator used to write the targets
+func (r *ReplikaReconciler) replicator() replicator.Replicator {
+	return replicator.New(r.Client)
+}
+
+// uncachedReader return the reader of the ConfigMaps and Secrets, which are never cached.
+// The cached client is used when the APIReader is not set
+func (r *ReplikaReconciler) uncachedReader() client.Reader {
+	if r.APIReader == nil {
+		return r.Client
+	}
+	return r.APIReader
+}
+
+// targetReplicator return the Replicator writing the targets of the Replika, with its write options
+func (r *ReplikaReconciler) targetReplicator(replika *replikav1beta1.Replika) replicator.Replicator {
+	return replicator.NewWithOptions(r.Client, replicator.Options{
+		OptimisticLock: replika.Spec.Synchronization.OptimisticLock,
+		OwnershipLabel: resourceReplikaLabelCreatedKey,
+		AdoptExisting:  replika.Spec.Target.AdoptExisting,
+		Reader:         r.APIReader,
+	})
+}
@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/conditions"
+)
+
+func TestGetMaxTargets(t *testing.T) {
+	tests := []struct {
+		name     string
+		replika  int
+		operator int
+		expected int
+	}{
+		{name: "no limit"},
+		{name: "limit of the Replika", replika: 5, expected: 5},
+		{name: "limit of the operator", operator: 10, expected: 10},
+		{name: "lower limit of the Replika", replika: 5, operator: 10, expected: 5},
+		{name: "lower limit of the operator", replika: 20, operator: 10, expected: 10},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			replika := &replikav1beta1.Replika{}
+			replika.Spec.Target.MaxTargets = test.replika
+			r := &ReplikaReconciler{MaxTargets: test.operator}
+			if maxTargets := r.GetMaxTargets(replika); maxTargets != test.expected {
+				t.Errorf("expected %d, got %d", test.expected, maxTargets)
+			}
+		})
+	}
+}
+
+func TestCheckMaxTargets(t *testing.T) {
+	replika := &replikav1beta1.Replika{}
+	replika.Spec.Target.MaxTargets = 2
+	r := &ReplikaReconciler{}
+
+	if err := r.CheckMaxTargets(replika, 2); err != nil {
+		t.Fatalf("unexpected error within the limit: %v", err)
+	}
+
+	err := r.CheckMaxTargets(replika, 3)
+	if !IsPermanentError(err) {
+		t.Fatalf("expected a permanent error above the limit, got %v", err)
+	}
+	condition := conditions.Get(replika.Status.Conditions, ConditionTypeSourceSynced)
+	if condition == nil || condition.Reason != ConditionReasonTooManyTargets {
+		t.Errorf("expected the reason %s, got %v", ConditionReasonTooManyTargets, condition)
+	}
+}
+
+func TestCheckTargetsSize(t *testing.T) {
+	replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-config"}}
+
+	var targets []unstructured.Unstructured
+	for namespace, size := range map[string]int{"small": 16, "large": maxTargetSize} {
+		target := unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"data":       map[string]interface{}{"content": strings.Repeat("x", size)},
+		}}
+		target.SetNamespace(namespace)
+		target.SetName("app-config")
+		targets = append(targets, target)
+	}
+
+	r := &ReplikaReconciler{}
+	accepted := r.CheckTargetsSize(context.Background(), replika, targets)
+	if len(accepted) != 1 || accepted[0].GetNamespace() != "small" {
+		t.Fatalf("expected only the small target accepted, got %d", len(accepted))
+	}
+	rejected := replika.Status.RejectedNamespaces
+	if len(rejected) != 1 || rejected[0].Namespace != "large" || rejected[0].Reason != ConditionReasonTargetTooLarge {
+		t.Errorf("expected the large target rejected, got %v", rejected)
+	}
+}
+
+// failingDeleteClient fails the deletions in a namespace
+type failingDeleteClient struct {
+	client.Client
+	namespace string
+}
+
+func (c *failingDeleteClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if obj.GetNamespace() == c.namespace {
+		return errors.New("the API server is unavailable")
+	}
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func TestDeleteTargetsContinuesPastErrors(t *testing.T) {
+	replika := &replikav1beta1.Replika{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-config"}}
+	replika.Spec.Source = replikav1beta1.ReplikaSourceSpec{Version: "v1", Kind: "ConfigMap", Name: "app-config", Namespace: "default"}
+
+	var objects []client.Object
+	for _, namespace := range []string{"team-a", "broken", "team-b"} {
+		objects = append(objects, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      "app-config",
+			Labels: map[string]string{
+				resourceReplikaLabelCreatedKey:         resourceReplikaLabelCreatedValue,
+				resourceReplikaLabelPartOfKey:          replika.Name,
+				resourceReplikaLabelPartOfNamespaceKey: replika.Namespace,
+			},
+		}})
+	}
+	r := &ReplikaReconciler{Client: &failingDeleteClient{
+		Client:    fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build(),
+		namespace: "broken",
+	}}
+
+	// The failing namespace is reported, the rest of the targets are deleted anyway
+	remaining, err := r.DeleteTargets(context.Background(), replika)
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("expected the error of the broken namespace, got %v", err)
+	}
+	if remaining != 1 {
+		t.Errorf("expected 1 target remaining, got %d", remaining)
+	}
+
+	list := &corev1.ConfigMapList{}
+	if err = r.List(context.Background(), list); err != nil || len(list.Items) != 1 || list.Items[0].Namespace != "broken" {
+		t.Errorf("expected only the target of the broken namespace kept, got %v: %v", list.Items, err)
+	}
+}
+
+func TestGetRequestTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		replika  string
+		operator time.Duration
+		expected time.Duration
+	}{
+		{name: "no timeout"},
+		{name: "timeout of the operator", operator: time.Minute, expected: time.Minute},
+		{name: "timeout of the Replika", replika: "10s", operator: time.Minute, expected: 10 * time.Second},
+		{name: "invalid timeout of the Replika", replika: "often", operator: time.Minute, expected: time.Minute},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			replika := &replikav1beta1.Replika{}
+			replika.Spec.Synchronization.RequestTimeout = test.replika
+			r := &ReplikaReconciler{RequestTimeout: test.operator}
+			if timeout := r.GetRequestTimeout(replika); timeout != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, timeout)
+			}
+		})
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	replika := &replikav1beta1.Replika{}
+	replika.Spec.Synchronization.RequestTimeout = "10ms"
+	r := &ReplikaReconciler{}
+
+	ctx, cancel := r.requestContext(context.Background(), replika)
+	defer cancel()
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the request cancelled once the timeout expires")
+	}
+
+	// A request timing out is reported as such
+	reason, _ := GetFailureReason(fmt.Errorf("reading the source: %w", ctx.Err()),
+		ConditionReasonSourceNotFound, ConditionReasonSourceNotFoundMessage)
+	if reason != ConditionReasonRequestTimeout {
+		t.Errorf("expected the reason %s, got %s", ConditionReasonRequestTimeout, reason)
+	}
+
+	// Without timeout, the request is only cancelled along with its parent
+	replika.Spec.Synchronization.RequestTimeout = ""
+	ctx, cancel = r.requestContext(context.Background(), replika)
+	defer cancel()
+	if _, found := ctx.Deadline(); found {
+		t.Errorf("expected no deadline without timeout")
+	}
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
+	"prosimcorp.com/replika/pkg/conditions"
+)
+
+const (
+	// Name of the singleton ReplikaReport maintained by the controller
+	replikaReportName = "cluster"
+
+	// Number of Replikas listed in status.slowestReplikas
+	replikaReportSlowestCount = 10
+
+	replikaReportUpdateError = "Can not update the ReplikaReport: %s"
+)
+
+// ReplikaReporter periodically summarizes all the Replikas of the cluster into the singleton ReplikaReport.
+// The figures are read from the status of the Replikas, so the report is complete right after a restart
+type ReplikaReporter struct {
+	Client   client.Client
+	Interval time.Duration
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, so only the leader writes the report
+func (r *ReplikaReporter) NeedLeaderElection() bool {
+	return true
+}
+
+// Start implements manager.Runnable, updating the report on each interval
+func (r *ReplikaReporter) Start(ctx context.Context) error {
+
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			err := r.UpdateReport(ctx)
+			if err != nil {
+				LogInfof(ctx, replikaReportUpdateError, err.Error())
+			}
+		}
+	}
+}
+
+// BuildReportStatus return the totals of the given Replikas
+func (r *ReplikaReporter) BuildReportStatus(replikas []replikav1beta1.Replika) (status replikav1beta1.ReplikaReportStatus) {
+
+	status.LastUpdateTime = metav1.Now()
+	status.Replikas = len(replikas)
+	status.FailuresByReason = map[string]int{}
+
+	var entries []replikav1beta1.ReplikaReportEntry
+	durations := map[types.NamespacedName]time.Duration{}
+
+	for _, v := range replikas {
+		key := types.NamespacedName{Namespace: v.Namespace, Name: v.Name}
+		status.Targets += v.Status.TotalTargets
+
+		if condition := conditions.Get(v.Status.Conditions, ConditionTypeSourceSynced); condition != nil &&
+			condition.Status == metav1.ConditionFalse {
+			status.FailedReplikas++
+			status.FailuresByReason[condition.Reason]++
+		}
+
+		if v.Status.ReconcileDuration != nil && v.Status.ReconcileDuration.Duration > 0 {
+			durations[key] = v.Status.ReconcileDuration.Duration
+			entries = append(entries, replikav1beta1.ReplikaReportEntry{
+				Namespace: v.Namespace,
+				Name:      v.Name,
+				Duration:  v.Status.ReconcileDuration.Duration.String(),
+			})
+		}
+	}
+
+	// Keep only the slowest ones
+	sort.SliceStable(entries, func(i, j int) bool {
+		return durations[types.NamespacedName{Namespace: entries[i].Namespace, Name: entries[i].Name}] >
+			durations[types.NamespacedName{Namespace: entries[j].Namespace, Name: entries[j].Name}]
+	})
+	if len(entries) > replikaReportSlowestCount {
+		entries = entries[:replikaReportSlowestCount]
+	}
+	status.SlowestReplikas = entries
+
+	return status
+}
+
+// UpdateReport write the totals of all the Replikas into the ReplikaReport, creating it when not existent
+func (r *ReplikaReporter) UpdateReport(ctx context.Context) (err error) {
+
+	replikaList := &replikav1beta1.ReplikaList{}
+	err = r.Client.List(ctx, replikaList)
+	if err != nil {
+		return err
+	}
+
+	report := &replikav1beta1.ReplikaReport{}
+	err = r.Client.Get(ctx, client.ObjectKey{Name: replikaReportName}, report)
+	if apierrors.IsNotFound(err) {
+		report.SetName(replikaReportName)
+		err = r.Client.Create(ctx, report)
+	}
+	if err != nil {
+		return err
+	}
+
+	report.Status = r.BuildReportStatus(replikaList.Items)
+	err = r.Client.Status().Update(ctx, report)
+
+	return err
+}
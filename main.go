@@ -17,25 +17,44 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/dynamic"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
+	replikav1 "prosimcorp.com/replika/api/v1"
 	replikav1beta1 "prosimcorp.com/replika/api/v1beta1"
 	"prosimcorp.com/replika/controllers"
+	"prosimcorp.com/replika/pkg/auditlog"
+	"prosimcorp.com/replika/pkg/webhookcert"
 	//+kubebuilder:scaffold:imports
 )
 
+const (
+	// Run modes of the manager
+	modeController = "controller"
+	modeWebhook    = "webhook"
+	modeAll        = "all"
+)
+
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
@@ -43,8 +62,10 @@ var (
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
 
 	utilruntime.Must(replikav1beta1.AddToScheme(scheme))
+	utilruntime.Must(replikav1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -52,11 +73,124 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var resyncSpread time.Duration
+	var resyncSpreadPerReplika time.Duration
+	var readyTimeout time.Duration
+	var migrateFrom string
+	var mode string
+	var configMap string
+	var maxConcurrentReconciles int
+	var maxTargets int
+	var auditInterval time.Duration
+	var reportInterval time.Duration
+	var logDeduplicationInterval time.Duration
+	var metricsPerReplika bool
+	var metricsTargetNamespaces bool
+	var syncScheduler bool
+	var watchSources bool
+	var confirmSecretsInAllNamespaces bool
+	var confirmationThreshold int
+	var auditLog string
+	var auditLogBuffer int
+	var debounceWindow time.Duration
+	var shutdownTimeout time.Duration
+	var cacheMetricsInterval time.Duration
+	var webhookCertDir string
+	var webhookCertSecret string
+	var webhookService string
+	var allowedSourceKinds string
+	var requestTimeout time.Duration
+	var httpHookAllowedHosts string
+	var watchConsumerPods bool
+	var enableHookJobs bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&resyncSpread, "resync-spread", 30*time.Second,
+		"Window used to spread the full resync of all the Replikas after acquiring the leadership. "+
+			"Setting it to 0 reconciles all of them at the same time.")
+	flag.DurationVar(&resyncSpreadPerReplika, "resync-spread-per-replika", 0,
+		"Time added to the resync window for each existing Replika, so large installations are smeared along a longer window. "+
+			"The window is never shorter than --resync-spread.")
+	flag.DurationVar(&readyTimeout, "ready-timeout", 5*time.Minute,
+		"Maximum time the readiness probe waits for the full resync of all the Replikas before reporting ready.")
+	flag.StringVar(&migrateFrom, "migrate-from", "",
+		"Copy the Replikas from an old group/version (e.g. replika.prosimcorp.com/v1alpha1) into the current one, "+
+			"adopt their targets and exit.")
+	flag.StringVar(&mode, "mode", modeController,
+		"Components started by the manager: 'controller' for the reconciler, 'webhook' for the admission webhooks "+
+			"or 'all' for both of them.")
+	flag.StringVar(&configMap, "config-map", "",
+		"ConfigMap holding the operator configuration as namespace/name. It is reloaded on changes without restarts.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 10,
+		"Number of workers of the controller. The active ones are limited by the concurrency of the operator configuration.")
+	flag.IntVar(&maxTargets, "max-targets", 0,
+		"Maximum number of namespaces a Replika can replicate its source in. Setting it to 0 disables the limit.")
+	flag.DurationVar(&auditInterval, "audit-interval", time.Hour,
+		"Time between two integrity audits of the targets of each Replika. Setting it to 0 disables the audits.")
+	flag.DurationVar(&reportInterval, "report-interval", time.Minute,
+		"Time between two updates of the cluster-wide ReplikaReport. Setting it to 0 disables the report.")
+	flag.DurationVar(&logDeduplicationInterval, "log-deduplication-interval", 5*time.Minute,
+		"Time an identical error message is suppressed from the logs before being summarized again. "+
+			"Setting it to 0 logs every occurrence.")
+	flag.BoolVar(&metricsPerReplika, "metrics-per-replika", true,
+		"Label the metrics with the namespace and name of each Replika. When disabled, the series of all of them are aggregated.")
+	flag.BoolVar(&metricsTargetNamespaces, "metrics-target-namespaces", false,
+		"Label the metrics of the targets with their namespace. It multiplies the series by the number of namespaces.")
+	flag.BoolVar(&syncScheduler, "sync-scheduler", true,
+		"Enqueue the periodical synchronizations from a shared scheduler, smearing the Replikas along their interval. "+
+			"When disabled, each Replika requeues itself.")
+	flag.BoolVar(&watchSources, "watch-sources", false,
+		"Synchronize the Replikas as soon as their sources change. An informer is started for each kind of source "+
+			"while some Replika references it.")
+	flag.BoolVar(&enableHookJobs, "enable-hook-jobs", false,
+		"Run the pre-sync and post-sync hook Jobs of the Replikas. They run any Pod as a ServiceAccount of the namespace "+
+			"of the Replika, so only enable them when every author of Replikas can create those Pods.")
+	flag.BoolVar(&watchConsumerPods, "watch-consumer-pods", false,
+		"Synchronize the on-demand Replikas as soon as a Pod consuming their source is created. "+
+			"Every Pod of the cluster is cached. When disabled, the new consumers get their targets on the next synchronization.")
+	flag.BoolVar(&confirmSecretsInAllNamespaces, "confirm-secrets-in-all-namespaces", false,
+		"Hold the Replikas replicating a Secret in all the namespaces until they are annotated with "+
+			"replika.prosimcorp.com/confirmed=sync/<generation>, confirming each generation of their spec.")
+	flag.IntVar(&confirmationThreshold, "confirmation-threshold", 0,
+		"Hold the deletion of the Replikas removing more targets until they are annotated with "+
+			"replika.prosimcorp.com/confirmed=delete/<generation>, or delete-targets/<generation> for the targets "+
+			"of a missing source. Setting it to 0 disables the confirmation.")
+	flag.StringVar(&auditLog, "audit-log", "",
+		"Record every write performed on the targets as JSON lines: 'stdout', the path of a file, "+
+			"or an http(s) URL receiving a POST per record. Empty disables the audit log.")
+	flag.IntVar(&auditLogBuffer, "audit-log-buffer", 10000,
+		"Records of the audit log waiting to be written. The failed writes are retried, the records being dropped "+
+			"only when the buffer is full.")
+	flag.DurationVar(&debounceWindow, "debounce-window", 0,
+		"Minimum time between two synchronizations of a Replika, coalescing the bursts of changes into a single one. "+
+			"Setting it to 0 synchronizes on every change.")
+	flag.DurationVar(&shutdownTimeout, "shutdown-timeout", 25*time.Second,
+		"Maximum time waiting for the in-flight synchronizations to finish on shutdown. "+
+			"It must be shorter than the termination grace period of the Pod.")
+	flag.DurationVar(&cacheMetricsInterval, "cache-metrics-interval", time.Minute,
+		"Time between two measures of the objects held by the informers. Setting it to 0 disables them.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "",
+		"Directory holding the tls.crt and tls.key serving the admission webhooks. They are reloaded when rotated. "+
+			"Empty uses the default directory of controller-runtime.")
+	flag.StringVar(&webhookCertSecret, "webhook-cert-secret", "",
+		"Secret as namespace/name where the certificates of the admission webhooks are generated and rotated in-process, "+
+			"for the installations without cert-manager. The directory of --webhook-cert-dir must be writable. "+
+			"Empty disables it.")
+	flag.StringVar(&webhookService, "webhook-service", "replika/replika-webhook-service",
+		"Service of the admission webhooks as namespace/name. The self-managed certificates are issued for its names "+
+			"and their CA is injected into the webhook configurations pointing at it.")
+	flag.StringVar(&allowedSourceKinds, "allowed-source-kinds", "",
+		"Kinds allowed as sources separated by commas, as 'Kind' or 'group/Kind', when the operator configuration "+
+			"does not define them. Empty allows all of them.")
+	flag.StringVar(&httpHookAllowedHosts, "http-hook-allowed-hosts", "",
+		"Hosts the HTTP hooks of the Replikas can call, separated by commas, as 'host' or '*.domain'. "+
+			"Empty refuses all the HTTP hooks.")
+	flag.DurationVar(&requestTimeout, "request-timeout", 0,
+		"Maximum time of each request reading a source or writing a target, for the Replikas not defining "+
+			"spec.synchronization.requestTimeout. Setting it to 0 disables it.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -64,14 +198,35 @@ func main() {
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	controllers.SetLogDeduplicationInterval(logDeduplicationInterval)
+	controllers.SetMetricsOptions(controllers.MetricsOptions{
+		PerReplika:       metricsPerReplika,
+		TargetNamespaces: metricsTargetNamespaces,
+	})
+	controllers.SetDefaultAllowedKinds(controllers.ParseKindList(allowedSourceKinds))
+	controllers.SetDefaultConcurrency(maxConcurrentReconciles)
+
+	if migrateFrom != "" {
+		migrate(migrateFrom)
+		return
+	}
+
+	if mode != modeController && mode != modeWebhook && mode != modeAll {
+		setupLog.Info("invalid mode, must be one of: controller, webhook, all", "mode", mode)
+		os.Exit(1)
+	}
+	runController := mode == modeController || mode == modeAll
+	runWebhook := mode == modeWebhook || mode == modeAll
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
-		MetricsBindAddress:     metricsAddr,
-		Port:                   9443,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "562e2a83.prosimcorp.com",
+		Scheme:                  scheme,
+		MetricsBindAddress:      metricsAddr,
+		Port:                    9443,
+		HealthProbeBindAddress:  probeAddr,
+		CertDir:                 webhookCertDir,
+		LeaderElection:          enableLeaderElection && runController,
+		LeaderElectionID:        "562e2a83.prosimcorp.com",
+		GracefulShutdownTimeout: &shutdownTimeout,
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -89,12 +244,151 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = (&controllers.ReplikaReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Replika")
-		os.Exit(1)
+	var readyzCheck healthz.Checker = healthz.Ping
+	if runController {
+		warnAllowedKindsPermissions(mgr.GetRESTMapper(), controllers.ParseKindList(allowedSourceKinds))
+
+		operatorConfig := controllers.NewOperatorConfig()
+		if configMap != "" {
+			configMapNamespace, configMapName, found := strings.Cut(configMap, "/")
+			if !found {
+				setupLog.Info("invalid config-map, must be namespace/name", "config-map", configMap)
+				os.Exit(1)
+			}
+
+			if err = (&controllers.OperatorConfigReconciler{
+				Client:    mgr.GetClient(),
+				ConfigMap: types.NamespacedName{Namespace: configMapNamespace, Name: configMapName},
+				Config:    operatorConfig,
+			}).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create controller", "controller", "OperatorConfig")
+				os.Exit(1)
+			}
+		}
+
+		replikaReconciler := &controllers.ReplikaReconciler{
+			Client:                        mgr.GetClient(),
+			Scheme:                        mgr.GetScheme(),
+			APIReader:                     mgr.GetAPIReader(),
+			Config:                        operatorConfig,
+			MaxConcurrentReconciles:       maxConcurrentReconciles,
+			Failures:                      controllers.NewFailureTracker(),
+			MaxTargets:                    maxTargets,
+			AuditInterval:                 auditInterval,
+			Recorder:                      mgr.GetEventRecorderFor("replika-controller"),
+			ConfirmSecretsInAllNamespaces: confirmSecretsInAllNamespaces,
+			ConfirmationThreshold:         confirmationThreshold,
+			DebounceWindow:                debounceWindow,
+			Drainer:                       controllers.NewShutdownDrainer(shutdownTimeout),
+			SourceCache:                   controllers.NewSourceCache(),
+			RequestTimeout:                requestTimeout,
+			Queue:                         controllers.NewQueueTracker(),
+			HTTPHookAllowedHosts:          controllers.ParseHostList(httpHookAllowedHosts),
+			WatchConsumerPods:             watchConsumerPods,
+			HookJobs:                      enableHookJobs,
+		}
+		if syncScheduler {
+			replikaReconciler.Scheduler = controllers.NewSyncScheduler()
+		}
+		if watchSources {
+			replikaReconciler.SourceWatcher = controllers.NewSourceWatcher(
+				dynamic.NewForConfigOrDie(mgr.GetConfig()), mgr.GetRESTMapper())
+		}
+		if auditLog != "" {
+			var sink auditlog.Sink
+			sink, err = auditlog.New(auditLog)
+			if err != nil {
+				setupLog.Error(err, "unable to open the audit log", "destination", auditLog)
+				os.Exit(1)
+			}
+
+			auditLogLog := ctrl.Log.WithName("auditlog")
+			bufferedSink := auditlog.NewBuffered(sink, auditLogBuffer)
+			bufferedSink.OnError = func(record auditlog.Record, err error) {
+				auditLogLog.Error(err, "unable to write the audit log, retrying", "replika", record.Replika,
+					"action", record.Action, "namespace", record.Namespace, "name", record.Name)
+			}
+			if err = mgr.Add(bufferedSink); err != nil {
+				setupLog.Error(err, "unable to start the audit log", "destination", auditLog)
+				os.Exit(1)
+			}
+			replikaReconciler.AuditLog = bufferedSink
+		}
+		if resyncSpread > 0 || resyncSpreadPerReplika > 0 {
+			replikaReconciler.Resync = controllers.NewLeaderResync(mgr.GetClient(), mgr.GetCache(), resyncSpread)
+			replikaReconciler.Resync.SpreadPerReplika = resyncSpreadPerReplika
+			readyzCheck = replikaReconciler.Resync.ReadyzCheck(readyTimeout)
+		}
+
+		if err = replikaReconciler.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Replika")
+			os.Exit(1)
+		}
+		if err = mgr.Add(replikaReconciler.Drainer); err != nil {
+			setupLog.Error(err, "unable to create drainer", "drainer", "Replika")
+			os.Exit(1)
+		}
+
+		if cacheMetricsInterval > 0 {
+			if err = mgr.Add(&controllers.CacheMetrics{
+				Client:        mgr.GetCache(),
+				SourceWatcher: replikaReconciler.SourceWatcher,
+				Pods:          watchConsumerPods,
+				Interval:      cacheMetricsInterval,
+			}); err != nil {
+				setupLog.Error(err, "unable to create cache metrics", "metrics", "cache")
+				os.Exit(1)
+			}
+		}
+
+		if reportInterval > 0 {
+			if err = mgr.Add(&controllers.ReplikaReporter{
+				Client:   mgr.GetClient(),
+				Interval: reportInterval,
+			}); err != nil {
+				setupLog.Error(err, "unable to create reporter", "reporter", "ReplikaReport")
+				os.Exit(1)
+			}
+		}
+	}
+
+	var certRotator *webhookcert.Rotator
+	if runWebhook {
+		if err = (&replikav1beta1.Replika{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Replika")
+			os.Exit(1)
+		}
+
+		if webhookCertSecret != "" {
+			secretNamespace, secretName, found := strings.Cut(webhookCertSecret, "/")
+			if !found {
+				setupLog.Info("invalid webhook-cert-secret, must be namespace/name", "webhook-cert-secret", webhookCertSecret)
+				os.Exit(1)
+			}
+			serviceNamespace, serviceName, found := strings.Cut(webhookService, "/")
+			if !found {
+				setupLog.Info("invalid webhook-service, must be namespace/name", "webhook-service", webhookService)
+				os.Exit(1)
+			}
+
+			// Read without cache, so the Secrets of the cluster are not watched
+			var rotatorClient client.Client
+			rotatorClient, err = client.New(mgr.GetConfig(), client.Options{Scheme: scheme})
+			if err != nil {
+				setupLog.Error(err, "unable to create the client")
+				os.Exit(1)
+			}
+			certRotator = &webhookcert.Rotator{
+				Client:  rotatorClient,
+				Secret:  types.NamespacedName{Namespace: secretNamespace, Name: secretName},
+				Service: types.NamespacedName{Namespace: serviceNamespace, Name: serviceName},
+				CertDir: mgr.GetWebhookServer().CertDir,
+			}
+			if err = mgr.Add(certRotator); err != nil {
+				setupLog.Error(err, "unable to create certificate rotator", "rotator", "webhook")
+				os.Exit(1)
+			}
+		}
 	}
 	//+kubebuilder:scaffold:builder
 
@@ -102,14 +396,83 @@ func main() {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+	if err := mgr.AddReadyzCheck("readyz", readyzCheck); err != nil {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
 
+	// The webhook server does not need the leadership, so every replica serves the admission requests
+	// once its server is listening
+	if runWebhook {
+		if err := mgr.AddHealthzCheck("webhook", mgr.GetWebhookServer().StartedChecker()); err != nil {
+			setupLog.Error(err, "unable to set up webhook health check")
+			os.Exit(1)
+		}
+		if err := mgr.AddReadyzCheck("webhook", mgr.GetWebhookServer().StartedChecker()); err != nil {
+			setupLog.Error(err, "unable to set up webhook ready check")
+			os.Exit(1)
+		}
+	}
+
+	ctx := ctrl.SetupSignalHandler()
+
+	// The webhook server can not start without its certificates
+	if certRotator != nil {
+		if err := certRotator.Ensure(ctx); err != nil {
+			setupLog.Error(err, "unable to generate the certificates of the webhooks")
+			os.Exit(1)
+		}
+	}
+
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// migrate copies the Replikas from an old group/version into the current one
+func migrate(from string) {
+	groupVersion, err := schema.ParseGroupVersion(from)
+	if err != nil {
+		setupLog.Error(err, "unable to parse the group/version to migrate from")
+		os.Exit(1)
+	}
+
+	c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		setupLog.Error(err, "unable to create the client")
+		os.Exit(1)
+	}
+
+	setupLog.Info("migrating replikas", "from", groupVersion.String())
+	ctx := ctrl.LoggerInto(context.Background(), setupLog)
+	if err = controllers.MigrateReplikas(ctx, c, groupVersion); err != nil {
+		setupLog.Error(err, "unable to migrate replikas")
+		os.Exit(1)
+	}
+}
+
+// warnAllowedKindsPermissions log the permissions needed to replicate exactly the allowed kinds,
+// so the broad grants of the default ClusterRole can be reduced to them
+func warnAllowedKindsPermissions(mapper meta.RESTMapper, kinds []string) {
+	if len(kinds) == 0 {
+		return
+	}
+
+	rules, err := controllers.AllowedKindsPolicyRules(mapper, kinds)
+	if err != nil {
+		setupLog.Error(err, "unable to resolve the allowed source kinds", "kinds", kinds)
+		return
+	}
+
+	var permissions []string
+	for _, rule := range rules {
+		for _, resource := range rule.Resources {
+			permissions = append(permissions, schema.GroupResource{Group: rule.APIGroups[0], Resource: resource}.String())
+		}
+	}
+	setupLog.Info("WARNING: only the allowed source kinds are replicated, the permissions of the controller can be "+
+		"reduced to them. Generate the ClusterRole with 'replikactl rbac --kinds'",
+		"kinds", kinds, "resources", permissions)
+}
@@ -0,0 +1,249 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package auditlog records the writes performed by the controller as JSON lines,
+// independently of the retention of the Kubernetes Events
+package auditlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Actions recorded on the targets
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+
+	// Destination writing the records to the standard output
+	DestinationStdout = "stdout"
+
+	// Time waiting for the response of an HTTP sink
+	httpSinkTimeout = 10 * time.Second
+
+	// Backoff between the retries of a record the sink failed to write
+	bufferedSinkMinBackoff = time.Second
+	bufferedSinkMaxBackoff = time.Minute
+
+	// Time given to the sink to write each of the records still buffered on shutdown
+	bufferedSinkFlushTimeout = 5 * time.Second
+)
+
+// ErrBufferFull is returned when a record is dropped because the sink can not keep up with the writes
+var ErrBufferFull = errors.New("the audit log buffer is full, the record is dropped")
+
+// Record defines a write performed by the controller: who did what, when, why and from which revision
+type Record struct {
+	Time     time.Time `json:"time"`
+	Actor    string    `json:"actor"`
+	Action   string    `json:"action"`
+	Reason   string    `json:"reason"`
+	Replika  string    `json:"replika"`
+	Source   string    `json:"source,omitempty"`
+	Revision string    `json:"revision,omitempty"`
+
+	// Object written, its kind as group/version/Kind
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// Sink stores the records
+type Sink interface {
+	Write(ctx context.Context, record Record) error
+}
+
+// New return the Sink for a destination: 'stdout', an http(s) URL receiving a POST per record,
+// or the path of a file where the records are appended
+func New(destination string) (sink Sink, err error) {
+
+	switch {
+	case destination == DestinationStdout:
+		sink = &writerSink{writer: os.Stdout}
+	case strings.HasPrefix(destination, "http://") || strings.HasPrefix(destination, "https://"):
+		sink = &httpSink{url: destination, client: &http.Client{Timeout: httpSinkTimeout}}
+	default:
+		var file *os.File
+		file, err = os.OpenFile(destination, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return sink, err
+		}
+		sink = &writerSink{writer: file}
+	}
+
+	return sink, err
+}
+
+// writerSink writes each record as a JSON line
+type writerSink struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+// Write implements Sink
+func (s *writerSink) Write(ctx context.Context, record Record) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return json.NewEncoder(s.writer).Encode(record)
+}
+
+// httpSink sends each record as the JSON body of a POST
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+// Write implements Sink
+func (s *httpSink) Write(ctx context.Context, record Record) (err error) {
+
+	var body []byte
+	body, err = json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	var request *http.Request
+	request, err = http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	var response *http.Response
+	response, err = s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		err = fmt.Errorf("the audit sink %s returned the status %d", s.url, response.StatusCode)
+	}
+
+	return err
+}
+
+// BufferedSink queues the records and writes them to another sink in the background, so the writes of the
+// controller never wait for a slow sink. The records the sink fails to write are retried with a backoff,
+// in order, until they are written. Records are only dropped when the buffer is full
+type BufferedSink struct {
+	sink    Sink
+	records chan Record
+
+	// OnError is called with the errors of the sink before retrying a record. Optional
+	OnError func(record Record, err error)
+}
+
+// NewBuffered return a BufferedSink holding up to size records for the sink.
+// It writes nothing until started
+func NewBuffered(sink Sink, size int) *BufferedSink {
+	return &BufferedSink{
+		sink:    sink,
+		records: make(chan Record, size),
+	}
+}
+
+// Write implements Sink, queueing the record. ErrBufferFull is returned when the record is dropped
+func (s *BufferedSink) Write(ctx context.Context, record Record) (err error) {
+	select {
+	case s.records <- record:
+		return err
+	default:
+		return ErrBufferFull
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, as the records are written by any replica
+func (s *BufferedSink) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable, writing the records until the context is done.
+// The records still buffered then are tried once more before returning
+func (s *BufferedSink) Start(ctx context.Context) (err error) {
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.flush()
+			return err
+		case record := <-s.records:
+			s.writeWithRetry(ctx, record)
+		}
+	}
+}
+
+// writeWithRetry write a record, retrying with a backoff until it is written or the context is done.
+// A record not written before the context is done is tried once more on the flush
+func (s *BufferedSink) writeWithRetry(ctx context.Context, record Record) {
+
+	backoff := bufferedSinkMinBackoff
+	for {
+		err := s.sink.Write(ctx, record)
+		if err == nil {
+			return
+		}
+		if s.OnError != nil {
+			s.OnError(record, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			s.writeOnce(record)
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > bufferedSinkMaxBackoff {
+			backoff = bufferedSinkMaxBackoff
+		}
+	}
+}
+
+// flush write once the records still buffered
+func (s *BufferedSink) flush() {
+	for {
+		select {
+		case record := <-s.records:
+			s.writeOnce(record)
+		default:
+			return
+		}
+	}
+}
+
+// writeOnce write a record with its own timeout, reporting the failure
+func (s *BufferedSink) writeOnce(record Record) {
+	ctx, cancel := context.WithTimeout(context.Background(), bufferedSinkFlushTimeout)
+	defer cancel()
+
+	err := s.sink.Write(ctx, record)
+	if err != nil && s.OnError != nil {
+		s.OnError(record, err)
+	}
+}
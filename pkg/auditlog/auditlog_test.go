@@ -0,0 +1,179 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auditlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink stores the records written, failing the first writes
+type recordingSink struct {
+	mutex    sync.Mutex
+	failures int
+	records  []Record
+	written  chan struct{}
+}
+
+func (s *recordingSink) Write(ctx context.Context, record Record) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("sink unavailable")
+	}
+	s.records = append(s.records, record)
+	if s.written != nil {
+		s.written <- struct{}{}
+	}
+	return err
+}
+
+func TestWriterSink(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	sink := &writerSink{writer: buffer}
+
+	for _, name := range []string{"first", "second"} {
+		if err := sink.Write(context.Background(), Record{Action: ActionCreate, Name: name}); err != nil {
+			t.Fatalf("unexpected error writing the record: %v", err)
+		}
+	}
+
+	decoder := json.NewDecoder(buffer)
+	for _, name := range []string{"first", "second"} {
+		record := Record{}
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("unexpected error decoding the record: %v", err)
+		}
+		if record.Action != ActionCreate || record.Name != name {
+			t.Errorf("expected the record of %s, got %+v", name, record)
+		}
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "accepted", status: http.StatusAccepted},
+		{name: "rejected", status: http.StatusInternalServerError, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var received Record
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("unexpected request %s with content type %s", r.Method, r.Header.Get("Content-Type"))
+				}
+				_ = json.NewDecoder(r.Body).Decode(&received)
+				w.WriteHeader(test.status)
+			}))
+			defer server.Close()
+
+			sink, err := New(server.URL)
+			if err != nil {
+				t.Fatalf("unexpected error creating the sink: %v", err)
+			}
+			err = sink.Write(context.Background(), Record{Action: ActionDelete, Name: "app-config"})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error %t, got %v", test.wantErr, err)
+			}
+			if received.Action != ActionDelete || received.Name != "app-config" {
+				t.Errorf("expected the record posted, got %+v", received)
+			}
+		})
+	}
+}
+
+func TestBufferedSinkFull(t *testing.T) {
+	sink := NewBuffered(&recordingSink{}, 1)
+
+	if err := sink.Write(context.Background(), Record{Name: "first"}); err != nil {
+		t.Fatalf("unexpected error queueing the record: %v", err)
+	}
+	if err := sink.Write(context.Background(), Record{Name: "second"}); !errors.Is(err, ErrBufferFull) {
+		t.Fatalf("expected ErrBufferFull, got %v", err)
+	}
+}
+
+func TestBufferedSinkRetry(t *testing.T) {
+	recording := &recordingSink{failures: 1, written: make(chan struct{}, 2)}
+	sink := NewBuffered(recording, 2)
+
+	var failed []string
+	sink.OnError = func(record Record, err error) {
+		failed = append(failed, record.Name)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = sink.Start(ctx) }()
+
+	for _, name := range []string{"first", "second"} {
+		if err := sink.Write(context.Background(), Record{Name: name}); err != nil {
+			t.Fatalf("unexpected error queueing the record: %v", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-recording.written:
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for the records to be written")
+		}
+	}
+
+	recording.mutex.Lock()
+	defer recording.mutex.Unlock()
+	if len(recording.records) != 2 || recording.records[0].Name != "first" || recording.records[1].Name != "second" {
+		t.Errorf("expected the records written in order, got %+v", recording.records)
+	}
+	if len(failed) != 1 || failed[0] != "first" {
+		t.Errorf("expected the first record reported as failed once, got %v", failed)
+	}
+}
+
+func TestBufferedSinkFlush(t *testing.T) {
+	recording := &recordingSink{}
+	sink := NewBuffered(recording, 2)
+
+	for _, name := range []string{"first", "second"} {
+		if err := sink.Write(context.Background(), Record{Name: name}); err != nil {
+			t.Fatalf("unexpected error queueing the record: %v", err)
+		}
+	}
+
+	// The records still buffered are written when the manager stops
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sink.Start(ctx); err != nil {
+		t.Fatalf("unexpected error stopping the sink: %v", err)
+	}
+	if len(recording.records) != 2 {
+		t.Errorf("expected the 2 buffered records written, got %d", len(recording.records))
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package celselector selects Kubernetes objects evaluating CEL expressions against them
+package celselector
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// NamespaceVariable is the name of the variable holding the Namespace inside the expressions
+	NamespaceVariable = "ns"
+
+	// ObjectVariable is the name of the variable holding the object inside the expressions over any kind
+	ObjectVariable = "object"
+)
+
+// Selector evaluates a compiled CEL expression against objects
+type Selector struct {
+	program  cel.Program
+	variable string
+}
+
+// Compile return a Selector for the expression over a Namespace. The expression must return a boolean
+func Compile(expression string) (selector *Selector, err error) {
+	return CompileFor(NamespaceVariable, expression)
+}
+
+// CompileFor return a Selector for the expression, where the object is available as the variable.
+// The expression must return a boolean
+func CompileFor(variable, expression string) (selector *Selector, err error) {
+
+	var env *cel.Env
+	env, err = cel.NewEnv(cel.Variable(variable, cel.DynType))
+	if err != nil {
+		return selector, err
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		err = issues.Err()
+		return selector, err
+	}
+
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		err = fmt.Errorf("the expression must return a boolean, got %s", ast.OutputType())
+		return selector, err
+	}
+
+	var program cel.Program
+	program, err = env.Program(ast)
+	if err != nil {
+		return selector, err
+	}
+
+	selector = &Selector{program: program, variable: variable}
+	return selector, err
+}
+
+// Matches evaluate the expression against the object, exposed as a map with its JSON fields
+func (s *Selector) Matches(object runtime.Object) (matches bool, err error) {
+
+	var content map[string]interface{}
+	content, err = runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+	if err != nil {
+		return matches, err
+	}
+
+	out, _, err := s.program.Eval(map[string]interface{}{
+		s.variable: content,
+	})
+	if err != nil {
+		return matches, err
+	}
+
+	matches, ok := out.Value().(bool)
+	if !ok {
+		err = errors.New("the expression did not return a boolean")
+	}
+	return matches, err
+}
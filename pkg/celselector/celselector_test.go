@@ -0,0 +1,96 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package celselector
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		invalid    bool
+	}{
+		{name: "label comparison", expression: `ns.metadata.labels.team == "platform"`},
+		{name: "label presence", expression: `has(ns.metadata.labels.env)`},
+		{name: "syntax error", expression: `ns.metadata.labels.team ==`, invalid: true},
+		{name: "not a boolean", expression: `ns.metadata.name + "-suffix"`, invalid: true},
+		{name: "unknown variable", expression: `object.metadata.name == "default"`, invalid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Compile(test.expression)
+			if (err != nil) != test.invalid {
+				t.Errorf("expected invalid %t, got %v", test.invalid, err)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "team-a",
+		Labels: map[string]string{"team": "platform", "env": "production"},
+	}}
+
+	tests := []struct {
+		name       string
+		expression string
+		expected   bool
+		failed     bool
+	}{
+		{name: "matching label", expression: `ns.metadata.labels.team == "platform"`, expected: true},
+		{name: "other label value", expression: `ns.metadata.labels.team == "data"`},
+		{name: "name prefix", expression: `ns.metadata.name.startsWith("team-")`, expected: true},
+		{name: "several conditions", expression: `ns.metadata.labels.env == "production" && !has(ns.metadata.labels.legacy)`, expected: true},
+		{name: "missing label", expression: `ns.metadata.labels.legacy == "true"`, failed: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selector, err := Compile(test.expression)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			matches, err := selector.Matches(namespace)
+			if (err != nil) != test.failed {
+				t.Fatalf("expected failed %t, got %v", test.failed, err)
+			}
+			if matches != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, matches)
+			}
+		})
+	}
+}
+
+func TestCompileFor(t *testing.T) {
+	selector, err := CompileFor(ObjectVariable, `object.data.ready == "true"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	configMap := &corev1.ConfigMap{Data: map[string]string{"ready": "true"}}
+	if matches, err := selector.Matches(configMap); err != nil || !matches {
+		t.Errorf("expected the object matched, got %t: %v", matches, err)
+	}
+}
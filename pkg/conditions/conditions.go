@@ -0,0 +1,121 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package conditions manages the conditions of the status of the resources, shared by all the reconcilers.
+// The transition time of a condition only changes when its status changes, so it tells since when
+// the resource is in that state
+package conditions
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// New return a condition whose message is formatted with the args, when any
+func New(condType string, status metav1.ConditionStatus, reason, messageFormat string, args ...interface{}) metav1.Condition {
+	message := messageFormat
+	if len(args) > 0 {
+		message = fmt.Sprintf(messageFormat, args...)
+	}
+
+	return metav1.Condition{
+		Type:    condType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+}
+
+// Get return the condition with the type, or nil when not present
+func Get(conditions []metav1.Condition, condType string) *metav1.Condition {
+	for i := range conditions {
+		if conditions[i].Type == condType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// Has return true when the condition with the type is present with the status
+func Has(conditions []metav1.Condition, condType string, status metav1.ConditionStatus) bool {
+	condition := Get(conditions, condType)
+	return condition != nil && condition.Status == status
+}
+
+// IsTrue return true when the condition with the type is present and true
+func IsTrue(conditions []metav1.Condition, condType string) bool {
+	return Has(conditions, condType, metav1.ConditionTrue)
+}
+
+// IsFalse return true when the condition with the type is present and false
+func IsFalse(conditions []metav1.Condition, condType string) bool {
+	return Has(conditions, condType, metav1.ConditionFalse)
+}
+
+// Set create or update the condition with the type of the given one. The transition time is kept
+// while the status does not change. It return true when the status transitioned, including the creation
+func Set(conditions *[]metav1.Condition, condition metav1.Condition) (transitioned bool) {
+
+	current := Get(*conditions, condition.Type)
+	if current == nil {
+		if condition.LastTransitionTime.IsZero() {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		*conditions = append(*conditions, condition)
+		return true
+	}
+
+	transitioned = current.Status != condition.Status
+	if transitioned {
+		current.Status = condition.Status
+		current.LastTransitionTime = condition.LastTransitionTime
+		if current.LastTransitionTime.IsZero() {
+			current.LastTransitionTime = metav1.Now()
+		}
+	}
+	current.Reason = condition.Reason
+	current.Message = condition.Message
+	current.ObservedGeneration = condition.ObservedGeneration
+
+	return transitioned
+}
+
+// MarkTrue set the condition with the type as true
+func MarkTrue(conditions *[]metav1.Condition, condType, reason, messageFormat string, args ...interface{}) (transitioned bool) {
+	return Set(conditions, New(condType, metav1.ConditionTrue, reason, messageFormat, args...))
+}
+
+// MarkFalse set the condition with the type as false
+func MarkFalse(conditions *[]metav1.Condition, condType, reason, messageFormat string, args ...interface{}) (transitioned bool) {
+	return Set(conditions, New(condType, metav1.ConditionFalse, reason, messageFormat, args...))
+}
+
+// MarkUnknown set the condition with the type as unknown
+func MarkUnknown(conditions *[]metav1.Condition, condType, reason, messageFormat string, args ...interface{}) (transitioned bool) {
+	return Set(conditions, New(condType, metav1.ConditionUnknown, reason, messageFormat, args...))
+}
+
+// Remove delete the condition with the type. It return true when it was present
+func Remove(conditions *[]metav1.Condition, condType string) (removed bool) {
+	for i := range *conditions {
+		if (*conditions)[i].Type == condType {
+			*conditions = append((*conditions)[:i], (*conditions)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conditions
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		args     []interface{}
+		expected string
+	}{
+		{name: "without args", format: "Source was 100% synchronized", expected: "Source was 100% synchronized"},
+		{name: "with args", format: "%d targets remaining", args: []interface{}{3}, expected: "3 targets remaining"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			condition := New("SourceSynced", metav1.ConditionTrue, "SourceSynced", test.format, test.args...)
+			if condition.Message != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, condition.Message)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	previous := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+
+	tests := []struct {
+		name         string
+		conditions   []metav1.Condition
+		condition    metav1.Condition
+		transitioned bool
+		keepsTime    bool
+	}{
+		{
+			name:         "created",
+			condition:    New("SourceSynced", metav1.ConditionTrue, "SourceSynced", "synchronized"),
+			transitioned: true,
+		},
+		{
+			name: "same status",
+			conditions: []metav1.Condition{
+				{Type: "SourceSynced", Status: metav1.ConditionTrue, Reason: "SourceSynced", LastTransitionTime: previous},
+			},
+			condition: New("SourceSynced", metav1.ConditionTrue, "SourceSynced", "synchronized again"),
+			keepsTime: true,
+		},
+		{
+			name: "status transitioned",
+			conditions: []metav1.Condition{
+				{Type: "SourceSynced", Status: metav1.ConditionTrue, Reason: "SourceSynced", LastTransitionTime: previous},
+			},
+			condition:    New("SourceSynced", metav1.ConditionFalse, "SourceNotFound", "not found"),
+			transitioned: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conditions := test.conditions
+			if transitioned := Set(&conditions, test.condition); transitioned != test.transitioned {
+				t.Errorf("expected transitioned %t, got %t", test.transitioned, transitioned)
+			}
+
+			condition := Get(conditions, test.condition.Type)
+			if len(conditions) != 1 || condition == nil {
+				t.Fatalf("expected a single condition, got %v", conditions)
+			}
+			if condition.Status != test.condition.Status || condition.Reason != test.condition.Reason || condition.Message != test.condition.Message {
+				t.Errorf("expected %v, got %v", test.condition, *condition)
+			}
+			if condition.LastTransitionTime.IsZero() {
+				t.Errorf("expected the transition time set")
+			}
+			if condition.LastTransitionTime.Equal(&previous) != test.keepsTime {
+				t.Errorf("expected the transition time kept %t, got %v", test.keepsTime, condition.LastTransitionTime)
+			}
+		})
+	}
+}
+
+func TestStatusHelpers(t *testing.T) {
+	var conditions []metav1.Condition
+	MarkFalse(&conditions, "SourceSynced", "SourceNotFound", "not found")
+	MarkTrue(&conditions, "Ready", "Ready", "ready")
+
+	if !IsFalse(conditions, "SourceSynced") || IsTrue(conditions, "SourceSynced") {
+		t.Errorf("expected SourceSynced false, got %v", conditions)
+	}
+	if !IsTrue(conditions, "Ready") {
+		t.Errorf("expected Ready true, got %v", conditions)
+	}
+	if IsTrue(conditions, "Missing") || IsFalse(conditions, "Missing") {
+		t.Errorf("expected a missing condition neither true nor false")
+	}
+
+	if !Remove(&conditions, "Ready") || Remove(&conditions, "Ready") {
+		t.Errorf("expected Ready removed once, got %v", conditions)
+	}
+	if len(conditions) != 1 {
+		t.Errorf("expected a single condition left, got %v", conditions)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ErrTargetConflict is returned when the namespace already has an object with the name of the target
+// that was not written by the replicator, so the objects of the namespace owners are never overwritten
+var ErrTargetConflict = errors.New("the namespace has an object with the same name not written by the replicator")
+
+// IsBaselineKind return true for the LimitRanges and ResourceQuotas, which enforce the resource baseline of a namespace
+func IsBaselineKind(gvk schema.GroupVersionKind) bool {
+	if gvk.Group != "" || gvk.Version != "v1" {
+		return false
+	}
+	return gvk.Kind == "LimitRange" || gvk.Kind == "ResourceQuota"
+}
+
+// IsManagedBy return true when some field of the object was written by the manager
+func IsManagedBy(object *unstructured.Unstructured, manager string) bool {
+	for _, entry := range object.GetManagedFields() {
+		if entry.Manager == manager {
+			return true
+		}
+	}
+	return false
+}
+
+// IsImmutableError return true when the API server refused the change of an immutable field
+func IsImmutableError(err error) bool {
+	return apierrors.IsInvalid(err) && strings.Contains(err.Error(), "field is immutable")
+}
+
+// pruneMergePatch add to the patch a null for each key of existing not defined on desired,
+// so the limits removed from the source are also removed from the targets
+func pruneMergePatch(patch, existing, desired map[string]interface{}) {
+	for k, existingValue := range existing {
+		desiredValue, found := desired[k]
+		if !found {
+			patch[k] = nil
+			continue
+		}
+
+		desiredMap, desiredIsMap := desiredValue.(map[string]interface{})
+		existingMap, existingIsMap := existingValue.(map[string]interface{})
+		if !desiredIsMap || !existingIsMap {
+			continue
+		}
+
+		nestedPatch, _ := patch[k].(map[string]interface{})
+		if nestedPatch == nil {
+			nestedPatch = map[string]interface{}{}
+		}
+		pruneMergePatch(nestedPatch, existingMap, desiredMap)
+		if len(nestedPatch) > 0 {
+			patch[k] = nestedPatch
+		}
+	}
+}
+
+// updateBaselineTarget update a LimitRange or ResourceQuota existing in the namespace of the target.
+// The spec is enforced as a whole, removing the limits not defined on the source, and the target is
+// recreated when an immutable field changes, like the scopes of a ResourceQuota
+func (r *replicator) updateBaselineTarget(ctx context.Context, existing, target *unstructured.Unstructured, dryRun bool) (result Result, err error) {
+
+	if !IsManagedBy(existing, FieldManager) {
+		err = fmt.Errorf("%w: %s %s/%s", ErrTargetConflict, target.GetKind(), target.GetNamespace(), target.GetName())
+		return result, err
+	}
+
+	createOptions := []client.CreateOption{client.FieldOwner(FieldManager)}
+	patchOptions := []client.PatchOption{client.FieldOwner(FieldManager)}
+	if dryRun {
+		createOptions = append(createOptions, client.DryRunAll)
+		patchOptions = append(patchOptions, client.DryRunAll)
+	}
+
+	result = ResultUnchanged
+	patchContent := MinimalMergePatch(existing.Object, target.Object)
+	existingSpec, _, _ := unstructured.NestedMap(existing.Object, "spec")
+	desiredSpec, _, _ := unstructured.NestedMap(target.Object, "spec")
+	specPatch, _ := patchContent["spec"].(map[string]interface{})
+	if specPatch == nil {
+		specPatch = map[string]interface{}{}
+	}
+	pruneMergePatch(specPatch, existingSpec, desiredSpec)
+	if len(specPatch) > 0 {
+		patchContent["spec"] = specPatch
+	}
+	if len(patchContent) == 0 {
+		return result, err
+	}
+	if r.options.OptimisticLock {
+		LockPatch(patchContent, existing.GetResourceVersion())
+	}
+
+	var patch []byte
+	patch, err = json.Marshal(patchContent)
+	if err != nil {
+		return result, err
+	}
+
+	result = ResultUpdated
+	err = r.client.Patch(ctx, target.DeepCopy(), client.RawPatch(types.MergePatchType, patch), patchOptions...)
+	if !IsImmutableError(err) {
+		return result, err
+	}
+
+	// Immutable fields can only change by replacing the object. The dry-run can not validate the
+	// creation while the object exists, so the replacement is assumed to be accepted
+	result = ResultRecreated
+	if dryRun {
+		err = nil
+		return result, err
+	}
+
+	uid := existing.GetUID()
+	err = r.client.Delete(ctx, existing, client.Preconditions{UID: &uid})
+	if client.IgnoreNotFound(err) != nil {
+		return result, err
+	}
+	err = r.client.Create(ctx, target.DeepCopy(), createOptions...)
+
+	return result, err
+}
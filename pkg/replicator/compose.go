@@ -0,0 +1,144 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicator
+
+import (
+	"encoding/base64"
+	"fmt"
+	"unicode/utf8"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// OutputKey defines a key of the data of the source copied into an output, renamed when To is set
+type OutputKey struct {
+	From string
+	To   string
+}
+
+// Output defines an object composed from the data of a ConfigMap or Secret source
+type Output struct {
+	Kind string
+	Name string
+
+	// Type of the Secret outputs, Opaque when empty
+	Type string
+
+	// Keys copied from the source, all of them when empty
+	Keys []OutputKey
+}
+
+// Compose return the output built from the data of the source, converting the values between the kinds.
+// The labels and annotations of the source are kept. Values that are not valid UTF-8 are stored as
+// binaryData in the ConfigMap outputs
+func Compose(source *unstructured.Unstructured, output Output) (composed *unstructured.Unstructured, err error) {
+
+	if !IsCoreDataKind(source.GroupVersionKind()) {
+		err = fmt.Errorf("the outputs can only be composed from ConfigMaps and Secrets, not from %s", source.GetKind())
+		return composed, err
+	}
+
+	var data map[string][]byte
+	data, err = getData(source)
+	if err != nil {
+		return composed, err
+	}
+
+	if len(output.Keys) > 0 {
+		selected := map[string][]byte{}
+		for _, key := range output.Keys {
+			value, found := data[key.From]
+			if !found {
+				err = fmt.Errorf("the key %s of the output %s %s is missing in the source", key.From, output.Kind, output.Name)
+				return composed, err
+			}
+			name := key.To
+			if name == "" {
+				name = key.From
+			}
+			selected[name] = value
+		}
+		data = selected
+	}
+
+	composed = &unstructured.Unstructured{}
+	composed.SetAPIVersion("v1")
+	composed.SetKind(output.Kind)
+	composed.SetNamespace(source.GetNamespace())
+	composed.SetName(output.Name)
+	composed.SetLabels(source.GetLabels())
+	composed.SetAnnotations(source.GetAnnotations())
+
+	switch output.Kind {
+	case "Secret":
+		secretType := output.Type
+		if secretType == "" {
+			secretType = "Opaque"
+		}
+		composed.Object["type"] = secretType
+		encoded := map[string]interface{}{}
+		for k, v := range data {
+			encoded[k] = base64.StdEncoding.EncodeToString(v)
+		}
+		composed.Object["data"] = encoded
+	case "ConfigMap":
+		text := map[string]interface{}{}
+		binary := map[string]interface{}{}
+		for k, v := range data {
+			if utf8.Valid(v) {
+				text[k] = string(v)
+				continue
+			}
+			binary[k] = base64.StdEncoding.EncodeToString(v)
+		}
+		composed.Object["data"] = text
+		if len(binary) > 0 {
+			composed.Object["binaryData"] = binary
+		}
+	default:
+		err = fmt.Errorf("the kind %s of the output %s is not a ConfigMap or a Secret", output.Kind, output.Name)
+	}
+
+	return composed, err
+}
+
+// getData return the decoded data of a ConfigMap or Secret
+func getData(source *unstructured.Unstructured) (data map[string][]byte, err error) {
+
+	data = map[string][]byte{}
+	encodedFields := []string{"data"}
+	if source.GetKind() == "ConfigMap" {
+		text, _, _ := unstructured.NestedStringMap(source.Object, "data")
+		for k, v := range text {
+			data[k] = []byte(v)
+		}
+		encodedFields = []string{"binaryData"}
+	}
+
+	for _, field := range encodedFields {
+		encoded, _, _ := unstructured.NestedStringMap(source.Object, field)
+		for k, v := range encoded {
+			data[k], err = base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				err = fmt.Errorf("the key %s is not valid base64: %w", k, err)
+				return data, err
+			}
+		}
+	}
+
+	return data, err
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// IsCoreDataKind return true for the ConfigMaps and Secrets, synchronized through the typed fast path
+func IsCoreDataKind(gvk schema.GroupVersionKind) bool {
+	if gvk.Group != "" || gvk.Version != "v1" {
+		return false
+	}
+	return gvk.Kind == "ConfigMap" || gvk.Kind == "Secret"
+}
+
+// ErrImmutableTarget is returned when an immutable ConfigMap or Secret holds other data than the source.
+// The API server refuses to change it, so it can only be replaced
+var ErrImmutableTarget = errors.New("the target is immutable and its data differs from the source")
+
+// IsImmutable return true for the ConfigMaps and Secrets marked as immutable
+func IsImmutable(object *unstructured.Unstructured) bool {
+	if !IsCoreDataKind(object.GroupVersionKind()) {
+		return false
+	}
+	immutable, _, _ := unstructured.NestedBool(object.Object, "immutable")
+	return immutable
+}
+
+// isImmutableCore return true for the typed ConfigMaps and Secrets marked as immutable
+func isImmutableCore(object client.Object) bool {
+	switch typedObject := object.(type) {
+	case *corev1.ConfigMap:
+		return typedObject.Immutable != nil && *typedObject.Immutable
+	case *corev1.Secret:
+		return typedObject.Immutable != nil && *typedObject.Immutable
+	}
+	return false
+}
+
+// equalCoreData return true when both ConfigMaps or Secrets hold the same data. Only the data is compared,
+// as it is the only content frozen by the immutability, the metadata can still be updated
+func equalCoreData(a, b client.Object) bool {
+	switch aObject := a.(type) {
+	case *corev1.ConfigMap:
+		bObject := b.(*corev1.ConfigMap)
+		return equality.Semantic.DeepEqual(aObject.Data, bObject.Data) &&
+			equality.Semantic.DeepEqual(aObject.BinaryData, bObject.BinaryData)
+	case *corev1.Secret:
+		bObject := b.(*corev1.Secret)
+		return equality.Semantic.DeepEqual(aObject.Data, bObject.Data) &&
+			equality.Semantic.DeepEqual(aObject.StringData, bObject.StringData)
+	}
+	return true
+}
+
+// mergeImmutable mark the existing object as immutable when desired, returning whether something changed.
+// Immutable objects can not become mutable again, so the mark is never removed
+func mergeImmutable(existing **bool, desired *bool) (changed bool) {
+	if desired == nil || !*desired || (*existing != nil && **existing) {
+		return false
+	}
+	immutable := true
+	*existing = &immutable
+	return true
+}
+
+// mergeStrings set the desired keys on the existing map, returning whether something changed.
+// Keys not desired are kept, the same way as the merge patches of the rest of the kinds
+func mergeStrings(existing *map[string]string, desired map[string]string) (changed bool) {
+	for k, v := range desired {
+		if current, found := (*existing)[k]; found && current == v {
+			continue
+		}
+		if *existing == nil {
+			*existing = map[string]string{}
+		}
+		(*existing)[k] = v
+		changed = true
+	}
+	return changed
+}
+
+// mergeBytes set the desired keys on the existing map, returning whether something changed
+func mergeBytes(existing *map[string][]byte, desired map[string][]byte) (changed bool) {
+	for k, v := range desired {
+		if current, found := (*existing)[k]; found && bytes.Equal(current, v) {
+			continue
+		}
+		if *existing == nil {
+			*existing = map[string][]byte{}
+		}
+		(*existing)[k] = v
+		changed = true
+	}
+	return changed
+}
+
+// mergeOwnerReferences add the desired owner references missing on the existing object, returning whether something changed.
+// Owners set by others are kept
+func mergeOwnerReferences(existing, desired client.Object) (changed bool) {
+	ownerReferences := existing.GetOwnerReferences()
+	for _, desiredReference := range desired.GetOwnerReferences() {
+		found := false
+		for _, reference := range ownerReferences {
+			if reference.UID == desiredReference.UID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ownerReferences = append(ownerReferences, desiredReference)
+			changed = true
+		}
+	}
+
+	if changed {
+		existing.SetOwnerReferences(ownerReferences)
+	}
+	return changed
+}
+
+// mergeMetadata set the desired labels, annotations and owners on the existing object, returning whether something changed
+func mergeMetadata(existing, desired client.Object) (changed bool) {
+	labels := existing.GetLabels()
+	annotations := existing.GetAnnotations()
+
+	changed = mergeStrings(&labels, desired.GetLabels())
+	changed = mergeStrings(&annotations, desired.GetAnnotations()) || changed
+
+	existing.SetLabels(labels)
+	existing.SetAnnotations(annotations)
+	return mergeOwnerReferences(existing, desired) || changed
+}
+
+// mergeCoreData set the data of the desired ConfigMap or Secret on the existing one, returning whether something changed.
+// Only the data, the type, the immutable mark, the labels, the annotations and the owners are synchronized
+func mergeCoreData(existing, desired client.Object) (changed bool) {
+	switch existingObject := existing.(type) {
+	case *corev1.ConfigMap:
+		desiredObject := desired.(*corev1.ConfigMap)
+		changed = mergeStrings(&existingObject.Data, desiredObject.Data)
+		changed = mergeBytes(&existingObject.BinaryData, desiredObject.BinaryData) || changed
+		changed = mergeImmutable(&existingObject.Immutable, desiredObject.Immutable) || changed
+
+	case *corev1.Secret:
+		desiredObject := desired.(*corev1.Secret)
+		changed = mergeBytes(&existingObject.Data, desiredObject.Data)
+		if desiredObject.Type != "" && existingObject.Type != desiredObject.Type {
+			existingObject.Type = desiredObject.Type
+			changed = true
+		}
+		changed = mergeImmutable(&existingObject.Immutable, desiredObject.Immutable) || changed
+	}
+
+	return mergeMetadata(existing, desired) || changed
+}
+
+// newCoreObject return an empty typed object for the kind
+func newCoreObject(kind string) client.Object {
+	if kind == "Secret" {
+		return &corev1.Secret{}
+	}
+	return &corev1.ConfigMap{}
+}
+
+// updateCoreTarget update a ConfigMap or Secret through the typed client, or create it when not existent.
+// Typed objects are read through the reader of the options and only the data is compared, avoiding the processing
+// of the whole unstructured object on installations with thousands of targets
+func (r *replicator) updateCoreTarget(ctx context.Context, target *unstructured.Unstructured, dryRun bool) (result Result, err error) {
+
+	createOptions := []client.CreateOption{client.FieldOwner(FieldManager)}
+	patchOptions := []client.PatchOption{client.FieldOwner(FieldManager)}
+	if dryRun {
+		createOptions = append(createOptions, client.DryRunAll)
+		patchOptions = append(patchOptions, client.DryRunAll)
+	}
+
+	desired := newCoreObject(target.GetKind())
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(target.Object, desired)
+	if err != nil {
+		return result, err
+	}
+
+	existing := newCoreObject(target.GetKind())
+	err = r.reader().Get(ctx, client.ObjectKeyFromObject(desired), existing)
+
+	// Create the resource when it is not found
+	if apierrors.IsNotFound(err) {
+		result = ResultCreated
+		err = r.client.Create(ctx, desired, createOptions...)
+		return result, err
+	}
+	if err != nil {
+		return result, err
+	}
+
+	// Objects written by others are never taken over unless requested
+	err = r.checkOwnership(existing, desired)
+	if err != nil {
+		return result, err
+	}
+
+	// Update only the data that changed
+	result = ResultUnchanged
+	original := existing.DeepCopyObject().(client.Object)
+	patch := client.MergeFrom(original)
+	if r.options.OptimisticLock {
+		patch = client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{})
+	}
+	if !mergeCoreData(existing, desired) {
+		return result, err
+	}
+
+	// Immutable objects only accept changes of their metadata, the rest is refused instead of failing on the API server
+	if isImmutableCore(original) && !equalCoreData(original, existing) {
+		err = fmt.Errorf("%w: %s %s/%s", ErrImmutableTarget, target.GetKind(), target.GetNamespace(), target.GetName())
+		return result, err
+	}
+
+	result = ResultUpdated
+	err = r.client.Patch(ctx, existing, patch, patchOptions...)
+
+	return result, err
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicator
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestIsImmutable(t *testing.T) {
+	tests := []struct {
+		name     string
+		object   map[string]interface{}
+		expected bool
+	}{
+		{
+			name:     "immutable ConfigMap",
+			object:   map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap", "immutable": true},
+			expected: true,
+		},
+		{
+			name:     "immutable Secret",
+			object:   map[string]interface{}{"apiVersion": "v1", "kind": "Secret", "immutable": true},
+			expected: true,
+		},
+		{
+			name:   "mutable ConfigMap",
+			object: map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap"},
+		},
+		{
+			name:   "other kinds",
+			object: map[string]interface{}{"apiVersion": "example.com/v1", "kind": "Config", "immutable": true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if immutable := IsImmutable(&unstructured.Unstructured{Object: test.object}); immutable != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, immutable)
+			}
+		})
+	}
+}
+
+func TestEqualCoreData(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        client.Object
+		b        client.Object
+		expected bool
+	}{
+		{
+			name:     "same ConfigMap data",
+			a:        &corev1.ConfigMap{Data: map[string]string{"a": "1"}},
+			b:        &corev1.ConfigMap{Data: map[string]string{"a": "1"}},
+			expected: true,
+		},
+		{
+			name: "different ConfigMap data",
+			a:    &corev1.ConfigMap{Data: map[string]string{"a": "1"}},
+			b:    &corev1.ConfigMap{Data: map[string]string{"a": "2"}},
+		},
+		{
+			name: "different ConfigMap binary data",
+			a:    &corev1.ConfigMap{BinaryData: map[string][]byte{"a": []byte("1")}},
+			b:    &corev1.ConfigMap{BinaryData: map[string][]byte{"a": []byte("2")}},
+		},
+		{
+			name: "ConfigMaps differing only on the metadata",
+			a:    &corev1.ConfigMap{Data: map[string]string{"a": "1"}},
+			b: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"b": "2"}},
+				Data:       map[string]string{"a": "1"},
+			},
+			expected: true,
+		},
+		{
+			name: "different Secret data",
+			a:    &corev1.Secret{Data: map[string][]byte{"a": []byte("1")}},
+			b:    &corev1.Secret{Data: map[string][]byte{"a": []byte("2")}},
+		},
+		{
+			name: "different Secret string data",
+			a:    &corev1.Secret{StringData: map[string]string{"a": "1"}},
+			b:    &corev1.Secret{StringData: map[string]string{"a": "2"}},
+		},
+		{
+			name:     "Secrets differing only on the type",
+			a:        &corev1.Secret{Data: map[string][]byte{"a": []byte("1")}, Type: corev1.SecretTypeOpaque},
+			b:        &corev1.Secret{Data: map[string][]byte{"a": []byte("1")}, Type: corev1.SecretTypeTLS},
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if equal := equalCoreData(test.a, test.b); equal != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, equal)
+			}
+		})
+	}
+}
+
+func TestMergeImmutable(t *testing.T) {
+	immutable, mutable := true, false
+
+	tests := []struct {
+		name     string
+		existing *bool
+		desired  *bool
+		changed  bool
+		expected bool
+	}{
+		{name: "not desired", existing: nil, desired: nil},
+		{name: "desired as mutable", existing: nil, desired: &mutable},
+		{name: "marked", existing: nil, desired: &immutable, changed: true, expected: true},
+		{name: "already immutable", existing: &immutable, desired: &immutable, expected: true},
+		{name: "never made mutable again", existing: &immutable, desired: &mutable, expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			existing := test.existing
+			if changed := mergeImmutable(&existing, test.desired); changed != test.changed {
+				t.Errorf("expected changed %t, got %t", test.changed, changed)
+			}
+			if result := existing != nil && *existing; result != test.expected {
+				t.Errorf("expected immutable %t, got %t", test.expected, result)
+			}
+		})
+	}
+}
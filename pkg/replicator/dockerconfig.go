@@ -0,0 +1,117 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// Types of the Secrets holding the credentials of the registries, and the key of their content
+	dockerConfigJSONType = "kubernetes.io/dockerconfigjson"
+	dockerConfigJSONKey  = ".dockerconfigjson"
+	dockerConfigType     = "kubernetes.io/dockercfg"
+	dockerConfigKey      = ".dockercfg"
+)
+
+// IsDockerConfigSecret return true for the Secrets holding the credentials of the registries
+func IsDockerConfigSecret(object *unstructured.Unstructured) bool {
+	if object.GetAPIVersion() != "v1" || object.GetKind() != "Secret" {
+		return false
+	}
+	secretType, _, _ := unstructured.NestedString(object.Object, "type")
+	return secretType == dockerConfigJSONType || secretType == dockerConfigType
+}
+
+// RewriteRegistries rename the registries of the docker config Secrets with the rewrites, keeping their credentials.
+// The credentials of a registry already present under the new name are replaced
+func RewriteRegistries(targets []unstructured.Unstructured, rewrites map[string]string) (err error) {
+
+	for i := range targets {
+		if !IsDockerConfigSecret(&targets[i]) {
+			continue
+		}
+
+		key := dockerConfigJSONKey
+		secretType, _, _ := unstructured.NestedString(targets[i].Object, "type")
+		if secretType == dockerConfigType {
+			key = dockerConfigKey
+		}
+
+		encoded, found, _ := unstructured.NestedString(targets[i].Object, "data", key)
+		if !found {
+			continue
+		}
+
+		var content []byte
+		content, err = base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return err
+		}
+
+		content, err = rewriteDockerConfig(content, secretType == dockerConfigJSONType, rewrites)
+		if err != nil {
+			return err
+		}
+
+		err = unstructured.SetNestedField(targets[i].Object, base64.StdEncoding.EncodeToString(content), "data", key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// rewriteDockerConfig rename the registries of the content of a docker config. The registries of the
+// '.dockerconfigjson' format are nested under 'auths', while the legacy '.dockercfg' has them at the root
+func rewriteDockerConfig(content []byte, nested bool, rewrites map[string]string) (result []byte, err error) {
+
+	config := map[string]interface{}{}
+	err = json.Unmarshal(content, &config)
+	if err != nil {
+		return result, err
+	}
+
+	registries := config
+	if nested {
+		registries, _ = config["auths"].(map[string]interface{})
+		if registries == nil {
+			return content, err
+		}
+	}
+
+	renamed := map[string]interface{}{}
+	for registry, credentials := range registries {
+		if replacement, found := rewrites[registry]; found {
+			renamed[replacement] = credentials
+			delete(registries, registry)
+		}
+	}
+	if len(renamed) == 0 {
+		return content, err
+	}
+	for registry, credentials := range renamed {
+		registries[registry] = credentials
+	}
+
+	result, err = json.Marshal(config)
+	return result, err
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicator
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ParseFieldPath return the segments of a path like .spec.template.metadata.labels
+func ParseFieldPath(path string) (fields []string, err error) {
+	if !strings.HasPrefix(path, ".") {
+		err = fmt.Errorf("the path %q must start with a dot", path)
+		return fields, err
+	}
+
+	fields = strings.Split(strings.TrimPrefix(path, "."), ".")
+	for _, field := range fields {
+		if field == "" {
+			err = fmt.Errorf("the path %q has an empty field", path)
+			return fields, err
+		}
+	}
+	if fields[0] == "metadata" || fields[0] == "apiVersion" || fields[0] == "kind" {
+		err = fmt.Errorf("the path %q can not select the identity of the object", path)
+	}
+	return fields, err
+}
+
+// ProjectFields return a skeleton of the source with only the subtrees selected by the paths.
+// The metadata is kept, so the skeleton can be replicated as any other source. Paths missing on the source are skipped
+func ProjectFields(source *unstructured.Unstructured, paths []string) (projection *unstructured.Unstructured, err error) {
+
+	projection = &unstructured.Unstructured{Object: map[string]interface{}{}}
+	projection.SetAPIVersion(source.GetAPIVersion())
+	projection.SetKind(source.GetKind())
+	projection.Object["metadata"] = runtime.DeepCopyJSONValue(source.Object["metadata"])
+
+	for _, path := range paths {
+		var fields []string
+		fields, err = ParseFieldPath(path)
+		if err != nil {
+			return projection, err
+		}
+
+		value, found, _ := unstructured.NestedFieldNoCopy(source.Object, fields...)
+		if !found {
+			continue
+		}
+
+		err = unstructured.SetNestedField(projection.Object, runtime.DeepCopyJSONValue(value), fields...)
+		if err != nil {
+			return projection, err
+		}
+	}
+
+	return projection, err
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicator
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestParseFieldPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		expected []string
+		invalid  bool
+	}{
+		{name: "nested path", path: ".spec.template.metadata.labels", expected: []string{"spec", "template", "metadata", "labels"}},
+		{name: "top level path", path: ".data", expected: []string{"data"}},
+		{name: "without leading dot", path: "spec.replicas", invalid: true},
+		{name: "empty field", path: ".spec..replicas", invalid: true},
+		{name: "metadata", path: ".metadata.labels", invalid: true},
+		{name: "kind", path: ".kind", invalid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fields, err := ParseFieldPath(test.path)
+			if (err != nil) != test.invalid {
+				t.Fatalf("expected invalid %t, got %v", test.invalid, err)
+			}
+			if !test.invalid && !reflect.DeepEqual(fields, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, fields)
+			}
+		})
+	}
+}
+
+func TestProjectFields(t *testing.T) {
+	source := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "web", "namespace": "default"},
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "web"}},
+			},
+		},
+	}}
+
+	projection, err := ProjectFields(source, []string{".spec.template.metadata.labels", ".spec.missing"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "web", "namespace": "default"},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "web"}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(projection.Object, expected) {
+		t.Errorf("expected %v, got %v", expected, projection.Object)
+	}
+}
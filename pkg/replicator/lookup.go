@@ -0,0 +1,107 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicator
+
+import (
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// lookupPattern matches the placeholders like '{{ lookup("tenant-config").data.region }}'
+var lookupPattern = regexp.MustCompile(`\{\{\s*lookup\("([-.a-z0-9]+)"\)\.data\.([-._a-zA-Z0-9]+)\s*\}\}`)
+
+// LookupFunc return the value of the key of the ConfigMap with the name in the namespace of the target
+type LookupFunc func(name, key string) (value string, err error)
+
+// ResolveLookups replace the lookup placeholders in the string values of the target. Only the labels and
+// annotations are walked inside the metadata, so the identity of the target never changes
+func ResolveLookups(target *unstructured.Unstructured, lookup LookupFunc) (err error) {
+
+	for k, v := range target.Object {
+		if k == "metadata" {
+			continue
+		}
+		target.Object[k], err = resolveLookups(v, lookup)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, field := range []string{"labels", "annotations"} {
+		values, found, _ := unstructured.NestedStringMap(target.Object, "metadata", field)
+		if !found {
+			continue
+		}
+		for k, v := range values {
+			values[k], err = resolveLookupString(v, lookup)
+			if err != nil {
+				return err
+			}
+		}
+		err = unstructured.SetNestedStringMap(target.Object, values, "metadata", field)
+		if err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// resolveLookups walk the object replacing the placeholders of the strings
+func resolveLookups(object interface{}, lookup LookupFunc) (result interface{}, err error) {
+
+	switch value := object.(type) {
+	case string:
+		return resolveLookupString(value, lookup)
+
+	case map[string]interface{}:
+		for k, v := range value {
+			value[k], err = resolveLookups(v, lookup)
+			if err != nil {
+				return value, err
+			}
+		}
+
+	case []interface{}:
+		for i, v := range value {
+			value[i], err = resolveLookups(v, lookup)
+			if err != nil {
+				return value, err
+			}
+		}
+	}
+
+	return object, err
+}
+
+// resolveLookupString replace each placeholder of the text with its value
+func resolveLookupString(text string, lookup LookupFunc) (result string, err error) {
+
+	result = lookupPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		if err != nil {
+			return placeholder
+		}
+		match := lookupPattern.FindStringSubmatch(placeholder)
+
+		var value string
+		value, err = lookup(match[1], match[2])
+		return value
+	})
+
+	return result, err
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicator
+
+import (
+	"reflect"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// PrecedenceFirst keeps the value of the first source defining a key
+	PrecedenceFirst = "First"
+
+	// PrecedenceLast keeps the value of the last source defining a key
+	PrecedenceLast = "Last"
+)
+
+// mergedFields are the maps merged key by key. The rest of the fields are taken from the first source
+var mergedFields = []string{"data", "binaryData", "stringData"}
+
+// Conflict defines a key defined with different values by several sources
+type Conflict struct {
+	Field string
+	Key   string
+
+	// Sources defining the key as namespace/name, the winning one being the last
+	Sources []string
+}
+
+// Merge return a copy of the first source where the data of all of them is merged key by key.
+// Duplicated keys are resolved by the precedence and reported as conflicts when their values differ
+func Merge(sources []*unstructured.Unstructured, precedence string) (merged *unstructured.Unstructured, conflicts []Conflict) {
+
+	if len(sources) == 0 {
+		return merged, conflicts
+	}
+
+	merged = sources[0].DeepCopy()
+
+	for _, field := range mergedFields {
+		values := map[string]interface{}{}
+		owners := map[string][]string{}
+		differ := map[string]bool{}
+
+		for _, source := range sources {
+			data, found, _ := unstructured.NestedMap(source.Object, field)
+			if !found {
+				continue
+			}
+
+			sourceRef := source.GetNamespace() + "/" + source.GetName()
+			for k, v := range data {
+				previous, defined := values[k]
+				if defined && !reflect.DeepEqual(previous, v) {
+					differ[k] = true
+				}
+
+				if !defined || precedence != PrecedenceFirst {
+					values[k] = v
+					owners[k] = append(owners[k], sourceRef)
+					continue
+				}
+
+				// The first value wins, so the winner stays the last one of the list
+				owners[k] = append([]string{sourceRef}, owners[k]...)
+			}
+		}
+
+		if len(values) == 0 {
+			continue
+		}
+		_ = unstructured.SetNestedMap(merged.Object, values, field)
+
+		for k := range differ {
+			conflicts = append(conflicts, Conflict{Field: field, Key: k, Sources: owners[k]})
+		}
+	}
+
+	// Keep the conflicts in a stable order, so the status does not change between synchronizations
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		if conflicts[i].Field != conflicts[j].Field {
+			return conflicts[i].Field < conflicts[j].Field
+		}
+		return conflicts[i].Key < conflicts[j].Key
+	})
+
+	return merged, conflicts
+}
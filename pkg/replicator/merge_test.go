@@ -0,0 +1,80 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicator
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// newConfigMap return a ConfigMap source holding the data
+func newConfigMap(namespace, name string, data map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+		"data":       data,
+	}}
+}
+
+func TestMerge(t *testing.T) {
+	first := newConfigMap("default", "first", map[string]interface{}{"a": "1", "shared": "first", "same": "x"})
+	second := newConfigMap("default", "second", map[string]interface{}{"b": "2", "shared": "second", "same": "x"})
+
+	tests := []struct {
+		name       string
+		precedence string
+		expected   map[string]interface{}
+		conflicts  []Conflict
+	}{
+		{
+			name:       "last wins",
+			precedence: PrecedenceLast,
+			expected:   map[string]interface{}{"a": "1", "b": "2", "shared": "second", "same": "x"},
+			conflicts:  []Conflict{{Field: "data", Key: "shared", Sources: []string{"default/first", "default/second"}}},
+		},
+		{
+			name:       "first wins",
+			precedence: PrecedenceFirst,
+			expected:   map[string]interface{}{"a": "1", "b": "2", "shared": "first", "same": "x"},
+			conflicts:  []Conflict{{Field: "data", Key: "shared", Sources: []string{"default/second", "default/first"}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged, conflicts := Merge([]*unstructured.Unstructured{first, second}, test.precedence)
+
+			data, _, _ := unstructured.NestedMap(merged.Object, "data")
+			if !reflect.DeepEqual(data, test.expected) {
+				t.Errorf("expected data %v, got %v", test.expected, data)
+			}
+			if merged.GetName() != "first" {
+				t.Errorf("expected the metadata of the first source, got %s", merged.GetName())
+			}
+			if !reflect.DeepEqual(conflicts, test.conflicts) {
+				t.Errorf("expected conflicts %v, got %v", test.conflicts, conflicts)
+			}
+		})
+	}
+
+	if merged, _ := Merge(nil, PrecedenceLast); merged != nil {
+		t.Errorf("expected nothing merged without sources, got %v", merged)
+	}
+}
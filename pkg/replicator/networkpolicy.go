@@ -0,0 +1,109 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicator
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// Group of the NetworkPolicies whose selectors are templated
+	networkingGroup = "networking.k8s.io"
+
+	// Placeholders replaced in the values of the selectors. They are valid label values,
+	// so the source NetworkPolicy is accepted by the API server
+	TargetNamespacePlaceholder = "replika.target-namespace"
+	SourceNamespacePlaceholder = "replika.source-namespace"
+)
+
+// IsNetworkPolicyKind return true for the NetworkPolicies
+func IsNetworkPolicyKind(object *unstructured.Unstructured) bool {
+	gvk := object.GroupVersionKind()
+	return gvk.Group == networkingGroup && gvk.Kind == "NetworkPolicy"
+}
+
+// TemplateSelectors replace the placeholders in the values of the namespaceSelector and podSelector entries
+// of the NetworkPolicies with the namespace of each target and the namespace of the source
+func TemplateSelectors(targets []unstructured.Unstructured, sourceNamespace string) {
+
+	for i := range targets {
+		if !IsNetworkPolicyKind(&targets[i]) {
+			continue
+		}
+
+		spec, found := targets[i].Object["spec"].(map[string]interface{})
+		if !found {
+			continue
+		}
+
+		replacer := strings.NewReplacer(
+			TargetNamespacePlaceholder, targets[i].GetNamespace(),
+			SourceNamespacePlaceholder, sourceNamespace,
+		)
+		templateSelectors(spec, replacer)
+	}
+}
+
+// templateSelectors walk the object looking for the selectors and replacing their values
+func templateSelectors(object interface{}, replacer *strings.Replacer) {
+
+	switch value := object.(type) {
+	case map[string]interface{}:
+		for k, v := range value {
+			if k != "namespaceSelector" && k != "podSelector" {
+				templateSelectors(v, replacer)
+				continue
+			}
+			if selector, ok := v.(map[string]interface{}); ok {
+				templateSelector(selector, replacer)
+			}
+		}
+
+	case []interface{}:
+		for _, v := range value {
+			templateSelectors(v, replacer)
+		}
+	}
+}
+
+// templateSelector replace the values of the matchLabels and matchExpressions of a label selector
+func templateSelector(selector map[string]interface{}, replacer *strings.Replacer) {
+
+	if labels, ok := selector["matchLabels"].(map[string]interface{}); ok {
+		for k, v := range labels {
+			if text, ok := v.(string); ok {
+				labels[k] = replacer.Replace(text)
+			}
+		}
+	}
+
+	expressions, _ := selector["matchExpressions"].([]interface{})
+	for _, item := range expressions {
+		expression, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		values, _ := expression["values"].([]interface{})
+		for j, v := range values {
+			if text, ok := v.(string); ok {
+				values[j] = replacer.Replace(text)
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicator
+
+import (
+	"reflect"
+)
+
+// MinimalMergePatch return a JSON merge patch with only the fields of desired whose value differs on existing.
+// Fields not defined on desired are never touched, the same way as sending desired as a whole merge patch
+func MinimalMergePatch(existing, desired map[string]interface{}) (patch map[string]interface{}) {
+
+	patch = map[string]interface{}{}
+
+	for k, desiredValue := range desired {
+		existingValue, found := existing[k]
+		if !found {
+			patch[k] = desiredValue
+			continue
+		}
+
+		// Nested objects are compared field by field
+		desiredMap, desiredIsMap := desiredValue.(map[string]interface{})
+		existingMap, existingIsMap := existingValue.(map[string]interface{})
+		if desiredIsMap && existingIsMap {
+			nestedPatch := MinimalMergePatch(existingMap, desiredMap)
+			if len(nestedPatch) > 0 {
+				patch[k] = nestedPatch
+			}
+			continue
+		}
+
+		if !reflect.DeepEqual(existingValue, desiredValue) {
+			patch[k] = desiredValue
+		}
+	}
+
+	return patch
+}
+
+// LockPatch add the resourceVersion read from the target to the patch, so the API server refuses it
+// with a conflict when the target was modified since then
+func LockPatch(patch map[string]interface{}, resourceVersion string) {
+	metadata, _ := patch["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata["resourceVersion"] = resourceVersion
+	patch["metadata"] = metadata
+}
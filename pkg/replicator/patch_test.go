@@ -0,0 +1,110 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMinimalMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing map[string]interface{}
+		desired  map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name:     "equal objects",
+			existing: map[string]interface{}{"data": map[string]interface{}{"a": "1"}},
+			desired:  map[string]interface{}{"data": map[string]interface{}{"a": "1"}},
+			expected: map[string]interface{}{},
+		},
+		{
+			name:     "changed nested value",
+			existing: map[string]interface{}{"data": map[string]interface{}{"a": "1", "b": "2"}},
+			desired:  map[string]interface{}{"data": map[string]interface{}{"a": "1", "b": "3"}},
+			expected: map[string]interface{}{"data": map[string]interface{}{"b": "3"}},
+		},
+		{
+			name:     "missing field",
+			existing: map[string]interface{}{},
+			desired:  map[string]interface{}{"data": map[string]interface{}{"a": "1"}},
+			expected: map[string]interface{}{"data": map[string]interface{}{"a": "1"}},
+		},
+		{
+			name:     "fields only on existing are not touched",
+			existing: map[string]interface{}{"data": map[string]interface{}{"a": "1", "extra": "x"}},
+			desired:  map[string]interface{}{"data": map[string]interface{}{"a": "1"}},
+			expected: map[string]interface{}{},
+		},
+		{
+			name:     "lists are replaced as a whole",
+			existing: map[string]interface{}{"rules": []interface{}{"a", "b"}},
+			desired:  map[string]interface{}{"rules": []interface{}{"a"}},
+			expected: map[string]interface{}{"rules": []interface{}{"a"}},
+		},
+		{
+			name:     "type change of a field",
+			existing: map[string]interface{}{"spec": "text"},
+			desired:  map[string]interface{}{"spec": map[string]interface{}{"a": "1"}},
+			expected: map[string]interface{}{"spec": map[string]interface{}{"a": "1"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patch := MinimalMergePatch(test.existing, test.desired)
+			if !reflect.DeepEqual(patch, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, patch)
+			}
+		})
+	}
+}
+
+func TestLockPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name:  "patch without metadata",
+			patch: map[string]interface{}{"data": map[string]interface{}{"a": "1"}},
+			expected: map[string]interface{}{
+				"data":     map[string]interface{}{"a": "1"},
+				"metadata": map[string]interface{}{"resourceVersion": "42"},
+			},
+		},
+		{
+			name:  "patch with metadata",
+			patch: map[string]interface{}{"metadata": map[string]interface{}{"labels": map[string]interface{}{"a": "1"}}},
+			expected: map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": map[string]interface{}{"a": "1"}, "resourceVersion": "42"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			LockPatch(test.patch, "42")
+			if !reflect.DeepEqual(test.patch, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, test.patch)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicator
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// Group of the RBAC kinds whose subjects are rewritten
+	rbacGroup = "rbac.authorization.k8s.io"
+
+	// Kind of the subjects living in a namespace
+	serviceAccountSubjectKind = "ServiceAccount"
+)
+
+// IsBindingKind return true for the RoleBindings, the only RBAC kind whose subjects are namespaced
+// by the binding itself
+func IsBindingKind(object *unstructured.Unstructured) bool {
+	gvk := object.GroupVersionKind()
+	return gvk.Group == rbacGroup && gvk.Kind == "RoleBinding"
+}
+
+// RewriteSubjectNamespaces set the namespace of each target on the ServiceAccount subjects of the RoleBindings
+// that reference the namespace of the source. Subjects of other namespaces are kept, as they point to
+// shared ServiceAccounts on purpose
+func RewriteSubjectNamespaces(targets []unstructured.Unstructured, sourceNamespace string) (err error) {
+
+	for i := range targets {
+		if !IsBindingKind(&targets[i]) {
+			continue
+		}
+
+		var subjects []interface{}
+		var found bool
+		subjects, found, err = unstructured.NestedSlice(targets[i].Object, "subjects")
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+
+		for _, item := range subjects {
+			subject, ok := item.(map[string]interface{})
+			if !ok || subject["kind"] != serviceAccountSubjectKind {
+				continue
+			}
+			if namespace, _ := subject["namespace"].(string); namespace != sourceNamespace {
+				continue
+			}
+			subject["namespace"] = targets[i].GetNamespace()
+		}
+
+		err = unstructured.SetNestedSlice(targets[i].Object, subjects, "subjects")
+		if err != nil {
+			return err
+		}
+	}
+
+	return err
+}
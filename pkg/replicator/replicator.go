@@ -0,0 +1,273 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package replicator copies a Kubernetes object into several namespaces.
+// It does not depend on the Replika resource, so it can be embedded by other operators
+package replicator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// FieldManager is the manager of the fields written on the targets, so changes made by others can be told apart
+const FieldManager = "replika-controller"
+
+// Result defines the change made on a target by UpdateTarget
+type Result string
+
+const (
+	ResultCreated   Result = "Created"
+	ResultUpdated   Result = "Updated"
+	ResultUnchanged Result = "Unchanged"
+	ResultRecreated Result = "Recreated"
+)
+
+// Replicator creates, updates and deletes the copies of an object across namespaces
+type Replicator interface {
+
+	// BuildTargets return a clean copy of the source for each namespace, with the labels added
+	BuildTargets(source *unstructured.Unstructured, namespaces []string, labels map[string]string) []unstructured.Unstructured
+
+	// UpdateTarget update a target, or create it when not existent, returning the change made.
+	// When dryRun is set, the request is only validated by the API server and nothing is persisted
+	UpdateTarget(ctx context.Context, target *unstructured.Unstructured, dryRun bool) (Result, error)
+
+	// DeleteTargets delete all the objects of a kind matching the labels
+	DeleteTargets(ctx context.Context, gvk schema.GroupVersionKind, labels map[string]string) error
+}
+
+// Options defines how the targets are written
+type Options struct {
+	// OptimisticLock writes the targets only over the resourceVersion read before computing the changes.
+	// The target is read again and the changes computed on each conflict, so concurrent changes are never
+	// overwritten blindly, at the cost of more requests
+	OptimisticLock bool
+
+	// OwnershipLabel is the key of the label marking the targets as written by the replicator. Existing objects
+	// without the label, or with another value, are refused with ErrTargetConflict. Not checked when empty
+	OwnershipLabel string
+
+	// AdoptExisting writes the targets over the existing objects even when they are not marked by OwnershipLabel
+	AdoptExisting bool
+
+	// Reader reads the existing targets. Passing a reader not backed by informers, like the API reader of a
+	// manager, avoids caching every object of the replicated kinds, like the Secrets of the whole cluster.
+	// The client is used when not set
+	Reader client.Reader
+}
+
+// replicator implements Replicator on top of any controller-runtime client
+type replicator struct {
+	client  client.Client
+	options Options
+}
+
+// New return a Replicator using the given client
+func New(c client.Client) Replicator {
+	return &replicator{client: c}
+}
+
+// NewWithOptions return a Replicator using the given client, writing the targets as defined by the options
+func NewWithOptions(c client.Client, options Options) Replicator {
+	return &replicator{client: c, options: options}
+}
+
+// BuildTargets return a clean copy of the source for each namespace, with the labels added
+func (r *replicator) BuildTargets(source *unstructured.Unstructured, namespaces []string, labels map[string]string) (targets []unstructured.Unstructured) {
+	return BuildTargets(source, namespaces, labels)
+}
+
+// BuildTargets return a clean copy of the source for each namespace, with the labels added.
+// Metadata managed by the API server and the status are never copied
+func BuildTargets(source *unstructured.Unstructured, namespaces []string, labels map[string]string) (targets []unstructured.Unstructured) {
+
+	// Copy source object and generate a clean target object
+	target := source.DeepCopy()
+	unstructured.RemoveNestedField(target.Object, "metadata")
+	unstructured.RemoveNestedField(target.Object, "status")
+	target.SetName(source.GetName())
+	target.SetAnnotations(source.GetAnnotations())
+
+	targetLabels := make(map[string]string)
+	for k, v := range source.GetLabels() {
+		targetLabels[k] = v
+	}
+	for k, v := range labels {
+		targetLabels[k] = v
+	}
+	target.SetLabels(targetLabels)
+
+	// Add a new target to the list changing the namespace
+	targets = []unstructured.Unstructured{}
+	for _, ns := range namespaces {
+		target.SetNamespace(ns)
+		targets = append(targets, *target.DeepCopy())
+	}
+
+	return targets
+}
+
+// StripLabels remove from the object the labels whose key starts with any of the prefixes.
+// A whole key, like app.kubernetes.io/managed-by, is a prefix matching itself
+func StripLabels(object *unstructured.Unstructured, prefixes []string) {
+	if len(prefixes) == 0 {
+		return
+	}
+
+	labels := object.GetLabels()
+	for k := range labels {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(k, prefix) {
+				delete(labels, k)
+				break
+			}
+		}
+	}
+	object.SetLabels(labels)
+}
+
+// UpdateTarget update a target, or create it when not existent, returning the change made.
+// With the optimistic lock, the conflicts are retried reading the target again
+func (r *replicator) UpdateTarget(ctx context.Context, target *unstructured.Unstructured, dryRun bool) (result Result, err error) {
+
+	if !r.options.OptimisticLock {
+		return r.updateTarget(ctx, target, dryRun)
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultBackoff, func() (err error) {
+		result, err = r.updateTarget(ctx, target, dryRun)
+		return err
+	})
+	return result, err
+}
+
+// updateTarget update a target, or create it when not existent, returning the change made
+func (r *replicator) updateTarget(ctx context.Context, target *unstructured.Unstructured, dryRun bool) (result Result, err error) {
+
+	// ConfigMaps and Secrets only synchronize their data through the typed client
+	if IsCoreDataKind(target.GroupVersionKind()) {
+		return r.updateCoreTarget(ctx, target, dryRun)
+	}
+
+	createOptions := []client.CreateOption{client.FieldOwner(FieldManager)}
+	patchOptions := []client.PatchOption{client.FieldOwner(FieldManager)}
+	if dryRun {
+		createOptions = append(createOptions, client.DryRunAll)
+		patchOptions = append(patchOptions, client.DryRunAll)
+	}
+
+	// Look for the target in the target namespace
+	tmpTarget := target.DeepCopy()
+	err = r.reader().Get(ctx, client.ObjectKey{
+		Namespace: target.GetNamespace(),
+		Name:      tmpTarget.GetName(),
+	}, tmpTarget)
+
+	// Create the resource when it is not found
+	if err != nil {
+		result = ResultCreated
+		err = r.client.Create(ctx, target.DeepCopy(), createOptions...)
+		return result, err
+	}
+
+	// Objects written by others are never taken over unless requested
+	err = r.checkOwnership(tmpTarget, target)
+	if err != nil {
+		return result, err
+	}
+
+	// The baseline of the namespaces is enforced as a whole
+	if IsBaselineKind(target.GroupVersionKind()) {
+		return r.updateBaselineTarget(ctx, tmpTarget, target, dryRun)
+	}
+
+	// Update only the fields that changed, so the audit logs and etcd writes contain the real changes
+	result = ResultUnchanged
+	patchContent := MinimalMergePatch(tmpTarget.Object, target.Object)
+	if len(patchContent) == 0 {
+		return result, err
+	}
+	if r.options.OptimisticLock {
+		LockPatch(patchContent, tmpTarget.GetResourceVersion())
+	}
+
+	var patch []byte
+	patch, err = json.Marshal(patchContent)
+	if err != nil {
+		return result, err
+	}
+
+	result = ResultUpdated
+	err = r.client.Patch(ctx, target.DeepCopy(), client.RawPatch(types.MergePatchType, patch), patchOptions...)
+
+	return result, err
+}
+
+// reader return the reader of the existing targets
+func (r *replicator) reader() client.Reader {
+	if r.options.Reader != nil {
+		return r.options.Reader
+	}
+	return r.client
+}
+
+// checkOwnership return ErrTargetConflict when the existing object is not marked as written by the replicator
+func (r *replicator) checkOwnership(existing, desired metav1.Object) (err error) {
+
+	key := r.options.OwnershipLabel
+	if key == "" || r.options.AdoptExisting {
+		return err
+	}
+
+	if value, found := existing.GetLabels()[key]; !found || value != desired.GetLabels()[key] {
+		err = fmt.Errorf("%w: %s/%s", ErrTargetConflict, existing.GetNamespace(), existing.GetName())
+	}
+	return err
+}
+
+// DeleteTargets delete all the objects of a kind matching the labels
+func (r *replicator) DeleteTargets(ctx context.Context, gvk schema.GroupVersionKind, labels map[string]string) (err error) {
+
+	// Construct a target list object
+	targets := &unstructured.UnstructuredList{}
+	targets.SetGroupVersionKind(gvk)
+
+	// Look for the targets inside the cluster
+	err = r.client.List(ctx, targets, client.MatchingLabels(labels))
+	if err != nil {
+		return err
+	}
+
+	// Delete the targets
+	for i := range targets.Items {
+		err = r.client.Delete(ctx, &targets.Items[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return err
+}
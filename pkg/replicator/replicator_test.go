@@ -0,0 +1,157 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestBuildTargets(t *testing.T) {
+	source := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":            "app-config",
+			"namespace":       "default",
+			"resourceVersion": "42",
+			"uid":             "1234",
+			"labels":          map[string]interface{}{"app": "web"},
+			"annotations":     map[string]interface{}{"team": "platform"},
+		},
+		"data":   map[string]interface{}{"a": "1"},
+		"status": map[string]interface{}{"ready": true},
+	}}
+
+	targets := BuildTargets(source, []string{"ns-a", "ns-b"}, map[string]string{"replika.prosimcorp.com/created-by": "test"})
+	if len(targets) != 2 {
+		t.Fatalf("expected 2 targets, got %d", len(targets))
+	}
+
+	for i, namespace := range []string{"ns-a", "ns-b"} {
+		target := targets[i]
+		if target.GetNamespace() != namespace || target.GetName() != "app-config" {
+			t.Errorf("unexpected target %s/%s", target.GetNamespace(), target.GetName())
+		}
+		if target.GetResourceVersion() != "" || target.GetUID() != "" {
+			t.Errorf("the metadata managed by the API server is copied on %s", namespace)
+		}
+		if _, found := target.Object["status"]; found {
+			t.Errorf("the status is copied on %s", namespace)
+		}
+		expectedLabels := map[string]string{"app": "web", "replika.prosimcorp.com/created-by": "test"}
+		if !reflect.DeepEqual(target.GetLabels(), expectedLabels) {
+			t.Errorf("expected labels %v, got %v", expectedLabels, target.GetLabels())
+		}
+		if target.GetAnnotations()["team"] != "platform" {
+			t.Errorf("the annotations are not copied on %s", namespace)
+		}
+	}
+}
+
+func TestStripLabels(t *testing.T) {
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		prefixes []string
+		expected map[string]string
+	}{
+		{
+			name:     "no prefixes",
+			labels:   map[string]string{"app": "web", "argocd.argoproj.io/instance": "app"},
+			expected: map[string]string{"app": "web", "argocd.argoproj.io/instance": "app"},
+		},
+		{
+			name:     "prefix of a domain",
+			labels:   map[string]string{"app": "web", "argocd.argoproj.io/instance": "app"},
+			prefixes: []string{"argocd.argoproj.io/"},
+			expected: map[string]string{"app": "web"},
+		},
+		{
+			name:     "whole key",
+			labels:   map[string]string{"app": "web", "app.kubernetes.io/managed-by": "helm"},
+			prefixes: []string{"app.kubernetes.io/managed-by"},
+			expected: map[string]string{"app": "web"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			object := &unstructured.Unstructured{Object: map[string]interface{}{}}
+			object.SetLabels(test.labels)
+			StripLabels(object, test.prefixes)
+			if !reflect.DeepEqual(object.GetLabels(), test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, object.GetLabels())
+			}
+		})
+	}
+}
+
+func TestCheckOwnership(t *testing.T) {
+	const ownershipLabel = "replika.prosimcorp.com/created-by"
+
+	tests := []struct {
+		name           string
+		options        Options
+		existingLabels map[string]string
+		conflict       bool
+	}{
+		{
+			name:           "not checked",
+			existingLabels: map[string]string{},
+		},
+		{
+			name:           "written by the replicator",
+			options:        Options{OwnershipLabel: ownershipLabel},
+			existingLabels: map[string]string{ownershipLabel: "owner"},
+		},
+		{
+			name:           "written by others",
+			options:        Options{OwnershipLabel: ownershipLabel},
+			existingLabels: map[string]string{"app": "web"},
+			conflict:       true,
+		},
+		{
+			name:           "written by another owner",
+			options:        Options{OwnershipLabel: ownershipLabel},
+			existingLabels: map[string]string{ownershipLabel: "other"},
+			conflict:       true,
+		},
+		{
+			name:           "adopted",
+			options:        Options{OwnershipLabel: ownershipLabel, AdoptExisting: true},
+			existingLabels: map[string]string{"app": "web"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &replicator{options: test.options}
+			existing := &metav1.ObjectMeta{Namespace: "default", Name: "app-config", Labels: test.existingLabels}
+			desired := &metav1.ObjectMeta{Namespace: "default", Name: "app-config", Labels: map[string]string{ownershipLabel: "owner"}}
+
+			err := r.checkOwnership(existing, desired)
+			if errors.Is(err, ErrTargetConflict) != test.conflict {
+				t.Errorf("expected conflict %t, got %v", test.conflict, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicator
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// Type of the Secrets holding a certificate and its key, and the keys of their content
+	tlsSecretType = "kubernetes.io/tls"
+	tlsCertKey    = "tls.crt"
+	tlsKeyKey     = "tls.key"
+)
+
+// IsTLSSecret return true for the Secrets holding a certificate and its key
+func IsTLSSecret(object *unstructured.Unstructured) bool {
+	if object.GetAPIVersion() != "v1" || object.GetKind() != "Secret" {
+		return false
+	}
+	secretType, _, _ := unstructured.NestedString(object.Object, "type")
+	return secretType == tlsSecretType
+}
+
+// GetTLSCertificate return the leaf certificate of a TLS Secret, checking it parses and matches the key
+func GetTLSCertificate(object *unstructured.Unstructured) (certificate *x509.Certificate, err error) {
+
+	var content [2][]byte
+	for i, key := range []string{tlsCertKey, tlsKeyKey} {
+		encoded, found, _ := unstructured.NestedString(object.Object, "data", key)
+		if !found {
+			err = fmt.Errorf("the key %s is missing", key)
+			return certificate, err
+		}
+		content[i], err = base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			err = fmt.Errorf("the key %s is not valid base64: %w", key, err)
+			return certificate, err
+		}
+	}
+
+	var pair tls.Certificate
+	pair, err = tls.X509KeyPair(content[0], content[1])
+	if err != nil {
+		return certificate, err
+	}
+
+	certificate, err = x509.ParseCertificate(pair.Certificate[0])
+	return certificate, err
+}
+
+// ValidateTLSSecret return an error when the certificate of the TLS Secret does not parse, does not match
+// its key or is not valid at the given time
+func ValidateTLSSecret(object *unstructured.Unstructured, now time.Time) (err error) {
+
+	var certificate *x509.Certificate
+	certificate, err = GetTLSCertificate(object)
+	if err != nil {
+		return err
+	}
+
+	if now.After(certificate.NotAfter) {
+		err = fmt.Errorf("the certificate expired at %s", certificate.NotAfter.Format(time.RFC3339))
+		return err
+	}
+	if now.Before(certificate.NotBefore) {
+		err = fmt.Errorf("the certificate is not valid until %s", certificate.NotBefore.Format(time.RFC3339))
+		return err
+	}
+
+	return err
+}
@@ -0,0 +1,469 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhookcert generates and rotates the serving certificate of the admission webhooks in-process,
+// so they can be enabled on the installations without cert-manager
+package webhookcert
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// Keys of the Secret holding the certificates. The serving ones are the names read by the webhook server
+	CACertKey      = "ca.crt"
+	CAKeyKey       = "ca.key"
+	ServingCertKey = "tls.crt"
+	ServingKeyKey  = "tls.key"
+
+	// Validity of the generated certificates
+	caValidity      = 10 * 365 * 24 * time.Hour
+	servingValidity = 365 * 24 * time.Hour
+
+	// Fraction of the validity left when the certificates are renewed
+	renewalFraction = 5
+
+	// Time between two checks of the certificates
+	defaultInterval = time.Hour
+
+	rotatedMessage = "Rotated the certificates of the webhooks"
+	patchedMessage = "Injected the CA bundle into the webhook configuration"
+	crdMessage     = "Injected the CA bundle into the conversion webhook of the CustomResourceDefinition"
+)
+
+//+kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations;validatingwebhookconfigurations,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch;update;patch
+
+// Rotator keeps the serving certificate of the webhooks valid. The certificates are shared through a Secret,
+// so all the replicas of the webhook server serve the same one, and the CA is injected into every webhook
+// configuration and conversion webhook pointing at the Service of the webhooks
+type Rotator struct {
+	// Client must read without cache, so the Secrets of the cluster are not watched
+	Client client.Client
+
+	// Secret holding the certificates
+	Secret types.NamespacedName
+
+	// Service of the webhooks, whose DNS names are set on the serving certificate
+	Service types.NamespacedName
+
+	// CertDir is the directory where the webhook server reads the serving certificate
+	CertDir string
+
+	// Interval is the time between two checks of the certificates
+	Interval time.Duration
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, as every replica writes its own certificate files
+func (r *Rotator) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable, renewing the certificates until the manager stops
+func (r *Rotator) Start(ctx context.Context) (err error) {
+
+	interval := r.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return err
+		case <-ticker.C:
+			if err := r.Ensure(ctx); err != nil {
+				log.FromContext(ctx).Error(err, "unable to rotate the certificates of the webhooks")
+			}
+		}
+	}
+}
+
+// Ensure renew the certificates when they are missing or close to their expiry, inject the CA into
+// the webhook configurations and write the serving certificate into the directory of the webhook server.
+// It must be called before starting the manager, as the webhook server can not start without certificates
+func (r *Rotator) Ensure(ctx context.Context) (err error) {
+
+	secret, err := r.ensureSecret(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The CA is injected first, so the clients trust a renewed CA before it is served
+	err = r.injectCABundle(ctx, secret.Data[CACertKey])
+	if err != nil {
+		return err
+	}
+
+	err = r.writeFiles(secret)
+	return err
+}
+
+// ensureSecret return the Secret holding valid certificates, creating or renewing them when needed.
+// The replicas racing for the renewal keep the certificates written by the first one
+func (r *Rotator) ensureSecret(ctx context.Context) (secret *corev1.Secret, err error) {
+
+	secret = &corev1.Secret{}
+	err = r.Client.Get(ctx, r.Secret, secret)
+	if client.IgnoreNotFound(err) != nil {
+		return secret, err
+	}
+
+	if apierrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: r.Secret.Namespace, Name: r.Secret.Name},
+			Type:       corev1.SecretTypeTLS,
+		}
+		secret.Data, err = r.generate(nil)
+		if err != nil {
+			return secret, err
+		}
+		err = r.Client.Create(ctx, secret)
+		if apierrors.IsAlreadyExists(err) {
+			return r.ensureSecret(ctx)
+		}
+		if err == nil {
+			log.FromContext(ctx).Info(rotatedMessage, "secret", r.Secret.String())
+		}
+		return secret, err
+	}
+
+	if r.isValid(secret.Data, time.Now()) {
+		return secret, err
+	}
+
+	secret.Data, err = r.generate(secret.Data)
+	if err != nil {
+		return secret, err
+	}
+	err = r.Client.Update(ctx, secret)
+	if apierrors.IsConflict(err) {
+		return r.ensureSecret(ctx)
+	}
+	if err == nil {
+		log.FromContext(ctx).Info(rotatedMessage, "secret", r.Secret.String())
+	}
+	return secret, err
+}
+
+// isValid return true when the CA and the serving certificate are not close to their expiry,
+// and the serving certificate is signed by the CA for the names of the Service
+func (r *Rotator) isValid(data map[string][]byte, now time.Time) bool {
+
+	ca, _, err := parseKeyPair(data[CACertKey], data[CAKeyKey])
+	if err != nil || needsRenewal(ca, now) {
+		return false
+	}
+
+	serving, _, err := parseKeyPair(data[ServingCertKey], data[ServingKeyKey])
+	if err != nil || needsRenewal(serving, now) {
+		return false
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	_, err = serving.Verify(x509.VerifyOptions{
+		DNSName:     r.dnsNames()[0],
+		Roots:       roots,
+		CurrentTime: now,
+	})
+	return err == nil
+}
+
+// generate return the data of the Secret with a new serving certificate. The CA is kept while it is valid,
+// otherwise a new one is generated and the previous one is kept in the CA bundle until it expires,
+// so the clients trusting it keep working until the webhook server reloads the new certificate
+func (r *Rotator) generate(previous map[string][]byte) (data map[string][]byte, err error) {
+
+	now := time.Now()
+	ca, caKey, err := parseKeyPair(previous[CACertKey], previous[CAKeyKey])
+	caBundle := previous[CACertKey]
+	if err != nil || needsRenewal(ca, now) {
+		ca, caKey, err = newCertificate(nil, nil, pkix.Name{CommonName: r.Service.Name + "-ca"}, nil, caValidity)
+		if err != nil {
+			return data, err
+		}
+
+		newBundle := encodeCertificate(ca)
+		if oldCA, err := parseCertificate(caBundle); err == nil && now.Before(oldCA.NotAfter) {
+			newBundle = append(newBundle, encodeCertificate(oldCA)...)
+		}
+		caBundle = newBundle
+	}
+
+	serving, servingKey, err := newCertificate(ca, caKey, pkix.Name{CommonName: r.dnsNames()[0]}, r.dnsNames(), servingValidity)
+	if err != nil {
+		return data, err
+	}
+
+	var caKeyPEM, servingKeyPEM []byte
+	caKeyPEM, err = encodeKey(caKey)
+	if err != nil {
+		return data, err
+	}
+	servingKeyPEM, err = encodeKey(servingKey)
+	if err != nil {
+		return data, err
+	}
+
+	data = map[string][]byte{
+		CACertKey:      caBundle,
+		CAKeyKey:       caKeyPEM,
+		ServingCertKey: encodeCertificate(serving),
+		ServingKeyKey:  servingKeyPEM,
+	}
+	return data, err
+}
+
+// writeFiles write the serving certificate into the directory of the webhook server when it changed.
+// The webhook server reloads it without restarting
+func (r *Rotator) writeFiles(secret *corev1.Secret) (err error) {
+
+	err = os.MkdirAll(r.CertDir, 0o700)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range []string{ServingCertKey, ServingKeyKey} {
+		path := filepath.Join(r.CertDir, key)
+		current, _ := os.ReadFile(path)
+		if bytes.Equal(current, secret.Data[key]) {
+			continue
+		}
+
+		// Written and renamed, so the webhook server never reads a partial file
+		err = os.WriteFile(path+".tmp", secret.Data[key], 0o600)
+		if err != nil {
+			return err
+		}
+		err = os.Rename(path+".tmp", path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// injectCABundle set the CA bundle on the webhooks and the conversion webhooks pointing at the Service of the webhooks
+func (r *Rotator) injectCABundle(ctx context.Context, caBundle []byte) (err error) {
+
+	mutatingList := &admissionregistrationv1.MutatingWebhookConfigurationList{}
+	err = r.Client.List(ctx, mutatingList)
+	if err != nil {
+		return err
+	}
+	for i := range mutatingList.Items {
+		configuration := &mutatingList.Items[i]
+		patch := client.MergeFrom(configuration.DeepCopy())
+		changed := false
+		for j := range configuration.Webhooks {
+			changed = r.setCABundle(&configuration.Webhooks[j].ClientConfig, caBundle) || changed
+		}
+		if !changed {
+			continue
+		}
+		err = r.Client.Patch(ctx, configuration, patch)
+		if err != nil {
+			return err
+		}
+		log.FromContext(ctx).Info(patchedMessage, "configuration", configuration.Name)
+	}
+
+	validatingList := &admissionregistrationv1.ValidatingWebhookConfigurationList{}
+	err = r.Client.List(ctx, validatingList)
+	if err != nil {
+		return err
+	}
+	for i := range validatingList.Items {
+		configuration := &validatingList.Items[i]
+		patch := client.MergeFrom(configuration.DeepCopy())
+		changed := false
+		for j := range configuration.Webhooks {
+			changed = r.setCABundle(&configuration.Webhooks[j].ClientConfig, caBundle) || changed
+		}
+		if !changed {
+			continue
+		}
+		err = r.Client.Patch(ctx, configuration, patch)
+		if err != nil {
+			return err
+		}
+		log.FromContext(ctx).Info(patchedMessage, "configuration", configuration.Name)
+	}
+
+	// The conversion webhooks share the Service, so the versions of the CustomResourceDefinitions are served too
+	crdList := &apiextensionsv1.CustomResourceDefinitionList{}
+	err = r.Client.List(ctx, crdList)
+	if err != nil {
+		return err
+	}
+	for i := range crdList.Items {
+		crd := &crdList.Items[i]
+		conversion := crd.Spec.Conversion
+		if conversion == nil || conversion.Webhook == nil || conversion.Webhook.ClientConfig == nil {
+			continue
+		}
+		service := conversion.Webhook.ClientConfig.Service
+		if service == nil || service.Namespace != r.Service.Namespace || service.Name != r.Service.Name {
+			continue
+		}
+		if bytes.Equal(conversion.Webhook.ClientConfig.CABundle, caBundle) {
+			continue
+		}
+		patch := client.MergeFrom(crd.DeepCopy())
+		conversion.Webhook.ClientConfig.CABundle = caBundle
+		err = r.Client.Patch(ctx, crd, patch)
+		if err != nil {
+			return err
+		}
+		log.FromContext(ctx).Info(crdMessage, "customResourceDefinition", crd.Name)
+	}
+
+	return err
+}
+
+// setCABundle set the CA bundle on a webhook pointing at the Service of the webhooks. It return true when changed
+func (r *Rotator) setCABundle(clientConfig *admissionregistrationv1.WebhookClientConfig, caBundle []byte) bool {
+	service := clientConfig.Service
+	if service == nil || service.Namespace != r.Service.Namespace || service.Name != r.Service.Name {
+		return false
+	}
+	if bytes.Equal(clientConfig.CABundle, caBundle) {
+		return false
+	}
+	clientConfig.CABundle = caBundle
+	return true
+}
+
+// dnsNames return the names the Service of the webhooks is reached by
+func (r *Rotator) dnsNames() []string {
+	return []string{
+		fmt.Sprintf("%s.%s.svc", r.Service.Name, r.Service.Namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", r.Service.Name, r.Service.Namespace),
+		fmt.Sprintf("%s.%s", r.Service.Name, r.Service.Namespace),
+		r.Service.Name,
+	}
+}
+
+// needsRenewal return true when the certificate is in the last part of its validity
+func needsRenewal(certificate *x509.Certificate, now time.Time) bool {
+	validity := certificate.NotAfter.Sub(certificate.NotBefore)
+	return now.After(certificate.NotAfter.Add(-validity / renewalFraction))
+}
+
+// newCertificate return a certificate and its key signed by the parent. A nil parent creates a CA signing itself
+func newCertificate(parent *x509.Certificate, parentKey *ecdsa.PrivateKey, subject pkix.Name, dnsNames []string,
+	validity time.Duration) (certificate *x509.Certificate, key *ecdsa.PrivateKey, err error) {
+
+	key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return certificate, key, err
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return certificate, key, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               subject,
+		DNSNames:              dnsNames,
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		template.ExtKeyUsage = nil
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return certificate, key, err
+	}
+	certificate, err = x509.ParseCertificate(der)
+	return certificate, key, err
+}
+
+// parseCertificate return the first certificate of a PEM bundle
+func parseCertificate(content []byte) (certificate *x509.Certificate, err error) {
+	block, _ := pem.Decode(content)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return certificate, fmt.Errorf("no PEM certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// parseKeyPair return the first certificate of a PEM bundle and its ECDSA key
+func parseKeyPair(certContent, keyContent []byte) (certificate *x509.Certificate, key *ecdsa.PrivateKey, err error) {
+
+	certificate, err = parseCertificate(certContent)
+	if err != nil {
+		return certificate, key, err
+	}
+
+	block, _ := pem.Decode(keyContent)
+	if block == nil {
+		return certificate, key, fmt.Errorf("no PEM key found")
+	}
+	key, err = x509.ParseECPrivateKey(block.Bytes)
+	return certificate, key, err
+}
+
+// encodeCertificate return the PEM encoding of a certificate
+func encodeCertificate(certificate *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw})
+}
+
+// encodeKey return the PEM encoding of an ECDSA key
+func encodeKey(key *ecdsa.PrivateKey) (content []byte, err error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return content, err
+	}
+	content = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	return content, err
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhookcert
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newRotator return a Rotator for the webhooks of the replika-system namespace, with the objects in its client
+func newRotator(t *testing.T, objects ...client.Object) *Rotator {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error building the scheme: %v", err)
+	}
+	if err := apiextensionsv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error building the scheme: %v", err)
+	}
+
+	return &Rotator{
+		Client:  fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+		Secret:  types.NamespacedName{Namespace: "replika-system", Name: "webhook-server-cert"},
+		Service: types.NamespacedName{Namespace: "replika-system", Name: "webhook-service"},
+		CertDir: t.TempDir(),
+	}
+}
+
+// newWebhookConfiguration return a validating webhook configuration calling the Service
+func newWebhookConfiguration(name, service string) *admissionregistrationv1.ValidatingWebhookConfiguration {
+	return &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+			Name: name + ".example.com",
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{Namespace: "replika-system", Name: service},
+			},
+		}},
+	}
+}
+
+func TestEnsure(t *testing.T) {
+	crd := &apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "replikas.replika.prosimcorp.com"}}
+	crd.Spec.Conversion = &apiextensionsv1.CustomResourceConversion{
+		Strategy: apiextensionsv1.WebhookConverter,
+		Webhook: &apiextensionsv1.WebhookConversion{ClientConfig: &apiextensionsv1.WebhookClientConfig{
+			Service: &apiextensionsv1.ServiceReference{Namespace: "replika-system", Name: "webhook-service"},
+		}},
+	}
+	rotator := newRotator(t,
+		newWebhookConfiguration("replika", "webhook-service"),
+		newWebhookConfiguration("other", "other-service"),
+		crd,
+	)
+	ctx := context.Background()
+
+	if err := rotator.Ensure(ctx); err != nil {
+		t.Fatalf("unexpected error ensuring the certificates: %v", err)
+	}
+
+	secret := &corev1.Secret{}
+	if err := rotator.Client.Get(ctx, rotator.Secret, secret); err != nil {
+		t.Fatalf("unexpected error getting the Secret: %v", err)
+	}
+	if !rotator.isValid(secret.Data, time.Now()) {
+		t.Errorf("expected valid certificates for the Service")
+	}
+
+	// The serving certificate is written for the webhook server
+	for _, key := range []string{ServingCertKey, ServingKeyKey} {
+		content, err := os.ReadFile(filepath.Join(rotator.CertDir, key))
+		if err != nil || !bytes.Equal(content, secret.Data[key]) {
+			t.Errorf("expected %s written, got %v", key, err)
+		}
+	}
+
+	// The CA is only injected into the webhooks calling the Service
+	for name, expected := range map[string]bool{"replika": true, "other": false} {
+		configuration := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		if err := rotator.Client.Get(ctx, types.NamespacedName{Name: name}, configuration); err != nil {
+			t.Fatalf("unexpected error getting the webhook configuration: %v", err)
+		}
+		injected := bytes.Equal(configuration.Webhooks[0].ClientConfig.CABundle, secret.Data[CACertKey])
+		if injected != expected {
+			t.Errorf("expected the CA injected into %s %t, got %t", name, expected, injected)
+		}
+	}
+	if err := rotator.Client.Get(ctx, client.ObjectKeyFromObject(crd), crd); err != nil {
+		t.Fatalf("unexpected error getting the CustomResourceDefinition: %v", err)
+	}
+	if !bytes.Equal(crd.Spec.Conversion.Webhook.ClientConfig.CABundle, secret.Data[CACertKey]) {
+		t.Errorf("expected the CA injected into the conversion webhook")
+	}
+
+	// The valid certificates are kept
+	if err := rotator.Ensure(ctx); err != nil {
+		t.Fatalf("unexpected error ensuring the certificates again: %v", err)
+	}
+	kept := &corev1.Secret{}
+	if err := rotator.Client.Get(ctx, rotator.Secret, kept); err != nil {
+		t.Fatalf("unexpected error getting the Secret: %v", err)
+	}
+	if !bytes.Equal(kept.Data[ServingCertKey], secret.Data[ServingCertKey]) {
+		t.Errorf("expected the valid serving certificate kept")
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	rotator := newRotator(t)
+	data, err := rotator.generate(nil)
+	if err != nil {
+		t.Fatalf("unexpected error generating the certificates: %v", err)
+	}
+
+	if !rotator.isValid(data, time.Now()) {
+		t.Errorf("expected the generated certificates valid")
+	}
+	if rotator.isValid(data, time.Now().Add(servingValidity)) {
+		t.Errorf("expected the certificates renewed close to their expiry")
+	}
+
+	// A certificate for another Service is not valid
+	other := newRotator(t)
+	other.Service.Name = "other-service"
+	if other.isValid(data, time.Now()) {
+		t.Errorf("expected the certificates of another Service not valid")
+	}
+}
+
+func TestGenerateRenewsCA(t *testing.T) {
+	rotator := newRotator(t)
+
+	// A CA in the last part of its validity, still trusted by the clients
+	oldCA, oldKey, err := newCertificate(nil, nil, pkix.Name{CommonName: "old-ca"}, nil, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error generating the CA: %v", err)
+	}
+	oldKeyPEM, err := encodeKey(oldKey)
+	if err != nil {
+		t.Fatalf("unexpected error encoding the key: %v", err)
+	}
+
+	data, err := rotator.generate(map[string][]byte{CACertKey: encodeCertificate(oldCA), CAKeyKey: oldKeyPEM})
+	if err != nil {
+		t.Fatalf("unexpected error generating the certificates: %v", err)
+	}
+
+	// The new CA comes first, followed by the previous one until it expires
+	var subjects []string
+	for rest := data[CACertKey]; len(rest) > 0; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		certificate, err := parseCertificate(pem.EncodeToMemory(block))
+		if err != nil {
+			t.Fatalf("unexpected error parsing the CA bundle: %v", err)
+		}
+		subjects = append(subjects, certificate.Subject.CommonName)
+	}
+	if len(subjects) != 2 || subjects[0] != "webhook-service-ca" || subjects[1] != "old-ca" {
+		t.Errorf("expected the new and the previous CA in the bundle, got %v", subjects)
+	}
+	if !rotator.isValid(data, time.Now()) {
+		t.Errorf("expected the serving certificate signed by the new CA")
+	}
+}